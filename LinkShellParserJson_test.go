@@ -2,19 +2,24 @@ package main
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
-func Test_parseIDList(t *testing.T) {
+func Test_ParseIDList(t *testing.T) {
 	type args struct {
 		idListData []byte
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    []*ItemID
+		want    []ItemID
 		wantErr bool
 	}{
 		{
@@ -22,10 +27,20 @@ func Test_parseIDList(t *testing.T) {
 			args: args{
 				idListData: []byte{0x14, 0x00, 0x1F, 0x50, 0xE0, 0x4F, 0xD0, 0x20, 0xEA, 0x3A, 0x69, 0x10, 0xA2, 0xD8, 0x08, 0x00, 0x2B, 0x30, 0x30, 0x9D},
 			},
-			want: []*ItemID{
+			want: []ItemID{
 				{
-					ItemIDSize: 0x14,
-					Data:       []byte{0x1F, 0x50, 0xE0, 0x4F, 0xD0, 0x20, 0xEA, 0x3A, 0x69, 0x10, 0xA2, 0xD8, 0x08, 0x00, 0x2B, 0x30, 0x30, 0x9D},
+					ItemIDSize:       0x14,
+					ItemIDDataBase64: "H1DgT9Ag6jppEKLYCAArMDCd",
+					ItemIDData:       []byte{0x1F, 0x50, 0xE0, 0x4F, 0xD0, 0x20, 0xEA, 0x3A, 0x69, 0x10, 0xA2, 0xD8, 0x08, 0x00, 0x2B, 0x30, 0x30, 0x9D},
+					ShellItem: ShellItem{
+						ClassType:     0x1F,
+						ClassTypeName: "RootFolder",
+						RootFolder: &RootFolderShellItem{
+							SortIndex:       0x50,
+							ShellFolderID:   GUID{0xE0, 0x4F, 0xD0, 0x20, 0xEA, 0x3A, 0x69, 0x10, 0xA2, 0xD8, 0x08, 0x00, 0x2B, 0x30, 0x30, 0x9D},
+							ShellFolderName: "My Computer",
+						},
+					},
 				},
 			},
 			wantErr: false,
@@ -34,172 +49,451 @@ func Test_parseIDList(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseIDList(tt.args.idListData)
+			got, err := ParseIDList(tt.args.idListData, nil)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseIDList() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseIDList() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseIDList() = %v, want %v", got, tt.want)
+				t.Errorf("ParseIDList() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseExtraData(t *testing.T) {
+func Test_ParseStringData(t *testing.T) {
 	type args struct {
-		reader *bytes.Reader
+		reader          *bytes.Reader
+		linkFlagsParsed LinkFlagsParsed
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    []*ExtraDataBlock
+		want    StringData
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "unicode arguments and working dir",
+			args: args{
+				reader: bytes.NewReader([]byte{
+					0x03, 0x00, 'C', 0x00, ':', 0x00, '\\', 0x00,
+					0x02, 0x00, '/', 0x00, 0x1F, 0x04,
+				}),
+				linkFlagsParsed: LinkFlagsParsed{HasWorkingDir: true, HasArguments: true, IsUnicode: true},
+			},
+			want: StringData{
+				WorkingDir:            "C:\\",
+				WorkingDirBase64:      "QwA6AFwA",
+				CommandLineArgs:       "/П",
+				CommandLineArgsBase64: "LwAfBA==",
+			},
+		},
+		{
+			name: "ansi all fields",
+			args: args{
+				reader: bytes.NewReader([]byte{
+					0x01, 0x00, 'n',
+					0x02, 0x00, '.', '\\',
+					0x01, 0x00, 'w',
+					0x01, 0x00, 0x80,
+					0x03, 0x00, 'i', ',', '1',
+				}),
+				linkFlagsParsed: LinkFlagsParsed{HasName: true, HasRelativePath: true, HasWorkingDir: true, HasArguments: true, HasIconLocation: true},
+			},
+			want: StringData{
+				NameString:            "n",
+				NameStringBase64:      "bg==",
+				RelativePath:          ".\\",
+				RelativePathBase64:    "Llw=",
+				WorkingDir:            "w",
+				WorkingDirBase64:      "dw==",
+				CommandLineArgs:       "€",
+				CommandLineArgsBase64: "gA==",
+				IconLocation:          "i,1",
+				IconLocationBase64:    "aSwx",
+			},
+		},
+		{
+			name: "truncated",
+			args: args{
+				reader:          bytes.NewReader([]byte{0x05, 0x00, 'a', 0x00}),
+				linkFlagsParsed: LinkFlagsParsed{HasName: true, IsUnicode: true},
+			},
+			want:    StringData{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseExtraData(tt.args.reader)
+			got, err := ParseStringData(tt.args.reader, tt.args.linkFlagsParsed, nil)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseExtraData() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseStringData() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseExtraData() = %v, want %v", got, tt.want)
+				t.Errorf("ParseStringData() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseAllStringData(t *testing.T) {
+func Test_ParseData(t *testing.T) {
 	type args struct {
-		reader    *bytes.Reader
-		isUnicode bool
+		reader *bytes.Reader
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    *StringData
+		want    ShellLinkParsed
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name:    "truncated header",
+			args:    args{reader: bytes.NewReader([]byte{0x4C, 0x00, 0x00, 0x00})},
+			want:    ShellLinkParsed{CodePage: DefaultCodePage, CodePageSource: "default"},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseAllStringData(tt.args.reader, tt.args.isUnicode)
+			got, err := ParseData(tt.args.reader)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseAllStringData() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseData() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseAllStringData() = %v, want %v", got, tt.want)
+				t.Errorf("ParseData() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseShellLink(t *testing.T) {
-	type args struct {
-		data []byte
+func Test_ParseData_parseError(t *testing.T) {
+	data, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    *ShellLink
-		wantErr bool
+		name   string
+		length int
+		want   ParseError
 	}{
-		// TODO: Add test cases.
+		{
+			name:   "truncated header",
+			length: 0x20,
+			want:   ParseError{Section: "ShellLinkHeader", Offset: 0, Expected: 0x4C, Available: 0x20},
+		},
+		{
+			name:   "truncated IDListSize",
+			length: 0x4D,
+			want:   ParseError{Section: "LinkTargetIDList", Offset: 0x4C, Expected: 2, Available: 1},
+		},
+		{
+			name:   "truncated IDList",
+			length: 0x100,
+			want:   ParseError{Section: "LinkTargetIDList", Offset: 0x4C, Expected: 0xE9, Available: 0xB4},
+		},
+		{
+			name:   "truncated LinkInfo",
+			length: 0x150,
+			want:   ParseError{Section: "LinkInfo", Offset: 0x135, Expected: 0x45, Available: 0x1B},
+		},
+		{
+			name:   "truncated StringData",
+			length: 0x17B,
+			want:   ParseError{Section: "StringData.RelativePath", Offset: 0x17A, Expected: 2, Available: 1},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseShellLink(tt.args.data)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseShellLink() error = %v, wantErr %v", err, tt.wantErr)
-				return
+			_, err := ParseData(bytes.NewReader(data[:tt.length]))
+			var parseError *ParseError
+			if !errors.As(err, &parseError) {
+				t.Fatalf("ParseData() error = %v, want a ParseError", err)
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseShellLink() = %v, want %v", got, tt.want)
+			if *parseError != tt.want {
+				t.Errorf("ParseData() error = %+v, want %+v", *parseError, tt.want)
+			}
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("ParseData() error = %v, want io.ErrUnexpectedEOF", err)
+			}
+		})
+	}
+}
+
+func Test_ParseData_linkCLSID(t *testing.T) {
+	data, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0x04] ^= 0xFF
+	_, err = ParseData(bytes.NewReader(data))
+	var constMismatchError *ConstMismatchError
+	if !errors.As(err, &constMismatchError) {
+		t.Errorf("ParseData() error = %v, want a ConstMismatchError", err)
+	}
+}
+
+func Test_ParseDataWithOptions_lenient(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		data          func() []byte
+		wantWarning   ParseWarning
+		wantLinkInfo  bool
+		wantExtraData int
+	}{
+		{
+			name: "LinkCLSID mismatch",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				data[0x04] ^= 0xFF
+				return data
+			},
+			wantWarning:   ParseWarning{Section: "ShellLinkHeader", Offset: 0},
+			wantLinkInfo:  true,
+			wantExtraData: 5,
+		},
+		{
+			name: "VolumeIDSize beyond LinkInfo",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				binary.LittleEndian.PutUint32(data[0x151:], 0xFFFF)
+				return data
+			},
+			wantWarning:   ParseWarning{Section: "LinkInfo.VolumeID", Offset: 0x151},
+			wantLinkInfo:  true,
+			wantExtraData: 5,
+		},
+		{
+			name: "CommonPathSuffixOffset beyond LinkInfo",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				binary.LittleEndian.PutUint32(data[0x14D:], 0x45)
+				return data
+			},
+			wantWarning:   ParseWarning{Section: "LinkInfo.CommonPathSuffix", Offset: 0x17A},
+			wantLinkInfo:  true,
+			wantExtraData: 5,
+		},
+		{
+			name: "truncated LinkInfo",
+			data: func() []byte {
+				return fixture[:0x150]
+			},
+			wantWarning: ParseWarning{Section: "LinkInfo", Offset: 0x135},
+		},
+		{
+			name: "StorageSize beyond PropertyStoreDataBlock",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				binary.LittleEndian.PutUint32(data[0x526:], 0xFFFF)
+				return data
+			},
+			wantWarning:   ParseWarning{Section: "ExtraData[3].PropertyStore.PropertyStorage[0]", Offset: 0x526},
+			wantLinkInfo:  true,
+			wantExtraData: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data()
+			if _, err := ParseData(bytes.NewReader(data)); err == nil {
+				t.Errorf("ParseData() error = nil, want strict mode to fail")
+			}
+
+			got, err := ParseDataWithOptions(bytes.NewReader(data), ParseOptions{Lenient: true})
+			if err != nil {
+				t.Fatalf("ParseDataWithOptions() error = %v", err)
+			}
+			if len(got.Warnings) != 1 || got.Warnings[0].Section != tt.wantWarning.Section || got.Warnings[0].Offset != tt.wantWarning.Offset {
+				t.Errorf("ParseDataWithOptions() Warnings = %+v, want %+v", got.Warnings, tt.wantWarning)
+			}
+			if got.LinkTargetIDList == nil || len(got.LinkTargetIDList.IDListData.ItemIDs) != 4 {
+				t.Errorf("ParseDataWithOptions() LinkTargetIDList = %v", got.LinkTargetIDList)
+			}
+			if (got.LinkInfo != nil) != tt.wantLinkInfo {
+				t.Errorf("ParseDataWithOptions() LinkInfo = %v, want present %v", got.LinkInfo, tt.wantLinkInfo)
+			}
+			if len(got.ExtraData) != tt.wantExtraData {
+				t.Errorf("ParseDataWithOptions() ExtraData has %v blocks, want %v", len(got.ExtraData), tt.wantExtraData)
+			}
+			if got.Target == nil || got.Target.Path != "C:\\Windows\\notepad.exe" {
+				t.Errorf("ParseDataWithOptions() Target = %v", got.Target)
 			}
 		})
 	}
 }
 
-func Test_parseLinkFlags(t *testing.T) {
+func Test_Parse(t *testing.T) {
+	data, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.LinkTargetIDList == nil || len(got.LinkTargetIDList.IDListData.ItemIDs) != 4 {
+		t.Errorf("Parse() LinkTargetIDList = %v, want 4 ItemIDs", got.LinkTargetIDList)
+	} else if got.LinkTargetIDList.IDListData.Path != "C:\\Windows\\notepad.exe" {
+		t.Errorf("Parse() LinkTargetIDList Path = %v", got.LinkTargetIDList.IDListData.Path)
+	}
+	if got.LinkInfo == nil || got.LinkInfo.LocalBasePath != "C:\\Windows\\notepad.exe" {
+		t.Errorf("Parse() LinkInfo = %v", got.LinkInfo)
+	}
+	if got.LinkInfo != nil && (got.LinkInfo.VolumeID.DriveTypeName != "DRIVE_FIXED" || got.LinkInfo.VolumeID.DriveSerialNumberFormatted != "1234-ABCD") {
+		t.Errorf("Parse() VolumeID = %v", got.LinkInfo.VolumeID)
+	}
+	wantStringData := StringData{
+		RelativePath:          "..\\..\\..\\Windows\\notepad.exe",
+		RelativePathBase64:    "LgAuAFwALgAuAFwALgAuAFwAVwBpAG4AZABvAHcAcwBcAG4AbwB0AGUAcABhAGQALgBlAHgAZQA=",
+		WorkingDir:            "%windir%",
+		WorkingDirBase64:      "JQB3AGkAbgBkAGkAcgAlAA==",
+		CommandLineArgs:       "/A test.txt",
+		CommandLineArgsBase64: "LwBBACAAdABlAHMAdAAuAHQAeAB0AA==",
+	}
+	if !reflect.DeepEqual(got.StringData, wantStringData) {
+		t.Errorf("Parse() StringData = %v, want %v", got.StringData, wantStringData)
+	}
+	if len(got.ExtraData) != 5 {
+		t.Fatalf("Parse() ExtraData has %v blocks, want 5", len(got.ExtraData))
+	}
+	if block := got.ExtraData[0].EnvironmentVariableDataBlock; block == nil || block.TargetUnicode != "%windir%\\notepad.exe" {
+		t.Errorf("Parse() EnvironmentVariableDataBlock = %v", block)
+	}
+	if block := got.ExtraData[4].TrackerDataBlock; block == nil || block.MachineID != "desktop-01" {
+		t.Errorf("Parse() TrackerDataBlock = %v", block)
+	}
+	if got.HeaderParsed.HotKey != "Ctrl+Shift+F5" || got.HeaderParsed.ShowCommand != "SW_SHOWNORMAL" {
+		t.Errorf("Parse() HeaderParsed = %v", got.HeaderParsed)
+	}
+	if got.HeaderParsed.WriteTime == nil || !got.HeaderParsed.WriteTime.Equal(time.Date(2019, 12, 7, 9, 9, 4, 0, time.UTC)) {
+		t.Errorf("Parse() WriteTime = %v", got.HeaderParsed.WriteTime)
+	}
+	if got.Target == nil || got.Target.Path != "C:\\Windows\\notepad.exe" || got.Target.Conflict || len(got.Target.Candidates) != 5 {
+		t.Errorf("Parse() Target = %v", got.Target)
+	}
+	// the folder blocks point into the middle of an ItemID, see Test_Parse_knownFolder
+	if got.KnownFolderPath != nil {
+		t.Errorf("Parse() KnownFolderPath = %v, want nil", got.KnownFolderPath)
+	}
+
+	out, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !bytes.Contains(out, []byte(`"CommandLineArgs":"/A test.txt"`)) {
+		t.Errorf("json.Marshal() = %s", out)
+	}
+}
+
+func Test_ParseLinkFlags(t *testing.T) {
 	type args struct {
 		flagsRaw uint32
 	}
 	tests := []struct {
 		name string
 		args args
-		want LinkFlags
+		want LinkFlagsParsed
 	}{
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := parseLinkFlags(tt.args.flagsRaw); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseLinkFlags() = %v, want %v", got, tt.want)
+			if got := ParseLinkFlags(tt.args.flagsRaw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLinkFlags() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseHeader(t *testing.T) {
+func Test_ParseShellLinkHeader(t *testing.T) {
 	type args struct {
 		reader *bytes.Reader
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    *ShellLinkHeader
+		want    ShellLinkHeader
 		wantErr bool
 	}{
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseHeader(tt.args.reader)
+			got, err := ParseShellLinkHeader(tt.args.reader)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseHeader() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseShellLinkHeader() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseHeader() = %v, want %v", got, tt.want)
+				t.Errorf("ParseShellLinkHeader() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseLinkTargetIDList(t *testing.T) {
+func Test_ParseLinkTargetIDList(t *testing.T) {
 	type args struct {
 		reader *bytes.Reader
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    *LinkTargetIDList
+		want    LinkTargetIDList
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "empty IDList",
+			args: args{reader: bytes.NewReader([]byte{0x02, 0x00, 0x00, 0x00})},
+			want: LinkTargetIDList{IDListSize: 2, IDListData: IDList{ItemIDs: []ItemID{}}},
+		},
+		{
+			name:    "truncated IDListSize",
+			args:    args{reader: bytes.NewReader([]byte{0x02})},
+			want:    LinkTargetIDList{},
+			wantErr: true,
+		},
+		{
+			name:    "IDListSize beyond data",
+			args:    args{reader: bytes.NewReader([]byte{0xFF, 0xFF, 0x00, 0x00})},
+			want:    LinkTargetIDList{},
+			wantErr: true,
+		},
+		{
+			name:    "ItemIDSize beyond IDList",
+			args:    args{reader: bytes.NewReader([]byte{0x04, 0x00, 0x10, 0x00, 0x00, 0x00})},
+			want:    LinkTargetIDList{IDListSize: 4, IDListData: IDList{ItemIDs: []ItemID{}, Unterminated: true, TrailingDataBase64: "EAAAAA=="}},
+			wantErr: true,
+		},
+		{
+			name:    "ItemIDSize below its own size",
+			args:    args{reader: bytes.NewReader([]byte{0x04, 0x00, 0x01, 0x00, 0x00, 0x00})},
+			want:    LinkTargetIDList{IDListSize: 4, IDListData: IDList{ItemIDs: []ItemID{}, Unterminated: true, TrailingDataBase64: "AQAAAA=="}},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseLinkTargetIDList(tt.args.reader)
+			got, err := ParseLinkTargetIDList(tt.args.reader, nil)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseLinkTargetIDList() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseLinkTargetIDList() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseLinkTargetIDList() = %v, want %v", got, tt.want)
+				t.Errorf("ParseLinkTargetIDList() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_readNullTerminatedString(t *testing.T) {
+func Test_readByteStringZeroTerminated(t *testing.T) {
 	type args struct {
-		reader io.Reader
+		reader *bytes.Reader
 	}
 	tests := []struct {
 		name    string
@@ -207,144 +501,223 @@ func Test_readNullTerminatedString(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "terminated",
+			args: args{reader: bytes.NewReader([]byte{'a', 'b', 0x00, 'c'})},
+			want: "ab",
+		},
+		{
+			name: "longer than MAX_PATH",
+			args: args{reader: bytes.NewReader(append(bytes.Repeat([]byte{'a'}, 600), 0x00))},
+			want: strings.Repeat("a", 600),
+		},
+		{
+			name:    "missing terminator",
+			args:    args{reader: bytes.NewReader([]byte{'a', 'b'})},
+			want:    "ab",
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := readNullTerminatedString(tt.args.reader)
+			got, _, err := readByteStringZeroTerminated(tt.args.reader, nil)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("readNullTerminatedString() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("readByteStringZeroTerminated() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.want {
-				t.Errorf("readNullTerminatedString() = %v, want %v", got, tt.want)
+				t.Errorf("readByteStringZeroTerminated() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseLinkInfo(t *testing.T) {
+func Test_ParseLinkInfo(t *testing.T) {
 	type args struct {
 		reader *bytes.Reader
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    *LinkInfo
+		want    LinkInfo
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name:    "LinkInfoSize beyond data",
+			args:    args{reader: bytes.NewReader([]byte{0x00, 0x00, 0x01, 0x00, 0x1C, 0x00, 0x00, 0x00})},
+			want:    LinkInfo{LinkInfoSize: 0x10000},
+			wantErr: true,
+		},
+		{
+			name:    "LinkInfoSize below header",
+			args:    args{reader: bytes.NewReader([]byte{0x02, 0x00, 0x00, 0x00})},
+			want:    LinkInfo{LinkInfoSize: 2},
+			wantErr: true,
+		},
+		{
+			name: "LinkInfoHeaderSize beyond LinkInfoSize",
+			args: args{reader: bytes.NewReader([]byte{
+				0x1C, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x90, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			})},
+			want:    LinkInfo{LinkInfoSize: 0x1C, LinkInfoHeaderSize: 0x90000000},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseLinkInfo(tt.args.reader)
+			got, err := ParseLinkInfo(tt.args.reader, nil)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseLinkInfo() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseLinkInfo() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseLinkInfo() = %v, want %v", got, tt.want)
+				t.Errorf("ParseLinkInfo() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseStringData(t *testing.T) {
-	type args struct {
-		reader    *bytes.Reader
-		isUnicode bool
+func Test_ParseLinkInfo_headerSize(t *testing.T) {
+	data, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
 	}
-	tests := []struct {
-		name    string
-		args    args
-		want    *StringDataItem
-		wantErr bool
-	}{
-		// TODO: Add test cases.
+	// LinkInfoHeaderSize of notepad.lnk at 0x139
+	data[0x13C] = 0x90
+	_, err = ParseData(bytes.NewReader(data))
+	var parseError *ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("ParseData() error = %v, want a ParseError", err)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseStringData(tt.args.reader, tt.args.isUnicode)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseStringData() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseStringData() = %v, want %v", got, tt.want)
-			}
-		})
+	if want := (ParseError{Section: "LinkInfo", Offset: 0x135, Expected: 0x9000001C, Available: 0x45}); *parseError != want {
+		t.Errorf("ParseData() error = %+v, want %+v", *parseError, want)
 	}
 }
 
-func Test_formatGUID(t *testing.T) {
+func Test_readUnicodeStringZeroTerminated(t *testing.T) {
 	type args struct {
-		guid string
+		reader *bytes.Reader
 	}
 	tests := []struct {
-		name string
-		args args
-		want string
+		name      string
+		args      args
+		want      string
+		wantB64   string
+		wantErr   bool
+		wantUTF16 bool // wantErr is an InvalidUTF16Error
 	}{
-		// TODO: Add test cases.
+		{
+			name:    "surrogate pair",
+			args:    args{reader: bytes.NewReader([]byte{'a', 0x00, 0x3D, 0xD8, 0x00, 0xDE, 0x00, 0x00, 'b', 0x00})},
+			want:    "a😀",
+			wantB64: "YQA92ADeAAA=",
+		},
+		{
+			name:      "unpaired high surrogate",
+			args:      args{reader: bytes.NewReader([]byte{'a', 0x00, 0x3D, 0xD8, 'b', 0x00, 0x00, 0x00})},
+			want:      "a\uFFFDb",
+			wantB64:   "YQA92GIAAAA=",
+			wantErr:   true,
+			wantUTF16: true,
+		},
+		{
+			name:      "unpaired low surrogate",
+			args:      args{reader: bytes.NewReader([]byte{0x00, 0xDE, 0x00, 0x00})},
+			want:      "\uFFFD",
+			wantB64:   "AN4AAA==",
+			wantErr:   true,
+			wantUTF16: true,
+		},
+		{
+			name:    "no terminator",
+			args:    args{reader: bytes.NewReader([]byte{'a', 0x00, 'b'})},
+			want:    "a",
+			wantB64: "YQA=",
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := formatGUID(tt.args.guid); got != tt.want {
-				t.Errorf("formatGUID() = %v, want %v", got, tt.want)
+			got, gotB64, err := readUnicodeStringZeroTerminated(tt.args.reader)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readUnicodeStringZeroTerminated() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var invalidUTF16Error *InvalidUTF16Error
+			if errors.As(err, &invalidUTF16Error) != tt.wantUTF16 {
+				t.Errorf("readUnicodeStringZeroTerminated() error = %v", err)
+			}
+			if got != tt.want || gotB64 != tt.wantB64 {
+				t.Errorf("readUnicodeStringZeroTerminated() = %v, %v, want %v, %v", got, gotB64, tt.want, tt.wantB64)
 			}
 		})
 	}
 }
 
-func Test_filetimeToUnix(t *testing.T) {
-	type args struct {
-		ft int64
+func Test_ParseLinkInfo_unicode(t *testing.T) {
+	utf16z := func(u16s ...uint16) []byte {
+		var b []byte
+		for _, u16 := range append(u16s, 0) {
+			b = append(b, byte(u16), byte(u16>>8))
+		}
+		return b
+	}
+	localBasePathUnicode := utf16z('C', ':', '\\', 0x0434, 0xD83D, 0xDE00)
+	commonPathSuffixUnicode := utf16z('x', 0xD800)
+	data := []byte{
+		0x00, 0x00, 0x00, 0x00, // LinkInfoSize
+		0x24, 0x00, 0x00, 0x00, // LinkInfoHeaderSize
+		0x01, 0x00, 0x00, 0x00, // LinkInfoFlags
+		0x24, 0x00, 0x00, 0x00, // VolumeIDOffset
+		0x35, 0x00, 0x00, 0x00, // LocalBasePathOffset
+		0x00, 0x00, 0x00, 0x00, // CommonNetworkRelativeLinkOffset
+		0x39, 0x00, 0x00, 0x00, // CommonPathSuffixOffset
+		0x3A, 0x00, 0x00, 0x00, // LocalBasePathOffsetUnicode
+		byte(0x3A + len(localBasePathUnicode)), 0x00, 0x00, 0x00, // CommonPathSuffixOffsetUnicode
+		0x11, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0xCD, 0xAB, 0x34, 0x12, 0x10, 0x00, 0x00, 0x00, 0x00, // VolumeID
+		'C', ':', '\\', 0x00, // LocalBasePath
+		0x00, // CommonPathSuffix
+	}
+	data = append(append(data, localBasePathUnicode...), commonPathSuffixUnicode...)
+	data[0] = byte(len(data))
+
+	got, err := ParseLinkInfo(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("ParseLinkInfo() error = %v", err)
 	}
-	tests := []struct {
-		name string
-		args args
-		want int64
-	}{
-		// TODO: Add test cases.
+	if got.LocalBasePath != "C:\\" || got.LocalBasePathUnicode != "C:\\д😀" || got.BasePath() != "C:\\д😀" {
+		t.Errorf("ParseLinkInfo() LocalBasePath = %v, LocalBasePathUnicode = %v", got.LocalBasePath, got.LocalBasePathUnicode)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := filetimeToUnix(tt.args.ft); got != tt.want {
-				t.Errorf("filetimeToUnix() = %v, want %v", got, tt.want)
-			}
-		})
+	if got.CommonPathSuffixUnicode != "x\uFFFD" || got.PathSuffix() != "x\uFFFD" {
+		t.Errorf("ParseLinkInfo() CommonPathSuffixUnicode = %v", got.CommonPathSuffixUnicode)
+	}
+	wantUnicodeErrors := []string{"CommonPathSuffixUnicode: unpaired UTF-16 surrogate 0xD800 at byte 2"}
+	if !reflect.DeepEqual(got.UnicodeErrors, wantUnicodeErrors) {
+		t.Errorf("ParseLinkInfo() UnicodeErrors = %v, want %v", got.UnicodeErrors, wantUnicodeErrors)
 	}
 }
 
-func Test_utf16BytesToUTF8(t *testing.T) {
+func Test_decodeUTF16LE(t *testing.T) {
 	type args struct {
-		u16s []uint16
+		b []byte
 	}
 	tests := []struct {
 		name string
 		args args
 		want string
 	}{
-		// TODO: Add test cases.
+		{
+			name: "surrogate pair",
+			args: args{b: []byte{'a', 0x00, 0x3D, 0xD8, 0x00, 0xDE}},
+			want: "a😀",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := utf16BytesToUTF8(tt.args.u16s); got != tt.want {
-				t.Errorf("utf16BytesToUTF8() = %v, want %v", got, tt.want)
+			if got := decodeUTF16LE(tt.args.b); got != tt.want {
+				t.Errorf("decodeUTF16LE() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
-
-func Test_main(t *testing.T) {
-	tests := []struct {
-		name string
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			main()
-		})
-	}
-}
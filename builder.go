@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ShortcutBuilder creates a shortcut from scratch without the IShellLink COM API. The setters can be chained,
+// the first invalid value is reported by Build or Bytes:
+//
+//	data, err := NewShortcut(`C:\Program Files\App\app.exe`).Arguments("-v").HotKey("Ctrl+Alt+A").Bytes()
+type ShortcutBuilder struct {
+	targetPath   string
+	header       ShellLinkHeader
+	stringData   StringData
+	volumeID     VolumeID
+	codePage     *CodePage
+	environment  string
+	knownFolders []KnownFolderDataBlock
+	idList       []PathItem
+	hasIDList    bool
+	err          error
+}
+
+// NewShortcut starts a shortcut to a local path like C:\dir\file.exe or a UNC path like \\server\share\file.exe.
+func NewShortcut(targetPath string) *ShortcutBuilder {
+	return &ShortcutBuilder{
+		targetPath: strings.ReplaceAll(targetPath, "/", `\`),
+		header: ShellLinkHeader{
+			LinkCLSID:   LinkCLSIDExpected,
+			ShowCommand: SW_SHOWNORMAL,
+		},
+		volumeID: VolumeID{DriveType: DriveFixed},
+	}
+}
+
+// Arguments sets the command line arguments.
+func (b *ShortcutBuilder) Arguments(arguments string) *ShortcutBuilder {
+	b.stringData.CommandLineArgs = arguments
+	return b
+}
+
+// WorkingDir sets the working directory.
+func (b *ShortcutBuilder) WorkingDir(workingDir string) *ShortcutBuilder {
+	b.stringData.WorkingDir = workingDir
+	return b
+}
+
+// Icon sets the icon location and the index of the icon in it.
+func (b *ShortcutBuilder) Icon(iconLocation string, iconIndex int32) *ShortcutBuilder {
+	b.stringData.IconLocation = iconLocation
+	b.header.IconIndex = iconIndex
+	return b
+}
+
+// HotKey sets the hot key, formatted like HotKeyName, e.g. "Ctrl+Alt+F5".
+func (b *ShortcutBuilder) HotKey(hotKey string) *ShortcutBuilder {
+	parsed, err := ParseHotKey(hotKey)
+	b.setErr(err)
+	b.header.HotKey = parsed
+	return b
+}
+
+// ShowCommand sets the window state, SW_SHOWNORMAL, SW_SHOWMAXIMIZED or SW_SHOWMINNOACTIVE.
+func (b *ShortcutBuilder) ShowCommand(showCommand uint32) *ShortcutBuilder {
+	if showCommand != SW_SHOWNORMAL && showCommand != SW_SHOWMAXIMIZED && showCommand != SW_SHOWMINNOACTIVE {
+		b.setErr(fmt.Errorf("invalid ShowCommand 0x%X", showCommand))
+	}
+	b.header.ShowCommand = showCommand
+	return b
+}
+
+// Description sets the description, stored as NameString.
+func (b *ShortcutBuilder) Description(description string) *ShortcutBuilder {
+	b.stringData.NameString = description
+	return b
+}
+
+// Environment adds an EnvironmentVariableDataBlock with a target path containing environment variables,
+// e.g. %ProgramFiles%\App\app.exe.
+func (b *ShortcutBuilder) Environment(target string) *ShortcutBuilder {
+	b.environment = target
+	return b
+}
+
+// KnownFolder adds a KnownFolderDataBlock, offset is the offset of the item ID following the known folder
+// in the LinkTargetIDList. It needs IDList, Bytes fails if offset is not on an ItemID boundary.
+func (b *ShortcutBuilder) KnownFolder(knownFolderID GUID, offset int32) *ShortcutBuilder {
+	b.knownFolders = append(b.knownFolders, KnownFolderDataBlock{KnownFolderID: knownFolderID, Offset: offset})
+	return b
+}
+
+// IDList adds a LinkTargetIDList for the target path, items describe its names from the first one below
+// the drive or share, see IDListForPath.
+func (b *ShortcutBuilder) IDList(items ...PathItem) *ShortcutBuilder {
+	b.idList = items
+	b.hasIDList = true
+	return b
+}
+
+// FileAttributes sets the FILE_ATTRIBUTE_* flags of the target.
+func (b *ShortcutBuilder) FileAttributes(fileAttributes uint32) *ShortcutBuilder {
+	b.header.FileAttributes = fileAttributes
+	return b
+}
+
+// Times sets the creation, access and write time of the target, zero times are left unset.
+func (b *ShortcutBuilder) Times(creationTime time.Time, accessTime time.Time, writeTime time.Time) *ShortcutBuilder {
+	b.header.CreationTime = timeToFiletime(creationTime)
+	b.header.AccessTime = timeToFiletime(accessTime)
+	b.header.WriteTime = timeToFiletime(writeTime)
+	return b
+}
+
+// FileSize sets the size of the target, the low 32 bits for files of 4 GiB and more.
+func (b *ShortcutBuilder) FileSize(fileSize uint64) *ShortcutBuilder {
+	b.header.FileSize = uint32(fileSize)
+	return b
+}
+
+// Volume sets the VolumeID of a local target, by default a DRIVE_FIXED volume without serial number and label.
+func (b *ShortcutBuilder) Volume(driveType uint32, driveSerialNumber uint32, volumeLabel string) *ShortcutBuilder {
+	if _, ok := driveTypeNames[driveType]; !ok {
+		b.setErr(fmt.Errorf("invalid DriveType 0x%X", driveType))
+	}
+	b.volumeID = VolumeID{DriveType: driveType, DriveSerialNumber: driveSerialNumber, VolumeLabel: volumeLabel}
+	return b
+}
+
+// CodePage sets the code page of the ANSI strings in LinkInfo and ExtraData, windows-1252 by default.
+func (b *ShortcutBuilder) CodePage(codePage *CodePage) *ShortcutBuilder {
+	b.codePage = codePage
+	return b
+}
+
+func (b *ShortcutBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build returns the shortcut as it is parsed from Bytes.
+func (b *ShortcutBuilder) Build() (ShellLinkParsed, error) {
+	data, err := b.Bytes()
+	if err != nil {
+		return ShellLinkParsed{}, err
+	}
+	return ParseDataWithOptions(bytes.NewReader(data), ParseOptions{CodePage: b.codePage})
+}
+
+// Bytes returns the shortcut as .lnk file. LinkFlags are set for the fields that were set,
+// strings are stored as Unicode with an ANSI copy in LinkInfo.
+func (b *ShortcutBuilder) Bytes() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	linkInfo, err := linkInfoForPath(b.targetPath, b.volumeID, b.codePage)
+	if err != nil {
+		return nil, err
+	}
+
+	shellLink := ShellLinkParsed{
+		Header:     b.header,
+		LinkInfo:   linkInfo,
+		StringData: b.stringData,
+		CodePage:   b.codePage,
+	}
+	shellLink.Header.LinkFlags = IsUnicode | HasLinkInfo
+	if b.hasIDList {
+		itemIDs, err := IDListForPath(b.targetPath, b.idList, b.codePage)
+		if err != nil {
+			return nil, err
+		}
+		shellLink.Header.LinkFlags |= HasLinkTargetIDList
+		shellLink.LinkTargetIDList = &LinkTargetIDList{IDListData: IDList{ItemIDs: itemIDs}}
+	}
+	for _, item := range []struct {
+		str  string
+		flag uint32
+	}{
+		{b.stringData.NameString, HasName},
+		{b.stringData.WorkingDir, HasWorkingDir},
+		{b.stringData.CommandLineArgs, HasArguments},
+		{b.stringData.IconLocation, HasIconLocation},
+	} {
+		if item.str != "" {
+			shellLink.Header.LinkFlags |= item.flag
+		}
+	}
+	if b.environment != "" {
+		shellLink.Header.LinkFlags |= HasExpString
+		shellLink.ExtraData = append(shellLink.ExtraData, ExtraDataBlock{EnvironmentVariableDataBlock: &EnvironmentVariableDataBlock{
+			TargetAnsi:    ansiFallback(b.environment, b.codePage),
+			TargetUnicode: b.environment,
+		}})
+	}
+	for i := range b.knownFolders {
+		// the Offset means nothing without the IDList it points into
+		if shellLink.LinkTargetIDList == nil {
+			return nil, errors.New("KnownFolder needs a LinkTargetIDList, call IDList")
+		}
+		if splitItemIDs(shellLink.LinkTargetIDList.IDListData.ItemIDs, b.knownFolders[i].Offset) < 0 {
+			return nil, fmt.Errorf("KnownFolder Offset %v is not on an ItemID boundary of the LinkTargetIDList", b.knownFolders[i].Offset)
+		}
+		shellLink.ExtraData = append(shellLink.ExtraData, ExtraDataBlock{KnownFolderDataBlock: &b.knownFolders[i]})
+	}
+	return Encode(&shellLink)
+}
+
+// linkInfoForPath returns the LinkInfo of a local or UNC path, volumeID is used for a local path.
+// Strings the code page cannot represent get a Unicode copy.
+func linkInfoForPath(path string, volumeID VolumeID, codePage *CodePage) (*LinkInfo, error) {
+	isDrivePath := len(path) >= 3 && path[1] == ':' && path[2] == '\\' &&
+		(path[0] >= 'A' && path[0] <= 'Z' || path[0] >= 'a' && path[0] <= 'z')
+	if isDrivePath {
+		linkInfo := &LinkInfo{
+			LinkInfoFlags: VolumeIDAndLocalBasePathPresent,
+			VolumeID:      volumeID,
+			LocalBasePath: ansiFallback(path, codePage),
+		}
+		if linkInfo.LocalBasePath != path {
+			linkInfo.LocalBasePathUnicode = path
+		}
+		if label := volumeID.VolumeLabel; ansiFallback(label, codePage) != label {
+			linkInfo.VolumeID.VolumeLabelUnicode = label
+		}
+		return linkInfo, nil
+	}
+
+	if !strings.HasPrefix(path, `\\`) {
+		return nil, fmt.Errorf("target path %q is neither a local nor a UNC path", path)
+	}
+	parts := strings.SplitN(path[2:], `\`, 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.New("UNC target path must start with \\\\server\\share")
+	}
+	netName := `\\` + parts[0] + `\` + parts[1]
+	commonPathSuffix := ""
+	if len(parts) == 3 {
+		commonPathSuffix = parts[2]
+	}
+	linkInfo := &LinkInfo{
+		LinkInfoFlags: CommonNetworkRelativeLinkAndPathSuffixPresent,
+		CommonNetworkRelativeLink: &CommonNetworkRelativeLink{
+			CommonNetworkRelativeLinkFlags: ValidNetType,
+			NetworkProviderType:            WNNC_NET_LANMAN,
+			NetName:                        ansiFallback(netName, codePage),
+		},
+		CommonPathSuffix: ansiFallback(commonPathSuffix, codePage),
+	}
+	if linkInfo.CommonNetworkRelativeLink.NetName != netName {
+		linkInfo.CommonNetworkRelativeLink.NetNameUnicode = netName
+	}
+	if linkInfo.CommonPathSuffix != commonPathSuffix {
+		linkInfo.CommonPathSuffixUnicode = commonPathSuffix
+	}
+	return linkInfo, nil
+}
+
+// ansiFallback replaces the characters the code page cannot represent with '?', as Windows does for ANSI copies.
+func ansiFallback(s string, codePage *CodePage) string {
+	if _, err := codePage.Encode(s); err == nil {
+		return s
+	}
+	var fallback strings.Builder
+	for _, r := range s {
+		if _, err := codePage.Encode(string(r)); err != nil {
+			r = '?'
+		}
+		fallback.WriteRune(r)
+	}
+	return fallback.String()
+}
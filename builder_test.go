@@ -0,0 +1,188 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ShortcutBuilder(t *testing.T) {
+	programFiles := mustParseGUID("905E63B6-C1BF-494E-B29C-65B732D3D21A")
+	writeTime := time.Date(2023, 5, 1, 12, 30, 0, 0, time.UTC)
+	itemIDs, err := IDListForPath(`C:\Program Files\App\app.exe`, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the root, volume and Program Files items
+	var appOffset int32
+	for _, itemID := range itemIDs[:3] {
+		appOffset += int32(itemID.ItemIDSize)
+	}
+	tests := []struct {
+		name    string
+		builder *ShortcutBuilder
+		check   func(*testing.T, ShellLinkParsed)
+		wantErr bool
+	}{
+		{
+			name: "local target",
+			builder: NewShortcut(`C:\Program Files\App\app.exe`).
+				Arguments("-v --log=x.log").
+				WorkingDir(`C:\Program Files\App`).
+				Icon(`C:\Program Files\App\app.ico`, 2).
+				HotKey("Ctrl+Alt+A").
+				ShowCommand(SW_SHOWMAXIMIZED).
+				Description("App").
+				Environment(`%ProgramFiles%\App\app.exe`).
+				FileAttributes(FileAttributeArchive).
+				Times(time.Time{}, time.Time{}, writeTime).
+				FileSize(1234).
+				Volume(DriveFixed, 0x1234ABCD, "System"),
+			check: func(t *testing.T, s ShellLinkParsed) {
+				wantFlags := IsUnicode | HasLinkInfo | HasName | HasWorkingDir | HasArguments | HasIconLocation | HasExpString
+				if s.Header.LinkFlags != wantFlags {
+					t.Errorf("LinkFlags = 0x%08X, want 0x%08X", s.Header.LinkFlags, wantFlags)
+				}
+				wantStringData := StringData{
+					NameString:      "App",
+					WorkingDir:      `C:\Program Files\App`,
+					CommandLineArgs: "-v --log=x.log",
+					IconLocation:    `C:\Program Files\App\app.ico`,
+				}
+				gotStringData := s.StringData
+				gotStringData.NameStringBase64, gotStringData.WorkingDirBase64, gotStringData.CommandLineArgsBase64, gotStringData.IconLocationBase64 = "", "", "", ""
+				if !reflect.DeepEqual(gotStringData, wantStringData) {
+					t.Errorf("StringData = %+v, want %+v", gotStringData, wantStringData)
+				}
+				if s.HeaderParsed.HotKey != "Ctrl+Alt+A" || s.HeaderParsed.ShowCommand != "SW_SHOWMAXIMIZED" || s.Header.IconIndex != 2 {
+					t.Errorf("HeaderParsed = %+v, IconIndex %v", s.HeaderParsed, s.Header.IconIndex)
+				}
+				if s.HeaderParsed.CreationTime != nil || s.HeaderParsed.WriteTime == nil || !s.HeaderParsed.WriteTime.Equal(writeTime) {
+					t.Errorf("HeaderParsed times = %v, %v", s.HeaderParsed.CreationTime, s.HeaderParsed.WriteTime)
+				}
+				if s.Header.FileSize != 1234 || !s.FileAttributesParsed.FileAttributeArchive {
+					t.Errorf("FileSize = %v, FileAttributes = 0x%X", s.Header.FileSize, s.Header.FileAttributes)
+				}
+				if s.LinkInfo.LocalBasePath != `C:\Program Files\App\app.exe` || s.LinkInfo.LinkInfoHeaderSize != LinkInfoHeaderSizeOptionalFieldsNotSpecified {
+					t.Errorf("LinkInfo = %+v", s.LinkInfo)
+				}
+				if s.LinkInfo.VolumeID.VolumeLabel != "System" || s.LinkInfo.VolumeID.DriveSerialNumberFormatted != "1234-ABCD" {
+					t.Errorf("VolumeID = %+v", s.LinkInfo.VolumeID)
+				}
+				if len(s.ExtraData) != 1 || s.ExtraData[0].EnvironmentVariableDataBlock.TargetUnicode != `%ProgramFiles%\App\app.exe` {
+					t.Errorf("ExtraData = %+v", s.ExtraData)
+				}
+				if violations := Validate(&s); len(violations) != 0 {
+					t.Errorf("Validate() = %+v", violations)
+				}
+			},
+		},
+		{
+			name:    "UNC target outside the code page",
+			builder: NewShortcut(`\\server\share\Отчёт.xlsx`),
+			check: func(t *testing.T, s ShellLinkParsed) {
+				if s.Header.LinkFlags != IsUnicode|HasLinkInfo {
+					t.Errorf("LinkFlags = 0x%08X", s.Header.LinkFlags)
+				}
+				link := s.LinkInfo.CommonNetworkRelativeLink
+				if link == nil || link.NetName != `\\server\share` || link.NetworkProviderType != WNNC_NET_LANMAN {
+					t.Fatalf("CommonNetworkRelativeLink = %+v", link)
+				}
+				if s.LinkInfo.CommonPathSuffix != "?????.xlsx" || s.LinkInfo.CommonPathSuffixUnicode != "Отчёт.xlsx" {
+					t.Errorf("CommonPathSuffix = %q, CommonPathSuffixUnicode = %q", s.LinkInfo.CommonPathSuffix, s.LinkInfo.CommonPathSuffixUnicode)
+				}
+				if s.Target == nil || s.Target.Path != `\\server\share\Отчёт.xlsx` {
+					t.Errorf("Target = %+v", s.Target)
+				}
+			},
+		},
+		{
+			name:    "code page",
+			builder: NewShortcut(`D:\Отчёт.xlsx`).CodePage(CodePageByID(1251)),
+			check: func(t *testing.T, s ShellLinkParsed) {
+				if s.LinkInfo.LocalBasePath != `D:\Отчёт.xlsx` || s.LinkInfo.LocalBasePathUnicode != "" {
+					t.Errorf("LocalBasePath = %q, LocalBasePathUnicode = %q", s.LinkInfo.LocalBasePath, s.LinkInfo.LocalBasePathUnicode)
+				}
+			},
+		},
+		{
+			name:    "IDList",
+			builder: NewShortcut(`C:\Tools\app.exe`).IDList(PathItem{}, PathItem{FileSize: 1234, ModificationTime: writeTime}),
+			check: func(t *testing.T, s ShellLinkParsed) {
+				if !s.LinkFlagsParsed.HasLinkTargetIDList || s.LinkTargetIDList == nil || len(s.LinkTargetIDList.IDListData.ItemIDs) != 4 {
+					t.Fatalf("LinkFlags = 0x%08X, LinkTargetIDList = %+v", s.Header.LinkFlags, s.LinkTargetIDList)
+				}
+				if s.LinkTargetIDList.IDListData.Path != `C:\Tools\app.exe` || s.Target.Conflict {
+					t.Errorf("IDListData.Path = %q, Target = %+v", s.LinkTargetIDList.IDListData.Path, s.Target)
+				}
+				if violations := Validate(&s); len(violations) != 0 {
+					t.Errorf("Validate() = %+v", violations)
+				}
+			},
+		},
+		{
+			name:    "known folder",
+			builder: NewShortcut(`C:\Program Files\App\app.exe`).IDList().KnownFolder(programFiles, appOffset),
+			check: func(t *testing.T, s ShellLinkParsed) {
+				if len(s.ExtraData) != 1 || s.ExtraData[0].KnownFolderDataBlock.KnownFolderName != "FOLDERID_ProgramFiles" {
+					t.Fatalf("ExtraData = %+v", s.ExtraData)
+				}
+				if s.KnownFolderPath == nil || s.KnownFolderPath.FolderIDList != `C:\Program Files` || s.KnownFolderPath.RelativeIDList != `App\app.exe` {
+					t.Errorf("KnownFolderPath = %+v", s.KnownFolderPath)
+				}
+			},
+		},
+		{
+			name:    "known folder without IDList",
+			builder: NewShortcut(`C:\Program Files\App\app.exe`).KnownFolder(programFiles, appOffset),
+			wantErr: true,
+		},
+		{
+			name:    "known folder offset inside an ItemID",
+			builder: NewShortcut(`C:\Program Files\App\app.exe`).IDList().KnownFolder(programFiles, appOffset+1),
+			wantErr: true,
+		},
+		{
+			name:    "IDList with more items than names",
+			builder: NewShortcut(`C:\app.exe`).IDList(PathItem{}, PathItem{}),
+			wantErr: true,
+		},
+		{
+			name:    "relative target",
+			builder: NewShortcut(`app.exe`),
+			wantErr: true,
+		},
+		{
+			name:    "UNC target without share",
+			builder: NewShortcut(`\\server`),
+			wantErr: true,
+		},
+		{
+			name:    "invalid hot key",
+			builder: NewShortcut(`C:\app.exe`).HotKey("Win+A"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid show command",
+			builder: NewShortcut(`C:\app.exe`).ShowCommand(0),
+			wantErr: true,
+		},
+		{
+			name:    "environment target too long",
+			builder: NewShortcut(`C:\app.exe`).Environment(strings.Repeat("x", 300)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				tt.check(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,800 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ByteRange locates a field or structure in a .lnk file.
+type ByteRange struct {
+	Offset int64  `json:"Offset"`          // absolute file offset
+	Size   int64  `json:"Size"`            // in bytes
+	Field  string `json:"Field,omitempty"` // e.g. "LinkInfo.VolumeID.DriveType", empty for uncovered bytes
+	Value  string `json:"Value,omitempty"` // decoded value
+}
+
+// ByteMap locates every decoded field of a .lnk file. Structures are the sections and the structures
+// within them, Fields the values they consist of; bytes that are not part of any field are Uncovered.
+type ByteMap struct {
+	Size       int64       `json:"Size"`
+	Structures []ByteRange `json:"Structures"`
+	Fields     []ByteRange `json:"Fields"`
+	Uncovered  []ByteRange `json:"Uncovered"`
+}
+
+// extraDataBlockNames maps BlockSignature values to the names of their blocks.
+var extraDataBlockNames = map[uint32]string{
+	ConsoleDataBlockSignature:             "ConsoleDataBlock",
+	ConsoleFEDataBlockSignature:           "ConsoleFEDataBlock",
+	DarwinDataBlockSignature:              "DarwinDataBlock",
+	EnviromentVariableDataBlockSignature:  "EnvironmentVariableDataBlock",
+	IconEnviromentDataBlockSignature:      "IconEnvironmentDataBlock",
+	KnownFolderDataBlockSignature:         "KnownFolderDataBlock",
+	PropertyStoreDataBlockSignature:       "PropertyStoreDataBlock",
+	ShimDataBlockSignature:                "ShimDataBlock",
+	SpecialFolderDataBlockSignature:       "SpecialFolderDataBlock",
+	TrackerDataBlockSignature:             "TrackerDataBlock",
+	VistaAndAboveIDListDataBlockSignature: "VistaAndAboveIDListDataBlock",
+}
+
+// MapBytes locates the fields of a .lnk file in file order, ANSI strings are decoded in codePage.
+// It follows the layout rules of the parser, a section the parser could not locate is left uncovered
+// together with everything after it.
+func MapBytes(data []byte, codePage *CodePage) ByteMap {
+	m := byteMapper{data: data, codePage: codePage}
+	m.byteMap.Size = int64(len(data))
+	m.byteMap.Structures = []ByteRange{}
+	m.byteMap.Fields = []ByteRange{}
+	m.mapSections()
+
+	sort.SliceStable(m.byteMap.Structures, func(i, j int) bool {
+		a, b := m.byteMap.Structures[i], m.byteMap.Structures[j]
+		return a.Offset < b.Offset || a.Offset == b.Offset && a.Size > b.Size
+	})
+	sort.SliceStable(m.byteMap.Fields, func(i, j int) bool {
+		return m.byteMap.Fields[i].Offset < m.byteMap.Fields[j].Offset
+	})
+	m.byteMap.Uncovered = uncoveredRanges(len(data), m.byteMap.Fields)
+	return m.byteMap
+}
+
+// uncoveredRanges returns the ranges of size bytes that none of fields covers.
+func uncoveredRanges(size int, fields []ByteRange) []ByteRange {
+	covered := make([]bool, size)
+	for _, field := range fields {
+		for i := field.Offset; i < field.Offset+field.Size; i++ {
+			covered[i] = true
+		}
+	}
+	uncovered := []ByteRange{}
+	for i := 0; i < size; {
+		if covered[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < size && !covered[i] {
+			i++
+		}
+		uncovered = append(uncovered, ByteRange{Offset: int64(start), Size: int64(i - start)})
+	}
+	return uncovered
+}
+
+type byteMapper struct {
+	data     []byte
+	codePage *CodePage
+	byteMap  ByteMap
+}
+
+// fits reports whether size bytes at offset are within end and the data.
+func (m *byteMapper) fits(offset int, size int, end int) bool {
+	return offset >= 0 && size >= 0 && offset+size <= end && offset+size <= len(m.data)
+}
+
+func (m *byteMapper) structure(name string, offset int, size int) {
+	m.byteMap.Structures = append(m.byteMap.Structures, ByteRange{Offset: int64(offset), Size: int64(size), Field: name})
+}
+
+func (m *byteMapper) field(name string, offset int, size int, value string) {
+	m.byteMap.Fields = append(m.byteMap.Fields, ByteRange{Offset: int64(offset), Size: int64(size), Field: name, Value: value})
+}
+
+func (m *byteMapper) uint16(name string, offset int) uint16 {
+	v := binary.LittleEndian.Uint16(m.data[offset:])
+	m.field(name, offset, 2, strconv.FormatUint(uint64(v), 10))
+	return v
+}
+
+func (m *byteMapper) uint32(name string, offset int) uint32 {
+	v := binary.LittleEndian.Uint32(m.data[offset:])
+	m.field(name, offset, 4, strconv.FormatUint(uint64(v), 10))
+	return v
+}
+
+func (m *byteMapper) int32(name string, offset int) {
+	m.field(name, offset, 4, strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(m.data[offset:]))), 10))
+}
+
+// flags maps a uint32 shown in hex, with name appended if it is known.
+func (m *byteMapper) flags(name string, offset int, valueName string) uint32 {
+	v := binary.LittleEndian.Uint32(m.data[offset:])
+	m.field(name, offset, 4, strings.TrimSpace(fmt.Sprintf("0x%08X %v", v, valueName)))
+	return v
+}
+
+// named maps a uint32 followed by the name of its value.
+func (m *byteMapper) named(name string, offset int, valueName func(uint32) string) uint32 {
+	v := binary.LittleEndian.Uint32(m.data[offset:])
+	m.field(name, offset, 4, strings.TrimSpace(fmt.Sprintf("%v %v", v, valueName(v))))
+	return v
+}
+
+func (m *byteMapper) filetime(name string, offset int) {
+	value := "0"
+	if ft := binary.LittleEndian.Uint64(m.data[offset:]); ft != 0 {
+		value = filetimeToTime(ft).Format(time.RFC3339Nano)
+	}
+	m.field(name, offset, 8, value)
+}
+
+func (m *byteMapper) guid(name string, offset int) GUID {
+	var guid GUID
+	copy(guid[:], m.data[offset:])
+	m.field(name, offset, 16, guid.String())
+	return guid
+}
+
+// ansiString maps a null-terminated ANSI string at offset that ends before end, the terminator included.
+func (m *byteMapper) ansiString(name string, offset int, end int) {
+	if !m.fits(offset, 1, end) {
+		return
+	}
+	size := bytes.IndexByte(m.data[offset:end], 0)
+	if size < 0 {
+		return
+	}
+	m.field(name, offset, size+1, m.codePage.Decode(m.data[offset:offset+size]))
+}
+
+// unicodeString maps a null-terminated UTF-16LE string at offset that ends before end, the terminator included.
+func (m *byteMapper) unicodeString(name string, offset int, end int) {
+	for i := offset; m.fits(i, 2, end); i += 2 {
+		if m.data[i] == 0 && m.data[i+1] == 0 {
+			m.field(name, offset, i+2-offset, decodeUTF16LE(m.data[offset:i]))
+			return
+		}
+	}
+}
+
+// mapSections maps the sections in file order, as parseSections reads them.
+func (m *byteMapper) mapSections() {
+	if !m.fits(0, int(HeaderSizeExpected), len(m.data)) {
+		return
+	}
+	linkFlags := m.mapHeader()
+	offset := int(HeaderSizeExpected)
+	var ok bool
+
+	if linkFlags&HasLinkTargetIDList != 0 {
+		if offset, ok = m.mapLinkTargetIDList(offset); !ok {
+			return
+		}
+	}
+	if linkFlags&HasLinkInfo != 0 {
+		if offset, ok = m.mapLinkInfo(offset); !ok {
+			return
+		}
+	}
+	if offset, ok = m.mapStringData(offset, ParseLinkFlags(linkFlags)); !ok {
+		return
+	}
+	m.mapExtraData(offset)
+}
+
+func (m *byteMapper) mapHeader() uint32 {
+	m.structure("ShellLinkHeader", 0, int(HeaderSizeExpected))
+	m.uint32("ShellLinkHeader.HeaderSize", 0)
+	m.guid("ShellLinkHeader.LinkCLSID", 4)
+	linkFlags := m.flags("ShellLinkHeader.LinkFlags", 20, "")
+	m.flags("ShellLinkHeader.FileAttributes", 24, "")
+	m.filetime("ShellLinkHeader.CreationTime", 28)
+	m.filetime("ShellLinkHeader.AccessTime", 36)
+	m.filetime("ShellLinkHeader.WriteTime", 44)
+	m.uint32("ShellLinkHeader.FileSize", 52)
+	m.int32("ShellLinkHeader.IconIndex", 56)
+	m.named("ShellLinkHeader.ShowCommand", 60, ShowCommandName)
+	hotKey := binary.LittleEndian.Uint16(m.data[64:])
+	m.field("ShellLinkHeader.HotKey", 64, 2, strings.TrimSpace(fmt.Sprintf("0x%04X %v", hotKey, HotKeyName(hotKey))))
+	m.uint16("ShellLinkHeader.Reserved1", 66)
+	m.uint32("ShellLinkHeader.Reserved2", 68)
+	m.uint32("ShellLinkHeader.Reserved3", 72)
+	return linkFlags
+}
+
+// mapLinkTargetIDList maps the LinkTargetIDList at offset and returns the offset after it,
+// ok is false if it does not fit in the file.
+func (m *byteMapper) mapLinkTargetIDList(offset int) (next int, ok bool) {
+	if !m.fits(offset, 2, len(m.data)) {
+		return offset, false
+	}
+	idListSize := int(binary.LittleEndian.Uint16(m.data[offset:]))
+	if !m.fits(offset+2, idListSize, len(m.data)) {
+		m.uint16("LinkTargetIDList.IDListSize", offset)
+		return offset, false
+	}
+	m.structure("LinkTargetIDList", offset, 2+idListSize)
+	m.uint16("LinkTargetIDList.IDListSize", offset)
+	m.mapIDList("LinkTargetIDList.IDList", offset+2, offset+2+idListSize)
+	return offset + 2 + idListSize, true
+}
+
+// mapIDList maps the ItemIDs from offset to end up to the TerminalID.
+func (m *byteMapper) mapIDList(name string, offset int, end int) {
+	for i := 0; m.fits(offset, 2, end); i++ {
+		itemIDSize := int(binary.LittleEndian.Uint16(m.data[offset:]))
+		if itemIDSize == 0 {
+			m.field(name+".TerminalID", offset, 2, "0")
+			return
+		}
+		itemName := fmt.Sprintf("%v[%d]", name, i)
+		if itemIDSize < 2 || !m.fits(offset, itemIDSize, end) {
+			m.uint16(itemName+".ItemIDSize", offset)
+			return
+		}
+		m.structure(itemName, offset, itemIDSize)
+		m.uint16(itemName+".ItemIDSize", offset)
+		m.mapShellItem(itemName, offset+2, offset+itemIDSize)
+		offset += itemIDSize
+	}
+}
+
+// mapShellItem maps the ItemIDData from offset to end as ParseShellItem decodes it. Bytes of a known
+// shell item that are not decoded are mapped as Data, an unknown shell item is mapped as Data as a whole.
+func (m *byteMapper) mapShellItem(name string, offset int, end int) {
+	if end <= offset {
+		return
+	}
+	itemIDData := m.data[offset:end]
+	shellItem := ParseShellItem(itemIDData, m.codePage)
+	if shellItem.ClassTypeName == "Unknown" {
+		m.field(name+".Data", offset, len(itemIDData), describeItemID(ItemID{ItemIDData: itemIDData, ShellItem: shellItem}))
+		return
+	}
+
+	fields := len(m.byteMap.Fields)
+	m.field(name+".ClassType", offset, 1, fmt.Sprintf("0x%02X %v", shellItem.ClassType, shellItem.ClassTypeName))
+	switch {
+	case shellItem.RootFolder != nil:
+		m.field(name+".SortIndex", offset+1, 1, strconv.Itoa(int(shellItem.RootFolder.SortIndex)))
+		m.field(name+".ShellFolderID", offset+2, 16, strings.TrimSpace(shellItem.RootFolder.ShellFolderID.String()+" "+shellItem.RootFolder.ShellFolderName))
+	case shellItem.Volume != nil && shellItem.Volume.ShellFolderID != nil:
+		m.field(name+".ShellFolderID", offset+VolumeShellItemShellFolderIDOffset, 16, strings.TrimSpace(shellItem.Volume.ShellFolderID.String()+" "+shellItem.Volume.ShellFolderName))
+	case shellItem.Volume != nil && shellItem.Volume.Flags&VolumeShellItemHasName != 0:
+		m.ansiString(name+".Name", offset+1, end)
+	case shellItem.FileEntry != nil:
+		m.mapFileEntryShellItem(name, shellItem.FileEntry, offset, end)
+	case shellItem.NetworkLocation != nil:
+		m.field(name+".Flags", offset+2, 1, fmt.Sprintf("0x%02X", shellItem.NetworkLocation.Flags))
+		next := m.cutANSIString(name+".Location", offset+3, end)
+		if shellItem.NetworkLocation.Flags&NetworkLocationShellItemHasDescription != 0 {
+			next = m.cutANSIString(name+".Description", next, end)
+		}
+		if shellItem.NetworkLocation.Flags&NetworkLocationShellItemHasComments != 0 {
+			m.cutANSIString(name+".Comments", next, end)
+		}
+	}
+	m.fillGaps(name+".Data", offset, end, fields)
+}
+
+func (m *byteMapper) mapFileEntryShellItem(name string, fileEntry *FileEntryShellItem, offset int, end int) {
+	m.uint32(name+".FileSize", offset+2)
+	m.fatTime(name+".ModificationTime", offset+6)
+	m.field(name+".FileAttributes", offset+10, 2, fmt.Sprintf("0x%04X", fileEntry.FileAttributes))
+	offset += FileEntryShellItemPrimaryNameOffset
+	var n int
+	if fileEntry.IsUnicode {
+		_, n = cutZeroTerminatedUnicode(m.data[offset:end])
+	} else {
+		_, n = cutZeroTerminatedANSI(m.data[offset:end], m.codePage)
+	}
+	m.field(name+".PrimaryName", offset, n, fileEntry.PrimaryName)
+	offset += n
+
+	// the extension blocks are located as ParseExtensionBlocks locates them
+	next := findExtensionBlock(m.data[offset:end])
+	for i := 0; next >= 0 && i < len(fileEntry.ExtensionBlocks); i++ {
+		offset += next
+		extensionBlock := fileEntry.ExtensionBlocks[i]
+		blockName := fmt.Sprintf("%v.ExtensionBlocks[%d]", name, i)
+		blockEnd := offset + int(extensionBlock.Size)
+		m.structure(blockName, offset, int(extensionBlock.Size))
+		m.mapExtensionBlock(blockName, extensionBlock, offset, blockEnd)
+		offset, next = blockEnd, 0
+	}
+}
+
+// mapExtensionBlock maps an extension block from offset to end as parseExtensionBlock decodes it,
+// bytes that are not decoded are mapped as Data.
+func (m *byteMapper) mapExtensionBlock(name string, extensionBlock ExtensionBlock, offset int, end int) {
+	fields := len(m.byteMap.Fields)
+	m.uint16(name+".Size", offset)
+	m.uint16(name+".Version", offset+2)
+	m.field(name+".Signature", offset+4, 4, fmt.Sprintf("0x%08X %v", extensionBlock.Signature, extensionBlock.SignatureName))
+	if fileEntry := extensionBlock.FileEntry; fileEntry != nil {
+		m.fatTime(name+".CreationTime", offset+8)
+		m.fatTime(name+".AccessTime", offset+12)
+		m.field(name+".Identifier", offset+16, 2, strings.TrimSpace(fmt.Sprintf("0x%04X %v", fileEntry.Identifier, fileEntry.IdentifierName)))
+		// the names end before the trailing FirstExtensionBlockVersionOffset
+		bodyEnd := end - 2
+		next := offset + 18
+		if extensionBlock.Version >= FileEntryExtensionBlockNTFSVersionMin {
+			if fileEntry.NTFSFileReference != nil {
+				reference := fileEntry.NTFSFileReference
+				m.field(name+".NTFSFileReference", offset+FileEntryExtensionBlockNTFSOffset, 8, fmt.Sprintf("MFT entry %v, sequence number %v", reference.MFTEntry, reference.SequenceNumber))
+			}
+			next = offset + FileEntryExtensionBlockVersionOffset
+		}
+		names := true
+		if extensionBlock.Version >= 3 {
+			if names = m.fits(next, 2, bodyEnd); names {
+				m.uint16(name+".LongStringSize", next)
+				next += 2
+			}
+		}
+		if extensionBlock.Version >= 9 {
+			next += 4
+		}
+		if extensionBlock.Version >= 8 {
+			next += 4
+		}
+		if names && next <= bodyEnd {
+			_, n := cutZeroTerminatedUnicode(m.data[next:bodyEnd])
+			if n > 0 {
+				m.field(name+".LongName", next, n, fileEntry.LongName)
+			}
+			next += n
+			if extensionBlock.Version >= 3 && fileEntry.LongStringSize > 0 && next < bodyEnd {
+				if extensionBlock.Version >= 7 {
+					_, n = cutZeroTerminatedUnicode(m.data[next:bodyEnd])
+				} else {
+					_, n = cutZeroTerminatedANSI(m.data[next:bodyEnd], m.codePage)
+				}
+				m.field(name+".LocalizedName", next, n, fileEntry.LocalizedName)
+			}
+		}
+		m.uint16(name+".FirstExtensionBlockVersionOffset", bodyEnd)
+	} else if extensionBlock.ShellFolderID != nil {
+		m.field(name+".ShellFolderID", offset+ExtensionBlockHeaderSize, 16, strings.TrimSpace(extensionBlock.ShellFolderID.String()+" "+shellFolderNames[*extensionBlock.ShellFolderID]))
+	}
+	m.fillGaps(name+".Data", offset, end, fields)
+}
+
+// cutANSIString maps a null-terminated ANSI string at offset as cutZeroTerminatedANSI decodes it
+// and returns the offset after it.
+func (m *byteMapper) cutANSIString(name string, offset int, end int) int {
+	if offset >= end {
+		return end
+	}
+	value, n := cutZeroTerminatedANSI(m.data[offset:end], m.codePage)
+	m.field(name, offset, n, value)
+	return offset + n
+}
+
+// fatTime maps a FAT date and time, the raw value if it is not a valid time.
+func (m *byteMapper) fatTime(name string, offset int) {
+	raw := binary.LittleEndian.Uint32(m.data[offset:])
+	value := strconv.FormatUint(uint64(raw), 10)
+	if t := fatToTime(raw); t != nil {
+		value = t.Format(time.RFC3339)
+	}
+	m.field(name, offset, 4, value)
+}
+
+// fillGaps maps the bytes from offset to end that none of the fields added since index fields covers as name.
+func (m *byteMapper) fillGaps(name string, offset int, end int, fields int) {
+	added := append([]ByteRange{}, m.byteMap.Fields[fields:]...)
+	sort.SliceStable(added, func(i, j int) bool {
+		return added[i].Offset < added[j].Offset
+	})
+	for _, field := range added {
+		if int(field.Offset) > offset {
+			m.field(name, offset, int(field.Offset)-offset, "")
+		}
+		if fieldEnd := int(field.Offset + field.Size); fieldEnd > offset {
+			offset = fieldEnd
+		}
+	}
+	if end > offset {
+		m.field(name, offset, end-offset, "")
+	}
+}
+
+// mapLinkInfo maps the LinkInfo at offset and returns the offset after it, ok is false if its size is invalid.
+// The structures and strings within it are located by their offsets, bytes between them stay uncovered.
+func (m *byteMapper) mapLinkInfo(offset int) (next int, ok bool) {
+	if !m.fits(offset, 4, len(m.data)) {
+		return offset, false
+	}
+	linkInfoSize := int(binary.LittleEndian.Uint32(m.data[offset:]))
+	if linkInfoSize < int(LinkInfoHeaderSizeOptionalFieldsNotSpecified) || !m.fits(offset, linkInfoSize, len(m.data)) {
+		m.uint32("LinkInfo.LinkInfoSize", offset)
+		return offset, false
+	}
+	end := offset + linkInfoSize
+	m.structure("LinkInfo", offset, linkInfoSize)
+	m.uint32("LinkInfo.LinkInfoSize", offset)
+	linkInfoHeaderSize := m.uint32("LinkInfo.LinkInfoHeaderSize", offset+4)
+	linkInfoFlags := m.flags("LinkInfo.LinkInfoFlags", offset+8, "")
+	volumeIDOffset := m.uint32("LinkInfo.VolumeIDOffset", offset+12)
+	localBasePathOffset := m.uint32("LinkInfo.LocalBasePathOffset", offset+16)
+	commonNetworkRelativeLinkOffset := m.uint32("LinkInfo.CommonNetworkRelativeLinkOffset", offset+20)
+	commonPathSuffixOffset := m.uint32("LinkInfo.CommonPathSuffixOffset", offset+24)
+	var localBasePathOffsetUnicode, commonPathSuffixOffsetUnicode uint32
+	if linkInfoHeaderSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom && m.fits(offset+28, 8, end) {
+		localBasePathOffsetUnicode = m.uint32("LinkInfo.LocalBasePathOffsetUnicode", offset+28)
+		commonPathSuffixOffsetUnicode = m.uint32("LinkInfo.CommonPathSuffixOffsetUnicode", offset+32)
+	}
+
+	if linkInfoFlags&VolumeIDAndLocalBasePathPresent != 0 {
+		if volumeIDOffset != 0 {
+			m.mapVolumeID(offset+int(volumeIDOffset), end)
+		}
+		if localBasePathOffset != 0 {
+			m.ansiString("LinkInfo.LocalBasePath", offset+int(localBasePathOffset), end)
+		}
+		if localBasePathOffsetUnicode != 0 {
+			m.unicodeString("LinkInfo.LocalBasePathUnicode", offset+int(localBasePathOffsetUnicode), end)
+		}
+	}
+	if linkInfoFlags&CommonNetworkRelativeLinkAndPathSuffixPresent != 0 && commonNetworkRelativeLinkOffset != 0 {
+		m.mapCommonNetworkRelativeLink(offset+int(commonNetworkRelativeLinkOffset), end)
+	}
+	if commonPathSuffixOffset != 0 {
+		m.ansiString("LinkInfo.CommonPathSuffix", offset+int(commonPathSuffixOffset), end)
+	}
+	if commonPathSuffixOffsetUnicode != 0 {
+		m.unicodeString("LinkInfo.CommonPathSuffixUnicode", offset+int(commonPathSuffixOffsetUnicode), end)
+	}
+	return end, true
+}
+
+func (m *byteMapper) mapVolumeID(offset int, end int) {
+	if !m.fits(offset, 4, end) {
+		return
+	}
+	volumeIDSize := int(binary.LittleEndian.Uint32(m.data[offset:]))
+	if volumeIDSize <= int(VolumeIDSizeMin) || !m.fits(offset, volumeIDSize, end) {
+		m.uint32("LinkInfo.VolumeID.VolumeIDSize", offset)
+		return
+	}
+	end = offset + volumeIDSize
+	m.structure("LinkInfo.VolumeID", offset, volumeIDSize)
+	m.uint32("LinkInfo.VolumeID.VolumeIDSize", offset)
+	m.named("LinkInfo.VolumeID.DriveType", offset+4, DriveTypeName)
+	driveSerialNumber := binary.LittleEndian.Uint32(m.data[offset+8:])
+	m.field("LinkInfo.VolumeID.DriveSerialNumber", offset+8, 4, FormatDriveSerialNumber(driveSerialNumber))
+	volumeLabelOffset := m.uint32("LinkInfo.VolumeID.VolumeLabelOffset", offset+12)
+	if volumeLabelOffset != VolumeLabelOffsetUnicodePresent {
+		m.ansiString("LinkInfo.VolumeID.VolumeLabel", offset+int(volumeLabelOffset), end)
+		return
+	}
+	if m.fits(offset+16, 4, end) {
+		volumeLabelOffsetUnicode := m.uint32("LinkInfo.VolumeID.VolumeLabelOffsetUnicode", offset+16)
+		m.unicodeString("LinkInfo.VolumeID.VolumeLabelUnicode", offset+int(volumeLabelOffsetUnicode), end)
+	}
+}
+
+func (m *byteMapper) mapCommonNetworkRelativeLink(offset int, end int) {
+	const name = "LinkInfo.CommonNetworkRelativeLink"
+	if !m.fits(offset, 4, end) {
+		return
+	}
+	size := int(binary.LittleEndian.Uint32(m.data[offset:]))
+	if size < int(CommonNetworkRelativeLinkUnicodeMinOffsets) || !m.fits(offset, size, end) {
+		m.uint32(name+".CommonNetworkRelativeLinkSize", offset)
+		return
+	}
+	end = offset + size
+	m.structure(name, offset, size)
+	m.uint32(name+".CommonNetworkRelativeLinkSize", offset)
+	flags := m.flags(name+".CommonNetworkRelativeLinkFlags", offset+4, "")
+	netNameOffset := m.uint32(name+".NetNameOffset", offset+8)
+	deviceNameOffset := m.uint32(name+".DeviceNameOffset", offset+12)
+	networkProviderType := binary.LittleEndian.Uint32(m.data[offset+16:])
+	m.flags(name+".NetworkProviderType", offset+16, networkProviderNames[networkProviderType])
+	var netNameOffsetUnicode, deviceNameOffsetUnicode uint32
+	if netNameOffset > CommonNetworkRelativeLinkUnicodeMinOffsets && m.fits(offset+20, 8, end) {
+		netNameOffsetUnicode = m.uint32(name+".NetNameOffsetUnicode", offset+20)
+		deviceNameOffsetUnicode = m.uint32(name+".DeviceNameOffsetUnicode", offset+24)
+	}
+
+	validDevice := flags&ValidDevice != 0
+	if netNameOffset != 0 {
+		m.ansiString(name+".NetName", offset+int(netNameOffset), end)
+	}
+	if validDevice && deviceNameOffset != 0 {
+		m.ansiString(name+".DeviceName", offset+int(deviceNameOffset), end)
+	}
+	if netNameOffsetUnicode != 0 {
+		m.unicodeString(name+".NetNameUnicode", offset+int(netNameOffsetUnicode), end)
+	}
+	if validDevice && deviceNameOffsetUnicode != 0 {
+		m.unicodeString(name+".DeviceNameUnicode", offset+int(deviceNameOffsetUnicode), end)
+	}
+}
+
+// mapStringData maps the StringData items the LinkFlags declare and returns the offset after them,
+// ok is false if an item does not fit in the file.
+func (m *byteMapper) mapStringData(offset int, linkFlagsParsed LinkFlagsParsed) (next int, ok bool) {
+	start := offset
+	for _, item := range []struct {
+		name    string
+		present bool
+	}{
+		{"StringData.NameString", linkFlagsParsed.HasName},
+		{"StringData.RelativePath", linkFlagsParsed.HasRelativePath},
+		{"StringData.WorkingDir", linkFlagsParsed.HasWorkingDir},
+		{"StringData.CommandLineArgs", linkFlagsParsed.HasArguments},
+		{"StringData.IconLocation", linkFlagsParsed.HasIconLocation},
+	} {
+		if !item.present {
+			continue
+		}
+		if !m.fits(offset, 2, len(m.data)) {
+			return offset, false
+		}
+		countCharacters := m.uint16(item.name+".CountCharacters", offset)
+		size := int(countCharacters)
+		if linkFlagsParsed.IsUnicode {
+			size *= 2
+		}
+		if !m.fits(offset+2, size, len(m.data)) {
+			return offset, false
+		}
+		str := m.data[offset+2 : offset+2+size]
+		if linkFlagsParsed.IsUnicode {
+			m.field(item.name, offset+2, size, decodeUTF16LE(str))
+		} else {
+			m.field(item.name, offset+2, size, m.codePage.Decode(str))
+		}
+		offset += 2 + size
+	}
+	if offset > start {
+		m.structure("StringData", start, offset-start)
+	}
+	return offset, true
+}
+
+// mapExtraData maps the ExtraData blocks at offset up to the TerminalBlock.
+func (m *byteMapper) mapExtraData(offset int) {
+	for i := 0; m.fits(offset, 4, len(m.data)); i++ {
+		blockSize := int(binary.LittleEndian.Uint32(m.data[offset:]))
+		if blockSize < int(ExtraDataBlockSizeMin) {
+			m.field("ExtraData.TerminalBlock", offset, 4, strconv.Itoa(blockSize))
+			return
+		}
+		name := fmt.Sprintf("ExtraData[%d]", i)
+		if !m.fits(offset, blockSize, len(m.data)) {
+			m.uint32(name+".BlockSize", offset)
+			return
+		}
+		blockSignature := binary.LittleEndian.Uint32(m.data[offset+4:])
+		m.structure(name, offset, blockSize)
+		m.uint32(name+".BlockSize", offset)
+		m.flags(name+".BlockSignature", offset+4, extraDataBlockNames[blockSignature])
+		m.mapExtraDataBlock(name, blockSignature, offset+int(ExtraDataBlockSizeMin), offset+blockSize)
+		offset += blockSize
+	}
+}
+
+// mapExtraDataBlock maps the data of a block from offset to end, fields that do not fit are left uncovered.
+func (m *byteMapper) mapExtraDataBlock(name string, blockSignature uint32, offset int, end int) {
+	name += "."
+	switch blockSignature {
+	case ConsoleDataBlockSignature:
+		var block ConsoleDataBlock
+		m.blockFields(name, offset, end, &block)
+	case ConsoleFEDataBlockSignature:
+		var block ConsoleFEDataBlock
+		m.blockFields(name, offset, end, &block)
+	case DarwinDataBlockSignature:
+		m.ansiUnicodeTarget(name+"DarwinDataAnsi", name+"DarwinDataUnicode", offset, end)
+	case EnviromentVariableDataBlockSignature, IconEnviromentDataBlockSignature:
+		m.ansiUnicodeTarget(name+"TargetAnsi", name+"TargetUnicode", offset, end)
+	case KnownFolderDataBlockSignature:
+		if m.fits(offset, 20, end) {
+			var knownFolderID GUID
+			copy(knownFolderID[:], m.data[offset:])
+			m.field(name+"KnownFolderID", offset, 16, strings.TrimSpace(knownFolderID.String()+" "+KnownFolderName(knownFolderID)))
+			m.int32(name+"Offset", offset+16)
+		}
+	case PropertyStoreDataBlockSignature:
+		m.mapPropertyStore(name+"PropertyStore", offset, end)
+	case ShimDataBlockSignature:
+		m.field(name+"LayerName", offset, end-offset, decodeFixedUnicode(m.data[offset:end]))
+	case SpecialFolderDataBlockSignature:
+		if m.fits(offset, 8, end) {
+			m.named(name+"SpecialFolderID", offset, SpecialFolderName)
+			m.int32(name+"Offset", offset+4)
+		}
+	case TrackerDataBlockSignature:
+		if m.fits(offset, int(TrackerDataBlockSize-ExtraDataBlockSizeMin), end) {
+			m.uint32(name+"Length", offset)
+			m.uint32(name+"Version", offset+4)
+			m.field(name+"MachineID", offset+8, 16, decodeFixedANSI(m.data[offset+8:offset+24], m.codePage))
+			for i, droid := range []string{"DroidVolume", "DroidFile", "BirthDroidVolume", "BirthDroidFile"} {
+				m.guid(name+droid, offset+24+16*i)
+			}
+		}
+	case VistaAndAboveIDListDataBlockSignature:
+		m.mapIDList(name+"IDList", offset, end)
+	default:
+		if end > offset {
+			m.field(name+"BlockData", offset, end-offset, "")
+		}
+	}
+}
+
+// blockFields maps the fields of a fixed size block struct after BlockSize and BlockSignature,
+// as readBlockFields decodes them.
+func (m *byteMapper) blockFields(name string, offset int, end int, block interface{}) {
+	blockValue := reflect.ValueOf(block).Elem()
+	size := binary.Size(block) - int(ExtraDataBlockSizeMin)
+	if !m.fits(offset, size, end) {
+		return
+	}
+	// the data is complete, binary.Read cannot fail
+	_ = binary.Read(bytes.NewReader(m.data[offset-int(ExtraDataBlockSizeMin):]), binary.LittleEndian, block)
+	for i := 2; i < blockValue.NumField(); i++ {
+		field := blockValue.Field(i)
+		fieldSize := binary.Size(field.Interface())
+		m.field(name+blockValue.Type().Field(i).Tag.Get("json"), offset, fieldSize, fmt.Sprint(field.Interface()))
+		offset += fieldSize
+	}
+}
+
+// ansiUnicodeTarget maps the fixed size ANSI and Unicode strings of Darwin, EnvironmentVariable and IconEnvironment blocks.
+func (m *byteMapper) ansiUnicodeTarget(ansiName string, unicodeName string, offset int, end int) {
+	ansiSize, unicodeSize := int(ExtraDataAnsiStringSize), int(ExtraDataUnicodeStringSize)
+	if !m.fits(offset, ansiSize+unicodeSize, end) {
+		return
+	}
+	m.field(ansiName, offset, ansiSize, decodeFixedANSI(m.data[offset:offset+ansiSize], m.codePage))
+	offset += ansiSize
+	m.field(unicodeName, offset, unicodeSize, decodeFixedUnicode(m.data[offset:offset+unicodeSize]))
+}
+
+// mapPropertyStore maps each serialized property storage and its values, bytes of a storage whose values
+// cannot be located are mapped as SerializedPropertyValues.
+func (m *byteMapper) mapPropertyStore(name string, offset int, end int) {
+	for i := 0; m.fits(offset, 4, end); i++ {
+		storageSize := int(binary.LittleEndian.Uint32(m.data[offset:]))
+		if storageSize == 0 {
+			m.field(name+".Terminator", offset, 4, "0")
+			return
+		}
+		storageName := fmt.Sprintf("%v[%d]", name, i)
+		if storageSize < 24 || !m.fits(offset, storageSize, end) {
+			m.uint32(storageName+".StorageSize", offset)
+			return
+		}
+		m.structure(storageName, offset, storageSize)
+		m.uint32(storageName+".StorageSize", offset)
+		m.field(storageName+".Version", offset+4, 4, fmt.Sprintf("0x%08X", binary.LittleEndian.Uint32(m.data[offset+4:])))
+		formatID := m.guid(storageName+".FormatID", offset+8)
+		fields := len(m.byteMap.Fields)
+		m.mapPropertyValues(storageName+".Values", formatID, offset+24, offset+storageSize)
+		m.fillGaps(storageName+".SerializedPropertyValues", offset+24, offset+storageSize, fields)
+		offset += storageSize
+	}
+}
+
+// mapPropertyValues maps the serialized property values from offset to end up to their terminator,
+// as parsePropertyStorage decodes them.
+func (m *byteMapper) mapPropertyValues(name string, formatID GUID, offset int, end int) {
+	stringNamed := formatID == PropertyStorageStringNameFormatID
+	for i := 0; m.fits(offset, 4, end); i++ {
+		valueSize := int(binary.LittleEndian.Uint32(m.data[offset:]))
+		if valueSize == 0 {
+			m.field(name+".Terminator", offset, 4, "0")
+			return
+		}
+		valueName := fmt.Sprintf("%v[%d]", name, i)
+		if valueSize < int(SerializedPropertyValueSizeMin) || !m.fits(offset, valueSize, end) {
+			m.uint32(valueName+".ValueSize", offset)
+			return
+		}
+		value, err := parseSerializedPropertyValue(m.data[offset:offset+valueSize], formatID, stringNamed)
+		if err != nil {
+			m.uint32(valueName+".ValueSize", offset)
+			return
+		}
+		m.structure(valueName, offset, valueSize)
+		m.uint32(valueName+".ValueSize", offset)
+		typed := offset + int(SerializedPropertyValueSizeMin)
+		if stringNamed {
+			m.uint32(valueName+".NameSize", offset+4)
+			m.field(valueName+".Reserved", offset+8, 1, strconv.Itoa(int(value.Reserved)))
+			if value.NameSize > 0 {
+				m.field(valueName+".Name", typed, int(value.NameSize), value.Name)
+			}
+			typed += int(value.NameSize)
+		} else {
+			m.named(valueName+".ID", offset+4, func(uint32) string { return value.PropertyName })
+			m.field(valueName+".Reserved", offset+8, 1, strconv.Itoa(int(value.Reserved)))
+		}
+		m.field(valueName+".Type", typed, 2, fmt.Sprintf("0x%04X %v", value.Value.Type, value.Value.TypeName))
+		m.uint16(valueName+".Padding", typed+2)
+		if valueEnd := offset + valueSize; valueEnd > typed+4 {
+			m.field(valueName+".Value", typed+4, valueEnd-typed-4, formatPropertyValue(value.Value.Value))
+		}
+		offset += valueSize
+	}
+}
+
+// formatPropertyValue formats a decoded property value for the byte map, empty if it is not decoded.
+func formatPropertyValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// WriteHexDump writes data as hex dump, 16 bytes per line, each field and uncovered range starting on its own line
+// with its name and value. Structures are announced by a comment line before their first field.
+func WriteHexDump(w io.Writer, data []byte, byteMap ByteMap) error {
+	ranges := append(append([]ByteRange{}, byteMap.Fields...), byteMap.Uncovered...)
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Offset < ranges[j].Offset
+	})
+
+	var b strings.Builder
+	structures := byteMap.Structures
+	for _, r := range ranges {
+		for len(structures) > 0 && structures[0].Offset <= r.Offset {
+			fmt.Fprintf(&b, "; %v at 0x%08X, %v bytes\n", structures[0].Field, structures[0].Offset, structures[0].Size)
+			structures = structures[1:]
+		}
+		annotation := "!! not covered by any structure"
+		if r.Field != "" {
+			annotation = r.Field
+			if r.Value != "" {
+				annotation += " = " + quoteUnprintable(r.Value)
+			}
+		}
+		for lineOffset := r.Offset; lineOffset < r.Offset+r.Size; lineOffset += 16 {
+			lineEnd := lineOffset + 16
+			if lineEnd > r.Offset+r.Size {
+				lineEnd = r.Offset + r.Size
+			}
+			hex := fmt.Sprintf("% X", data[lineOffset:lineEnd])
+			if lineOffset == r.Offset {
+				fmt.Fprintf(&b, "%08X  %-47s  %v\n", lineOffset, hex, annotation)
+			} else {
+				fmt.Fprintf(&b, "%08X  %v\n", lineOffset, hex)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoteUnprintable quotes a value with line breaks or other unprintable characters.
+func quoteUnprintable(value string) string {
+	if strings.IndexFunc(value, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_MapBytes(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := NewShortcut(`\\server\share\report.txt`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var faceName FaceName
+	if err := faceName.UnmarshalText([]byte("Consolas")); err != nil {
+		t.Fatal(err)
+	}
+	network.ExtraData = append(network.ExtraData,
+		ExtraDataBlock{ConsoleFEDataBlock: &ConsoleFEDataBlock{CodePage: 1252}},
+		ExtraDataBlock{ConsoleDataBlock: &ConsoleDataBlock{FontSize: 0x100000, FaceName: faceName}})
+	withConsole, err := Encode(&network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		wantFields    []ByteRange
+		wantUncovered []ByteRange
+	}{
+		{
+			name: "fixture",
+			data: fixture,
+			wantFields: []ByteRange{
+				{Offset: 0x14, Size: 4, Field: "ShellLinkHeader.LinkFlags", Value: "0x000802BB"},
+				{Offset: 0x40, Size: 2, Field: "ShellLinkHeader.HotKey", Value: "0x0374 Ctrl+Shift+F5"},
+				{Offset: 0x62, Size: 2, Field: "LinkTargetIDList.IDList[1].ItemIDSize", Value: "25"},
+				{Offset: 0x64, Size: 1, Field: "LinkTargetIDList.IDList[1].ClassType", Value: "0x2F Volume"},
+				{Offset: 0x65, Size: 4, Field: "LinkTargetIDList.IDList[1].Name", Value: `C:\`},
+				{Offset: 0xD5, Size: 4, Field: "LinkTargetIDList.IDList[3].FileSize", Value: "196608"},
+				{Offset: 0xDF, Size: 12, Field: "LinkTargetIDList.IDList[3].PrimaryName", Value: "notepad.exe"},
+				{Offset: 0xEF, Size: 4, Field: "LinkTargetIDList.IDList[3].ExtensionBlocks[0].Signature", Value: "0xBEEF0004 FileEntry"},
+				{Offset: 0x119, Size: 24, Field: "LinkTargetIDList.IDList[3].ExtensionBlocks[0].LongName", Value: "notepad.exe"},
+				{Offset: 0x133, Size: 2, Field: "LinkTargetIDList.IDList.TerminalID", Value: "0"},
+				{Offset: 0x159, Size: 4, Field: "LinkInfo.VolumeID.DriveSerialNumber", Value: "1234-ABCD"},
+				{Offset: 0x162, Size: 23, Field: "LinkInfo.LocalBasePath", Value: `C:\Windows\notepad.exe`},
+				{Offset: 0x1C8, Size: 22, Field: "StringData.CommandLineArgs", Value: "/A test.txt"},
+				{Offset: 0x1E2, Size: 4, Field: "ExtraData[0].BlockSignature", Value: "0xA0000001 EnvironmentVariableDataBlock"},
+				{Offset: 0x56B, Size: 4, Field: "ExtraData[3].PropertyStore[0].Values[1].ID", Value: "12 System.Size"},
+				{Offset: 0x574, Size: 8, Field: "ExtraData[3].PropertyStore[0].Values[1].Value", Value: "196608"},
+				{Offset: 0x5FE, Size: 14, Field: "ExtraData[3].PropertyStore[2].Values[0].Name", Value: "Custom"},
+				{Offset: 0x62C, Size: 16, Field: "ExtraData[4].MachineID", Value: "desktop-01"},
+				{Offset: 0x67C, Size: 4, Field: "ExtraData.TerminalBlock", Value: "0"},
+			},
+			wantUncovered: []ByteRange{},
+		},
+		{
+			name:          "data after TerminalBlock",
+			data:          append(append([]byte{}, fixture...), 0xDE, 0xAD),
+			wantFields:    []ByteRange{{Offset: 0x67C, Size: 4, Field: "ExtraData.TerminalBlock", Value: "0"}},
+			wantUncovered: []ByteRange{{Offset: 0x680, Size: 2}},
+		},
+		{
+			name: "property value out of its storage",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				data[0x567] = 0xFF
+				return data
+			}(),
+			wantFields: []ByteRange{
+				{Offset: 0x567, Size: 4, Field: "ExtraData[3].PropertyStore[0].Values[1].ValueSize", Value: "255"},
+				{Offset: 0x56B, Size: 21, Field: "ExtraData[3].PropertyStore[0].SerializedPropertyValues"},
+			},
+			wantUncovered: []ByteRange{},
+		},
+		{
+			name:          "truncated LinkInfo",
+			data:          fixture[:0x150],
+			wantFields:    []ByteRange{{Offset: 0x135, Size: 4, Field: "LinkInfo.LinkInfoSize", Value: "69"}},
+			wantUncovered: []ByteRange{{Offset: 0x139, Size: 0x17}},
+		},
+		{
+			name: "network target and console blocks",
+			data: withConsole,
+			wantFields: []ByteRange{
+				{Offset: 0x7C, Size: 15, Field: "LinkInfo.CommonNetworkRelativeLink.NetName", Value: `\\server\share`},
+				{Offset: 0x8B, Size: 11, Field: "LinkInfo.CommonPathSuffix", Value: "report.txt"},
+				{Offset: 0x9E, Size: 4, Field: "ExtraData[0].CodePage", Value: "1252"},
+				{Offset: 0xC2, Size: 4, Field: "ExtraData[1].FontSize", Value: "1048576"},
+				{Offset: 0xCE, Size: 64, Field: "ExtraData[1].FaceName", Value: "Consolas"},
+			},
+			wantUncovered: []ByteRange{},
+		},
+		{
+			name:          "too short for the header",
+			data:          fixture[:0x20],
+			wantUncovered: []ByteRange{{Offset: 0, Size: 0x20}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MapBytes(tt.data, nil)
+			for _, want := range tt.wantFields {
+				found := false
+				for _, field := range got.Fields {
+					if field.Field == want.Field {
+						found = true
+						if field != want {
+							t.Errorf("MapBytes() field = %+v, want %+v", field, want)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("MapBytes() has no field %v", want.Field)
+				}
+			}
+			if !reflect.DeepEqual(got.Uncovered, tt.wantUncovered) {
+				t.Errorf("MapBytes() Uncovered = %+v, want %+v", got.Uncovered, tt.wantUncovered)
+			}
+			// fields do not overlap and are in file order
+			for i := 1; i < len(got.Fields); i++ {
+				if previous := got.Fields[i-1]; previous.Offset+previous.Size > got.Fields[i].Offset {
+					t.Errorf("MapBytes() field %+v overlaps %+v", previous, got.Fields[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_WriteHexDump(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(append([]byte{}, fixture...), 0xDE, 0xAD)
+	var b bytes.Buffer
+	if err := WriteHexDump(&b, data, MapBytes(data, nil)); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"; ShellLinkHeader at 0x00000000, 76 bytes\n00000000  4C 00 00 00                                      ShellLinkHeader.HeaderSize = 76\n",
+		"; LinkInfo.VolumeID at 0x00000151, 17 bytes\n",
+		"000001C8  2F 00 41 00 20 00 74 00 65 00 73 00 74 00 2E 00  StringData.CommandLineArgs = /A test.txt\n000001D8  74 00 78 00 74 00\n",
+		"00000680  DE AD                                            !! not covered by any structure\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteHexDump() has no %q", want)
+		}
+	}
+}
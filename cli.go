@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Exit codes
+const (
+	ExitAllParsed  int = 0
+	ExitSomeFailed int = 1
+	ExitUsageError int = 2
+)
+
+const stdinPath = "-"
+
+const usageText = `Usage: lnk2json [flags] <path|glob|dir|-> [...]
+       lnk2json compile [-o file.lnk] <file.json|->
+       lnk2json edit -set field=value [...] [-o file.lnk] <file.lnk>
+
+Parses Windows shortcut (.lnk) files and prints them as JSON.
+Directories are scanned for *.lnk files, "-" reads a single file from stdin.
+
+Flags:
+`
+
+const compileUsageText = `Usage: lnk2json compile [-o file.lnk] <file.json|->
+
+Builds a .lnk file from JSON as printed by lnk2json, either a single file result or its "ShellLink".
+Sizes and offsets are recomputed, decoded fields must agree with the raw values they were decoded from.
+
+Flags:
+`
+
+const editUsageText = `Usage: lnk2json edit -set field=value [-set ...] [-o file.lnk] <file.lnk>
+
+Changes fields of a shortcut and saves it in place, structures that were not changed keep their bytes.
+Fields: args, workdir, description, icon ("path,index", or "path" to keep the index), target (local or UNC path)
+and flags (LinkFlags names separated by commas, "-" clears a flag, e.g. "RunAsUser,-HasExpIcon").
+
+Flags:
+`
+
+// cliOptions holds the parsed command line flags.
+type cliOptions struct {
+	compact   bool
+	outDir    string
+	recursive bool
+	codePage  *CodePage
+	lenient   bool
+	validate  bool
+	byteMap   string
+}
+
+// fileResult is a single parsed file as written to stdout.
+type fileResult struct {
+	Path      string          `json:"Path"`
+	ShellLink ShellLinkParsed `json:"ShellLink"`
+}
+
+// inputFile is a file to convert, name is its output path under -o without extension: the path relative
+// to the scanned directory for files found in one, the base name otherwise.
+type inputFile struct {
+	path string
+	name string
+}
+
+// validationResult is the -validate report of a single file, Path is left out with -o.
+// A file is Valid if it has neither Violations nor Warnings.
+type validationResult struct {
+	Path       string         `json:"Path,omitempty"`
+	Valid      bool           `json:"Valid"`
+	Violations []Violation    `json:"Violations"`
+	Warnings   []ParseWarning `json:"Warnings,omitempty"`
+}
+
+// byteMapResult is the -bytemap json output of a single file, Path is left out with -o.
+type byteMapResult struct {
+	Path string `json:"Path,omitempty"`
+	ByteMap
+}
+
+// run executes the command line tool and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "compile" {
+		return runCompile(args[1:], stdin, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "edit" {
+		return runEdit(args[1:], stderr)
+	}
+
+	var opts cliOptions
+	flags := flag.NewFlagSet("lnk2json", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&opts.compact, "compact", false, "write compact JSON instead of indented")
+	flags.StringVar(&opts.outDir, "o", "", "write one <name>.json file per input into this directory instead of stdout, files found in scanned directories keep their relative path")
+	flags.BoolVar(&opts.recursive, "r", false, "scan directories recursively")
+	flags.BoolVar(&opts.validate, "validate", false, "write an MS-SHLLINK conformance report instead of the parsed file, files with violations count as failed")
+	flags.StringVar(&opts.byteMap, "bytemap", "", "write the offset and size of every field instead of the parsed file: json for a byte map, hex for an annotated hex dump")
+	flags.BoolVar(&opts.lenient, "lenient", false, "recover what can be parsed from damaged files and list the problems in Warnings")
+	codePageName := flags.String("codepage", "", "code page of ANSI strings, e.g. windows-1251 or shift_jis (default a ConsoleFEDataBlock CodePage or "+DefaultCodePage.Name+")")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usageText)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitAllParsed
+		}
+		return ExitUsageError
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsageError
+	}
+	if opts.byteMap != "" && opts.byteMap != "json" && opts.byteMap != "hex" || opts.byteMap != "" && opts.validate {
+		fmt.Fprintln(stderr, "lnk2json: -bytemap must be json or hex and cannot be combined with -validate")
+		return ExitUsageError
+	}
+	if *codePageName != "" {
+		codePage, err := LookupCodePage(*codePageName)
+		if err != nil {
+			fmt.Fprintf(stderr, "lnk2json: %v\n", err)
+			return ExitUsageError
+		}
+		opts.codePage = codePage
+	}
+	if opts.outDir != "" {
+		info, err := os.Stat(opts.outDir)
+		if err != nil || !info.IsDir() {
+			fmt.Fprintf(stderr, "lnk2json: output directory %q does not exist\n", opts.outDir)
+			return ExitUsageError
+		}
+	}
+
+	files, failed := expandPaths(flags.Args(), opts.recursive, stderr)
+	// output names are compared case-insensitively, the output directory may be on such a file system
+	written := map[string]string{}
+	for _, file := range files {
+		key := strings.ToLower(filepath.Clean(file.name))
+		if first, ok := written[key]; ok && opts.outDir != "" {
+			fmt.Fprintf(stderr, "lnk2json: %v: output %v would overwrite the output of %v\n", file.path, file.name, first)
+			failed++
+			continue
+		}
+		written[key] = file.path
+		if err := convertFile(file, opts, stdin, stdout); err != nil {
+			fmt.Fprintf(stderr, "lnk2json: %v: %v\n", file.path, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return ExitSomeFailed
+	}
+	return ExitAllParsed
+}
+
+// expandPaths resolves globs and directories to a list of files, counting arguments that matched nothing.
+func expandPaths(args []string, recursive bool, stderr io.Writer) ([]inputFile, int) {
+	var files []inputFile
+	failed := 0
+	for _, arg := range args {
+		if arg == stdinPath {
+			files = append(files, inputFile{path: arg, name: "stdin"})
+			continue
+		}
+
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil || len(matches) == 0 {
+				fmt.Fprintf(stderr, "lnk2json: %v: no matching files\n", arg)
+				failed++
+				continue
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				// errors are reported when the file is opened
+				files = append(files, inputFile{path: match, name: trimExt(filepath.Base(match))})
+				continue
+			}
+			found, err := findLnkFiles(match, recursive)
+			if err != nil {
+				fmt.Fprintf(stderr, "lnk2json: %v: %v\n", match, err)
+				failed++
+			}
+			for _, path := range found {
+				name, err := filepath.Rel(match, path)
+				if err != nil {
+					name = filepath.Base(path)
+				}
+				files = append(files, inputFile{path: path, name: trimExt(name)})
+			}
+		}
+	}
+	return files, failed
+}
+
+// trimExt removes the file name extension of a path.
+func trimExt(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// findLnkFiles lists the .lnk files of a directory.
+func findLnkFiles(dir string, recursive bool) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.EqualFold(filepath.Ext(path), ".lnk") {
+			found = append(found, path)
+		}
+		return nil
+	})
+	return found, err
+}
+
+// convertFile parses a single file and writes its JSON.
+func convertFile(file inputFile, opts cliOptions, stdin io.Reader, stdout io.Writer) error {
+	path := file.path
+	var data []byte
+	var err error
+	if path == stdinPath {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ReadLnkFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	shellLinkParsed, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{CodePage: opts.codePage, Lenient: opts.lenient})
+	if err != nil {
+		return err
+	}
+
+	if opts.byteMap != "" {
+		// the code page may come from a ConsoleFEDataBlock
+		return writeByteMap(file, opts, data, MapBytes(data, shellLinkParsed.CodePage), stdout)
+	}
+
+	var report *validationResult
+	if opts.validate {
+		violations := Validate(&shellLinkParsed)
+		// a section the lenient parse skipped over does not conform either
+		report = &validationResult{
+			Valid:      len(violations) == 0 && len(shellLinkParsed.Warnings) == 0,
+			Violations: violations,
+			Warnings:   shellLinkParsed.Warnings,
+		}
+	}
+
+	if opts.outDir == "" {
+		var result interface{} = fileResult{Path: path, ShellLink: shellLinkParsed}
+		if report != nil {
+			report.Path = path
+			result = report
+		}
+		err = writeJSON(stdout, result, opts.compact)
+	} else {
+		var result interface{} = shellLinkParsed
+		if report != nil {
+			result = report
+		}
+		err = writeJSONFile(file, opts, result)
+	}
+	if err == nil && report != nil && !report.Valid {
+		err = fmt.Errorf("%v MS-SHLLINK violations, %v parse warnings", len(report.Violations), len(report.Warnings))
+	}
+	return err
+}
+
+// writeByteMap writes the -bytemap output of a file, a hex dump to stdout starts with a line naming the file.
+func writeByteMap(file inputFile, opts cliOptions, data []byte, byteMap ByteMap, stdout io.Writer) error {
+	if opts.byteMap == "json" {
+		if opts.outDir == "" {
+			return writeJSON(stdout, byteMapResult{Path: file.path, ByteMap: byteMap}, opts.compact)
+		}
+		return writeJSONFile(file, opts, byteMapResult{ByteMap: byteMap})
+	}
+
+	if opts.outDir == "" {
+		_, err := fmt.Fprintf(stdout, "# %v\n", file.path)
+		if err == nil {
+			err = WriteHexDump(stdout, data, byteMap)
+		}
+		return err
+	}
+	out, err := createOutput(file, opts, ".txt")
+	if err != nil {
+		return err
+	}
+	err = WriteHexDump(out, data, byteMap)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// runCompile executes the compile command, writing the .lnk file to stdout without -o.
+func runCompile(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lnk2json compile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	outPath := flags.String("o", "", "write the .lnk file to this path instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, compileUsageText)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitAllParsed
+		}
+		return ExitUsageError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return ExitUsageError
+	}
+
+	path := flags.Arg(0)
+	if err := compileFile(path, *outPath, stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "lnk2json: %v: %v\n", path, err)
+		return ExitSomeFailed
+	}
+	return ExitAllParsed
+}
+
+// compileFile compiles a JSON file, a file result printed to stdout is unwrapped to its ShellLink.
+func compileFile(path string, outPath string, stdin io.Reader, stdout io.Writer) error {
+	var data []byte
+	var err error
+	if path == stdinPath {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	var result map[string]json.RawMessage
+	if json.Unmarshal(data, &result) == nil && len(result) == 2 && result["Path"] != nil && result["ShellLink"] != nil {
+		data = result["ShellLink"]
+	}
+	lnk, err := Compile(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if outPath == "" {
+		_, err = stdout.Write(lnk)
+		return err
+	}
+	return ioutil.WriteFile(outPath, lnk, 0644)
+}
+
+// editSets collects the repeated -set flags of the edit command.
+type editSets []string
+
+func (s *editSets) String() string {
+	return strings.Join(*s, " ")
+}
+
+func (s *editSets) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("%q is not field=value", value)
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// runEdit executes the edit command, writing the file in place without -o.
+func runEdit(args []string, stderr io.Writer) int {
+	var sets editSets
+	flags := flag.NewFlagSet("lnk2json edit", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Var(&sets, "set", "field=value to change, can be repeated")
+	outPath := flags.String("o", "", "write the edited file to this path instead of replacing the input")
+	codePageName := flags.String("codepage", "", "code page of ANSI strings (default a ConsoleFEDataBlock CodePage or "+DefaultCodePage.Name+")")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, editUsageText)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitAllParsed
+		}
+		return ExitUsageError
+	}
+	if flags.NArg() != 1 || len(sets) == 0 {
+		flags.Usage()
+		return ExitUsageError
+	}
+	var codePage *CodePage
+	if *codePageName != "" {
+		var err error
+		codePage, err = LookupCodePage(*codePageName)
+		if err != nil {
+			fmt.Fprintf(stderr, "lnk2json: %v\n", err)
+			return ExitUsageError
+		}
+	}
+
+	path := flags.Arg(0)
+	if *outPath == "" {
+		*outPath = path
+	}
+	if err := editFile(path, *outPath, sets, codePage); err != nil {
+		fmt.Fprintf(stderr, "lnk2json: %v: %v\n", path, err)
+		return ExitSomeFailed
+	}
+	return ExitAllParsed
+}
+
+// editFile applies field=value changes to a shortcut, nothing is written if one of them fails.
+func editFile(path string, outPath string, sets []string, codePage *CodePage) error {
+	data, err := ReadLnkFile(path)
+	if err != nil {
+		return err
+	}
+	editor, err := OpenShortcut(data, ParseOptions{CodePage: codePage})
+	if err != nil {
+		return err
+	}
+	for _, set := range sets {
+		i := strings.Index(set, "=")
+		if err := editor.Set(set[:i], set[i+1:]); err != nil {
+			return fmt.Errorf("-set %v: %w", set, err)
+		}
+	}
+	edited, err := editor.Bytes()
+	if err != nil {
+		return err
+	}
+	return replaceFile(outPath, edited)
+}
+
+// replaceFile writes data to a temporary file next to path and renames it over path,
+// so an interrupted write never leaves a truncated shortcut. An existing file keeps its mode.
+func replaceFile(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// createOutput creates <name><ext> in the output directory, with the subdirectories of a file found in a scanned directory.
+func createOutput(file inputFile, opts cliOptions, ext string) (*os.File, error) {
+	path := filepath.Join(opts.outDir, file.name+ext)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// writeJSONFile writes v to <name>.json in the output directory.
+func writeJSONFile(file inputFile, opts cliOptions, v interface{}) error {
+	out, err := createOutput(file, opts, ".json")
+	if err != nil {
+		return err
+	}
+	err = writeJSON(out, v, opts.compact)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func writeJSON(w io.Writer, v interface{}, compact bool) error {
+	encoder := json.NewEncoder(w)
+	if !compact {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(v)
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_run(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		args  []string
+		stdin []byte
+	}
+	tests := []struct {
+		name       string
+		args       args
+		want       int
+		wantStdout string
+	}{
+		{
+			name:       "single file",
+			args:       args{args: []string{"-compact", "testdata/notepad.lnk"}},
+			want:       ExitAllParsed,
+			wantStdout: `{"Path":"testdata/notepad.lnk","ShellLink":{`,
+		},
+		{
+			name:       "directory",
+			args:       args{args: []string{"-compact", "testdata"}},
+			want:       ExitAllParsed,
+			wantStdout: `"Path":"testdata/notepad.lnk"`,
+		},
+		{
+			name:       "glob",
+			args:       args{args: []string{"testdata/*.lnk"}},
+			want:       ExitAllParsed,
+			wantStdout: `"Path": "testdata/notepad.lnk"`,
+		},
+		{
+			name:       "stdin",
+			args:       args{args: []string{"-compact", "-"}, stdin: fixture},
+			want:       ExitAllParsed,
+			wantStdout: `{"Path":"-","ShellLink":{`,
+		},
+		{
+			name:       "some failed",
+			args:       args{args: []string{"-compact", "testdata/notepad.lnk", "testdata/missing.lnk"}},
+			want:       ExitSomeFailed,
+			wantStdout: `{"Path":"testdata/notepad.lnk"`,
+		},
+		{
+			name: "glob without matches",
+			args: args{args: []string{"testdata/*.missing"}},
+			want: ExitSomeFailed,
+		},
+		{
+			name: "no arguments",
+			args: args{args: []string{}},
+			want: ExitUsageError,
+		},
+		{
+			name:       "code page",
+			args:       args{args: []string{"-compact", "-codepage", "cp1251", "testdata/notepad.lnk"}},
+			want:       ExitAllParsed,
+			wantStdout: `"CodePage":{"ID":1251,"Name":"windows-1251"}`,
+		},
+		{
+			name: "unknown code page",
+			args: args{args: []string{"-codepage", "ebcdic", "testdata/notepad.lnk"}},
+			want: ExitUsageError,
+		},
+		{
+			name:       "lenient",
+			args:       args{args: []string{"-compact", "-lenient", "-"}, stdin: fixture[:0x150]},
+			want:       ExitAllParsed,
+			wantStdout: `"Warnings":[{"Section":"LinkInfo","Offset":309,`,
+		},
+		{
+			name: "strict",
+			args: args{args: []string{"-compact", "-"}, stdin: fixture[:0x150]},
+			want: ExitSomeFailed,
+		},
+		{
+			name:       "validate",
+			args:       args{args: []string{"-compact", "-validate", "testdata/notepad.lnk"}},
+			want:       ExitAllParsed,
+			wantStdout: `{"Path":"testdata/notepad.lnk","Valid":true,"Violations":[]}`,
+		},
+		{
+			name:       "validate with warnings",
+			args:       args{args: []string{"-compact", "-validate", "-lenient", "-"}, stdin: fixture[:0x150]},
+			want:       ExitSomeFailed,
+			wantStdout: `"Valid":false,"Violations":[],"Warnings":[{"Section":"LinkInfo"`,
+		},
+		{
+			name:       "byte map",
+			args:       args{args: []string{"-compact", "-bytemap", "json", "testdata/notepad.lnk"}},
+			want:       ExitAllParsed,
+			wantStdout: `{"Path":"testdata/notepad.lnk","Size":1664,"Structures":[{"Offset":0,"Size":76,"Field":"ShellLinkHeader"}`,
+		},
+		{
+			name:       "hex dump of a damaged file",
+			args:       args{args: []string{"-bytemap", "hex", "-lenient", "-"}, stdin: fixture[:0x150]},
+			want:       ExitAllParsed,
+			wantStdout: "# -\n; ShellLinkHeader at 0x00000000, 76 bytes\n",
+		},
+		{
+			name: "unknown byte map format",
+			args: args{args: []string{"-bytemap", "xml", "testdata/notepad.lnk"}},
+			want: ExitUsageError,
+		},
+		{
+			name: "byte map with validate",
+			args: args{args: []string{"-bytemap", "json", "-validate", "testdata/notepad.lnk"}},
+			want: ExitUsageError,
+		},
+		{
+			name: "unknown flag",
+			args: args{args: []string{"-unknown", "testdata/notepad.lnk"}},
+			want: ExitUsageError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			got := run(tt.args.args, bytes.NewReader(tt.args.stdin), &stdout, &stderr)
+			if got != tt.want {
+				t.Errorf("run() = %v, want %v, stderr: %v", got, tt.want, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("run() stdout = %v, want %v", stdout.String(), tt.wantStdout)
+			}
+		})
+	}
+}
+
+func Test_run_outDir(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "lnk2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"-o", outDir, "testdata/notepad.lnk"}, nil, &stdout, &stderr); got != ExitAllParsed {
+		t.Fatalf("run() = %v, want %v, stderr: %v", got, ExitAllParsed, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("run() stdout = %v, want empty", stdout.String())
+	}
+	out, err := ioutil.ReadFile(filepath.Join(outDir, "notepad.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("{\n  \"ShellLinkHeader\"")) {
+		t.Errorf("notepad.json = %s", out)
+	}
+}
+
+func Test_run_outDir_recursive(t *testing.T) {
+	inDir, err := ioutil.TempDir("", "lnk2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(inDir)
+	outDir, err := ioutil.TempDir("", "lnk2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	data, err := ioutil.ReadFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(inDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(inDir, dir, "x.lnk"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"-o", outDir, "-r", inDir}, nil, &stdout, &stderr); got != ExitAllParsed {
+		t.Fatalf("run() = %v, want %v, stderr: %v", got, ExitAllParsed, stderr.String())
+	}
+	for _, name := range []string{"a/x.json", "b/x.json"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+
+	// the same file name given twice would be written to the same output
+	stderr.Reset()
+	if got := run([]string{"-o", outDir, filepath.Join(inDir, "a", "x.lnk"), filepath.Join(inDir, "b", "x.lnk")}, nil, &stdout, &stderr); got != ExitSomeFailed {
+		t.Fatalf("run() = %v, want %v", got, ExitSomeFailed)
+	}
+	if !strings.Contains(stderr.String(), "would overwrite the output of") {
+		t.Errorf("run() stderr = %v", stderr.String())
+	}
+}
+
+func Test_run_outDir_hexDump(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "lnk2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"-bytemap", "hex", "-o", outDir, "testdata/notepad.lnk"}, nil, &stdout, &stderr); got != ExitAllParsed {
+		t.Fatalf("run() = %v, want %v, stderr: %v", got, ExitAllParsed, stderr.String())
+	}
+	out, err := ioutil.ReadFile(filepath.Join(outDir, "notepad.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("; ShellLinkHeader at 0x00000000, 76 bytes\n")) || !bytes.HasSuffix(out, []byte("ExtraData.TerminalBlock = 0\n")) {
+		t.Errorf("notepad.txt = %s", out)
+	}
+}
+
+func Test_run_compile(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed bytes.Buffer
+	if got := run([]string{"-compact", "testdata/notepad.lnk"}, nil, &parsed, ioutil.Discard); got != ExitAllParsed {
+		t.Fatalf("run() = %v", got)
+	}
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      []byte
+		want       int
+		wantStdout []byte
+	}{
+		{name: "file result", args: []string{"compile", "-"}, stdin: parsed.Bytes(), want: ExitAllParsed, wantStdout: fixture},
+		{name: "invalid JSON", args: []string{"compile", "-"}, stdin: fixture, want: ExitSomeFailed},
+		{name: "missing file", args: []string{"compile", "testdata/missing.json"}, want: ExitSomeFailed},
+		{name: "no arguments", args: []string{"compile"}, want: ExitUsageError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			got := run(tt.args, bytes.NewReader(tt.stdin), &stdout, &stderr)
+			if got != tt.want {
+				t.Errorf("run() = %v, want %v, stderr: %v", got, tt.want, stderr.String())
+			}
+			if tt.wantStdout != nil && !bytes.Equal(stdout.Bytes(), tt.wantStdout) {
+				t.Errorf("run() stdout differs from the compiled file, got %v bytes, want %v", stdout.Len(), len(tt.wantStdout))
+			}
+		})
+	}
+}
+
+func Test_run_edit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnk2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "notepad.lnk")
+	tests := []struct {
+		name     string
+		args     []string
+		want     int
+		wantArgs string
+	}{
+		{name: "in place", args: []string{"edit", "--set", `args=/P "a b.txt"`, path}, want: ExitAllParsed, wantArgs: `/P "a b.txt"`},
+		{name: "output file", args: []string{"edit", "-set", "args=", "-set", "workdir=C:\\", "-o", path, "testdata/notepad.lnk"}, want: ExitAllParsed},
+		{name: "unknown field", args: []string{"edit", "-set", "hotkey=Ctrl+A", path}, want: ExitSomeFailed, wantArgs: "/A test.txt"},
+		{name: "not field=value", args: []string{"edit", "-set", "args", path}, want: ExitUsageError},
+		{name: "no -set", args: []string{"edit", path}, want: ExitUsageError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(path, fixture, 0644); err != nil {
+				t.Fatal(err)
+			}
+			var stderr bytes.Buffer
+			if got := run(tt.args, nil, ioutil.Discard, &stderr); got != tt.want {
+				t.Fatalf("run() = %v, want %v, stderr: %v", got, tt.want, stderr.String())
+			}
+			// the edited file replaces the original, no temporary file is left behind
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+				t.Errorf("ReadDir() = %v, %v, want only notepad.lnk", entries, err)
+			}
+			data, err := ReadLnkFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			edited, err := ParseData(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == ExitUsageError {
+				return
+			}
+			if edited.StringData.CommandLineArgs != tt.wantArgs {
+				t.Errorf("CommandLineArgs = %q, want %q", edited.StringData.CommandLineArgs, tt.wantArgs)
+			}
+		})
+	}
+}
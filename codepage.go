@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// CodePage is a Windows code page used to decode ANSI strings, a nil *CodePage is windows-1252.
+type CodePage struct {
+	ID       uint32   `json:"ID"`
+	Name     string   `json:"Name"`
+	aliases  []string // Alternative names accepted by LookupCodePage
+	encoding encoding.Encoding
+}
+
+// codePages lists the supported code pages by Windows code page identifier.
+var codePages = []*CodePage{
+	{ID: 437, Name: "ibm437", aliases: []string{"cp437"}, encoding: charmap.CodePage437},
+	{ID: 850, Name: "ibm850", aliases: []string{"cp850"}, encoding: charmap.CodePage850},
+	{ID: 852, Name: "ibm852", aliases: []string{"cp852"}, encoding: charmap.CodePage852},
+	{ID: 855, Name: "ibm855", aliases: []string{"cp855"}, encoding: charmap.CodePage855},
+	{ID: 858, Name: "ibm00858", aliases: []string{"cp858"}, encoding: charmap.CodePage858},
+	{ID: 860, Name: "ibm860", aliases: []string{"cp860"}, encoding: charmap.CodePage860},
+	{ID: 862, Name: "dos-862", aliases: []string{"cp862"}, encoding: charmap.CodePage862},
+	{ID: 863, Name: "ibm863", aliases: []string{"cp863"}, encoding: charmap.CodePage863},
+	{ID: 865, Name: "ibm865", aliases: []string{"cp865"}, encoding: charmap.CodePage865},
+	{ID: 866, Name: "cp866", aliases: []string{"ibm866"}, encoding: charmap.CodePage866},
+	{ID: 874, Name: "windows-874", aliases: []string{"cp874"}, encoding: charmap.Windows874},
+	{ID: 932, Name: "shift_jis", aliases: []string{"cp932", "sjis", "windows-31j"}, encoding: japanese.ShiftJIS},
+	{ID: 936, Name: "gbk", aliases: []string{"cp936", "gb2312"}, encoding: simplifiedchinese.GBK},
+	{ID: 949, Name: "ks_c_5601-1987", aliases: []string{"cp949", "euc-kr"}, encoding: korean.EUCKR},
+	{ID: 950, Name: "big5", aliases: []string{"cp950"}, encoding: traditionalchinese.Big5},
+	{ID: 1250, Name: "windows-1250", aliases: []string{"cp1250"}, encoding: charmap.Windows1250},
+	{ID: 1251, Name: "windows-1251", aliases: []string{"cp1251"}, encoding: charmap.Windows1251},
+	{ID: 1252, Name: "windows-1252", aliases: []string{"cp1252"}, encoding: charmap.Windows1252},
+	{ID: 1253, Name: "windows-1253", aliases: []string{"cp1253"}, encoding: charmap.Windows1253},
+	{ID: 1254, Name: "windows-1254", aliases: []string{"cp1254"}, encoding: charmap.Windows1254},
+	{ID: 1255, Name: "windows-1255", aliases: []string{"cp1255"}, encoding: charmap.Windows1255},
+	{ID: 1256, Name: "windows-1256", aliases: []string{"cp1256"}, encoding: charmap.Windows1256},
+	{ID: 1257, Name: "windows-1257", aliases: []string{"cp1257"}, encoding: charmap.Windows1257},
+	{ID: 1258, Name: "windows-1258", aliases: []string{"cp1258"}, encoding: charmap.Windows1258},
+	{ID: 20866, Name: "koi8-r", aliases: []string{"cp20866"}, encoding: charmap.KOI8R},
+	{ID: 21866, Name: "koi8-u", aliases: []string{"cp21866"}, encoding: charmap.KOI8U},
+	{ID: 28591, Name: "iso-8859-1", aliases: []string{"latin1"}, encoding: charmap.ISO8859_1},
+	{ID: 28592, Name: "iso-8859-2", aliases: []string{"latin2"}, encoding: charmap.ISO8859_2},
+	{ID: 28595, Name: "iso-8859-5", encoding: charmap.ISO8859_5},
+	{ID: 28597, Name: "iso-8859-7", encoding: charmap.ISO8859_7},
+	{ID: 65001, Name: "utf-8", aliases: []string{"cp65001"}, encoding: unicode.UTF8},
+}
+
+// DefaultCodePage is used for ANSI strings if no code page is configured.
+var DefaultCodePage = CodePageByID(1252)
+
+// CodePageByID returns the code page with a Windows code page identifier, nil if unsupported.
+func CodePageByID(id uint32) *CodePage {
+	for _, codePage := range codePages {
+		if codePage.ID == id {
+			return codePage
+		}
+	}
+	return nil
+}
+
+// LookupCodePage finds a code page by name, alias or identifier, e.g. "windows-1251", "cp932" or "1253".
+func LookupCodePage(name string) (*CodePage, error) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if codePage := CodePageByID(uint32(id)); codePage != nil {
+			return codePage, nil
+		}
+	}
+	for _, codePage := range codePages {
+		if strings.EqualFold(codePage.Name, name) {
+			return codePage, nil
+		}
+		for _, alias := range codePage.aliases {
+			if strings.EqualFold(alias, name) {
+				return codePage, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported code page %q", name)
+}
+
+// Decode converts ANSI bytes to a Go string, invalid sequences become U+FFFD.
+func (c *CodePage) Decode(b []byte) string {
+	if c == nil {
+		c = DefaultCodePage
+	}
+	decoded, err := c.encoding.NewDecoder().Bytes(b)
+	if err != nil {
+		return strings.ToValidUTF8(string(b), "�")
+	}
+	return string(decoded)
+}
+
+// Encode converts a Go string to ANSI bytes, characters the code page cannot represent are an error.
+func (c *CodePage) Encode(s string) ([]byte, error) {
+	if c == nil {
+		c = DefaultCodePage
+	}
+	encoded, err := c.encoding.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("encode %q in %v: %w", s, c, err)
+	}
+	return encoded, nil
+}
+
+// UnmarshalJSON resolves {"ID": ..., "Name": ...} to a supported code page, by ID if it is set.
+func (c *CodePage) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		ID   uint32
+		Name string
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	name := fields.Name
+	if fields.ID != 0 {
+		name = strconv.FormatUint(uint64(fields.ID), 10)
+	}
+	codePage, err := LookupCodePage(name)
+	if err != nil {
+		return err
+	}
+	*c = *codePage
+	return nil
+}
+
+func (c *CodePage) String() string {
+	if c == nil {
+		return DefaultCodePage.String()
+	}
+	return fmt.Sprintf("%v (%v)", c.Name, c.ID)
+}
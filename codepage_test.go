@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func Test_LookupCodePage(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    uint32
+		wantErr bool
+	}{
+		{name: "windows-1251", want: 1251},
+		{name: "Shift_JIS", want: 932},
+		{name: "cp1253", want: 1253},
+		{name: "866", want: 866},
+		{name: "ebcdic", wantErr: true},
+		{name: "12345", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupCodePage(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LookupCodePage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.ID != tt.want {
+				t.Errorf("LookupCodePage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodePage_Decode(t *testing.T) {
+	tests := []struct {
+		name     string
+		codePage *CodePage
+		data     []byte
+		want     string
+	}{
+		{name: "nil is windows-1252", codePage: nil, data: []byte{'C', 'a', 'f', 0xE9, ' ', 0x80}, want: "Café €"},
+		{name: "windows-1251", codePage: CodePageByID(1251), data: []byte{0xCF, 0xF0, 0xE8, 0xE2, 0xE5, 0xF2}, want: "Привет"},
+		{name: "windows-1253", codePage: CodePageByID(1253), data: []byte{0xC1, 0xE8, 0xDE, 0xED, 0xE1}, want: "Αθήνα"},
+		{name: "shift_jis", codePage: CodePageByID(932), data: []byte{0x93, 0xFA, 0x96, 0x7B}, want: "日本"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.codePage.Decode(tt.data); got != tt.want {
+				t.Errorf("CodePage.Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseDataWithOptions_codePage(t *testing.T) {
+	// non-Unicode link with a RelativePath in windows-1251
+	link := func(consoleFECodePage uint32) []byte {
+		data := make([]byte, HeaderSizeExpected)
+		binary.LittleEndian.PutUint32(data, HeaderSizeExpected)
+		copy(data[4:], LinkCLSIDExpected[:])
+		binary.LittleEndian.PutUint32(data[20:], HasRelativePath)
+		data = append(data, 0x06, 0x00, 0xCF, 0xE0, 0xEF, 0xEA, 0xE0, '\\')
+		if consoleFECodePage != 0 {
+			block := []byte{0x0C, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0xA0, 0x00, 0x00, 0x00, 0x00}
+			binary.LittleEndian.PutUint32(block[8:], consoleFECodePage)
+			data = append(data, block...)
+		}
+		return append(data, 0x00, 0x00, 0x00, 0x00)
+	}
+	tests := []struct {
+		name           string
+		data           []byte
+		opts           ParseOptions
+		wantPath       string
+		wantCodePageID uint32
+		wantSource     string
+	}{
+		{
+			name:           "default",
+			data:           link(0),
+			wantPath:       "Ïàïêà\\",
+			wantCodePageID: 1252,
+			wantSource:     "default",
+		},
+		{
+			name:           "configured",
+			data:           link(0),
+			opts:           ParseOptions{CodePage: CodePageByID(1251)},
+			wantPath:       "Папка\\",
+			wantCodePageID: 1251,
+			wantSource:     "ParseOptions",
+		},
+		{
+			name:           "ConsoleFEDataBlock hint",
+			data:           link(1251),
+			wantPath:       "Папка\\",
+			wantCodePageID: 1251,
+			wantSource:     "ConsoleFEDataBlock",
+		},
+		{
+			name:           "configured wins over ConsoleFEDataBlock",
+			data:           link(1253),
+			opts:           ParseOptions{CodePage: CodePageByID(1251)},
+			wantPath:       "Папка\\",
+			wantCodePageID: 1251,
+			wantSource:     "ParseOptions",
+		},
+		{
+			name:           "unsupported ConsoleFEDataBlock code page",
+			data:           link(1),
+			opts:           ParseOptions{CodePage: CodePageByID(1251)},
+			wantPath:       "Папка\\",
+			wantCodePageID: 1251,
+			wantSource:     "ParseOptions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDataWithOptions(bytes.NewReader(tt.data), tt.opts)
+			if err != nil {
+				t.Fatalf("ParseDataWithOptions() error = %v", err)
+			}
+			if got.StringData.RelativePath != tt.wantPath {
+				t.Errorf("ParseDataWithOptions() RelativePath = %v, want %v", got.StringData.RelativePath, tt.wantPath)
+			}
+			if got.CodePage.ID != tt.wantCodePageID || got.CodePageSource != tt.wantSource {
+				t.Errorf("ParseDataWithOptions() CodePage = %v %v, want %v %v", got.CodePage, got.CodePageSource, tt.wantCodePageID, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Compile builds a .lnk file from JSON in the ShellLinkParsed schema, as written by lnk2json.
+// Sizes and offsets are recomputed and fields composed from several structures (Target, IDListComparison,
+// KnownFolderPath, IDList Path, NetworkPath, UnicodeErrors, Warnings) are ignored. Fields decoded from a single
+// raw value are checked against it and have to be changed through it: ShellLinkHeaderParsed, the LinkFlags and
+// FileAttributes objects, DriveTypeName, DriveSerialNumberFormatted, ValidDevice, ValidNetType,
+// NetworkProviderName, KnownFolderName, SpecialFolderName, the TrackerDataBlock UUIDv1 fields and Moved,
+// ShellItems and PropertyStorages. A decoded object left out of the JSON is not checked.
+// A *Base64 field holds the bytes that are written and the text next to it has to decode from them, clear the
+// *Base64 field to have the text encoded instead. LinkInfoBase64 and IDListBase64 only supply the original layout,
+// they have to hold the values of the fields unless the *Base64 field of a changed string was cleared.
+func Compile(r io.Reader) ([]byte, error) {
+	var shellLinkParsed ShellLinkParsed
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&shellLinkParsed); err != nil {
+		return nil, fmt.Errorf("compile: %w", err)
+	}
+	if err := checkCompile(&shellLinkParsed); err != nil {
+		return nil, fmt.Errorf("compile: %w", err)
+	}
+	return Encode(&shellLinkParsed)
+}
+
+// checkCompile reports decoded fields that disagree with the raw data Encode writes
+// and structures that would be left out because their LinkFlag is not set.
+func checkCompile(shellLinkParsed *ShellLinkParsed) error {
+	linkFlags := shellLinkParsed.Header.LinkFlags
+	if shellLinkParsed.LinkFlagsParsed != (LinkFlagsParsed{}) {
+		if fields := differentFields(shellLinkParsed.LinkFlagsParsed, ParseLinkFlags(linkFlags)); len(fields) > 0 {
+			return fmt.Errorf("LinkFlags %v disagree with ShellLinkHeader.LinkFlags 0x%08X", strings.Join(fields, ", "), linkFlags)
+		}
+	}
+	fileAttributes := shellLinkParsed.Header.FileAttributes
+	if shellLinkParsed.FileAttributesParsed != (FileAttributesParsed{}) {
+		if fields := differentFields(shellLinkParsed.FileAttributesParsed, ParseFileAttributes(fileAttributes)); len(fields) > 0 {
+			return fmt.Errorf("FileAttributes %v disagree with ShellLinkHeader.FileAttributes 0x%08X", strings.Join(fields, ", "), fileAttributes)
+		}
+	}
+
+	linkFlagsParsed := ParseLinkFlags(linkFlags)
+	if !reflect.ValueOf(shellLinkParsed.HeaderParsed).IsZero() {
+		headerParsed := ParseHeaderFields(shellLinkParsed.Header)
+		if linkFlagsParsed.HasIconLocation {
+			headerParsed.IconLocation = fmt.Sprintf("%v,%v", shellLinkParsed.StringData.IconLocation, shellLinkParsed.Header.IconIndex)
+		}
+		if fields := differentFields(shellLinkParsed.HeaderParsed, headerParsed); len(fields) > 0 {
+			return fmt.Errorf("ShellLinkHeaderParsed %v disagree with ShellLinkHeader and StringData.IconLocation", strings.Join(fields, ", "))
+		}
+	}
+	if shellLinkParsed.LinkInfo != nil {
+		if err := checkLinkInfoDecoded(shellLinkParsed.LinkInfo); err != nil {
+			return fmt.Errorf("LinkInfo.%w", err)
+		}
+	}
+	if shellLinkParsed.LinkTargetIDList != nil && !linkFlagsParsed.HasLinkTargetIDList {
+		return fmt.Errorf("LinkTargetIDList is set but LinkFlag 'HasLinkTargetIDList' is not")
+	}
+	if shellLinkParsed.LinkInfo != nil && !linkFlagsParsed.HasLinkInfo {
+		return fmt.Errorf("LinkInfo is set but LinkFlag 'HasLinkInfo' is not")
+	}
+	stringData := shellLinkParsed.StringData
+	for _, item := range []struct {
+		name    string
+		flag    string
+		present bool
+		str     string
+	}{
+		{"NameString", "HasName", linkFlagsParsed.HasName, stringData.NameString},
+		{"RelativePath", "HasRelativePath", linkFlagsParsed.HasRelativePath, stringData.RelativePath},
+		{"WorkingDir", "HasWorkingDir", linkFlagsParsed.HasWorkingDir, stringData.WorkingDir},
+		{"CommandLineArgs", "HasArguments", linkFlagsParsed.HasArguments, stringData.CommandLineArgs},
+		{"IconLocation", "HasIconLocation", linkFlagsParsed.HasIconLocation, stringData.IconLocation},
+	} {
+		if item.str != "" && !item.present {
+			return fmt.Errorf("StringData.%v is set but LinkFlag '%v' is not", item.name, item.flag)
+		}
+	}
+
+	codePage := shellLinkParsed.CodePage
+	if err := checkTexts(shellLinkParsed); err != nil {
+		return err
+	}
+	if shellLinkParsed.LinkInfo != nil {
+		if err := checkLinkInfoBase64(shellLinkParsed.LinkInfo, codePage); err != nil {
+			return fmt.Errorf("LinkInfo.%w", err)
+		}
+	}
+	if shellLinkParsed.LinkTargetIDList != nil {
+		if err := checkItemIDs(shellLinkParsed.LinkTargetIDList.IDListData.ItemIDs, codePage); err != nil {
+			return fmt.Errorf("LinkTargetIDList.%w", err)
+		}
+	}
+	for i, block := range shellLinkParsed.ExtraData {
+		switch {
+		case block.VistaAndAboveIDListDataBlock != nil:
+			if err := checkItemIDs(block.VistaAndAboveIDListDataBlock.IDListData.ItemIDs, codePage); err != nil {
+				return fmt.Errorf("ExtraData[%d].%w", i, err)
+			}
+			if err := checkText(fmt.Sprintf("ExtraData[%d] IDListData", i), "IDListBase64", block.VistaAndAboveIDListDataBlock.IDListBase64, func() ([]byte, error) {
+				return EncodeIDList(block.VistaAndAboveIDListDataBlock.IDListData)
+			}); err != nil {
+				return err
+			}
+		case block.PropertyStoreDataBlock != nil && block.PropertyStoreDataBlock.PropertyStorages != nil:
+			data, err := base64.StdEncoding.DecodeString(block.PropertyStoreDataBlock.PropertyStoreBase64)
+			if err != nil {
+				return fmt.Errorf("ExtraData[%d] PropertyStoreBase64: %w", i, err)
+			}
+			propertyStorages, _ := ParsePropertyStore(data)
+			if !sameJSON(block.PropertyStoreDataBlock.PropertyStorages, propertyStorages) {
+				return fmt.Errorf("ExtraData[%d] PropertyStorages differ from PropertyStoreBase64, edit PropertyStoreBase64 instead", i)
+			}
+		case block.KnownFolderDataBlock != nil && block.KnownFolderDataBlock.KnownFolderName != "":
+			knownFolderID := block.KnownFolderDataBlock.KnownFolderID
+			if block.KnownFolderDataBlock.KnownFolderName != KnownFolderName(knownFolderID) {
+				return fmt.Errorf("ExtraData[%d] KnownFolderName %q disagrees with KnownFolderID %v", i, block.KnownFolderDataBlock.KnownFolderName, knownFolderID)
+			}
+		case block.SpecialFolderDataBlock != nil && block.SpecialFolderDataBlock.SpecialFolderName != "":
+			specialFolderID := block.SpecialFolderDataBlock.SpecialFolderID
+			if block.SpecialFolderDataBlock.SpecialFolderName != SpecialFolderName(specialFolderID) {
+				return fmt.Errorf("ExtraData[%d] SpecialFolderName %q disagrees with SpecialFolderID 0x%X", i, block.SpecialFolderDataBlock.SpecialFolderName, specialFolderID)
+			}
+		case block.TrackerDataBlock != nil:
+			tracker := *block.TrackerDataBlock
+			if tracker.DroidFileUUIDv1 == nil && tracker.BirthDroidFileUUIDv1 == nil && !tracker.Moved {
+				continue
+			}
+			decoded := tracker
+			decoded.DroidFileUUIDv1 = tracker.DroidFile.UUIDv1()
+			decoded.BirthDroidFileUUIDv1 = tracker.BirthDroidFile.UUIDv1()
+			decoded.Moved = tracker.DroidVolume != tracker.BirthDroidVolume || tracker.DroidFile != tracker.BirthDroidFile
+			if fields := differentFields(tracker, decoded); len(fields) > 0 {
+				return fmt.Errorf("ExtraData[%d] TrackerDataBlock %v disagree with the droids", i, strings.Join(fields, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// checkLinkInfoDecoded reports names and flags of the VolumeID and CommonNetworkRelativeLink
+// that disagree with the values they are decoded from, empty names are not checked.
+func checkLinkInfoDecoded(linkInfo *LinkInfo) error {
+	volumeID := linkInfo.VolumeID
+	if volumeID.DriveTypeName != "" && volumeID.DriveTypeName != DriveTypeName(volumeID.DriveType) {
+		return fmt.Errorf("VolumeID DriveTypeName %q disagrees with DriveType 0x%X", volumeID.DriveTypeName, volumeID.DriveType)
+	}
+	if volumeID.DriveSerialNumberFormatted != "" && volumeID.DriveSerialNumberFormatted != FormatDriveSerialNumber(volumeID.DriveSerialNumber) {
+		return fmt.Errorf("VolumeID DriveSerialNumberFormatted %q disagrees with DriveSerialNumber 0x%08X", volumeID.DriveSerialNumberFormatted, volumeID.DriveSerialNumber)
+	}
+	link := linkInfo.CommonNetworkRelativeLink
+	if link == nil {
+		return nil
+	}
+	flags := link.CommonNetworkRelativeLinkFlags
+	if link.ValidDevice != (flags&ValidDevice != 0) || link.ValidNetType != (flags&ValidNetType != 0) {
+		return fmt.Errorf("CommonNetworkRelativeLink ValidDevice and ValidNetType disagree with CommonNetworkRelativeLinkFlags 0x%08X", flags)
+	}
+	if link.NetworkProviderName != "" && (!link.ValidNetType || link.NetworkProviderName != NetworkProviderName(link.NetworkProviderType)) {
+		return fmt.Errorf("CommonNetworkRelativeLink NetworkProviderName %q disagrees with NetworkProviderType 0x%X", link.NetworkProviderName, link.NetworkProviderType)
+	}
+	return nil
+}
+
+// checkTexts reports a text that disagrees with its *Base64 field, so that neither an edit to the text
+// nor one to the *Base64 field is dropped.
+func checkTexts(shellLinkParsed *ShellLinkParsed) error {
+	codePage := shellLinkParsed.CodePage
+	isUnicode := ParseLinkFlags(shellLinkParsed.Header.LinkFlags).IsUnicode
+	type text struct {
+		name    string
+		b64Name string
+		b64     string
+		encode  func() ([]byte, error)
+	}
+	// stringItem, ansi, unicode, fixedANSI and fixedUnicode return a text encoded as Encode writes it
+	stringItem := func(name string, str string, b64 string) text {
+		return text{"StringData." + name, name + "Base64", b64, func() ([]byte, error) {
+			if isUnicode {
+				return encodeUnicodeString(str, b64, false)
+			}
+			return encodeANSIString(str, b64, false, codePage)
+		}}
+	}
+	ansi := func(name string, b64Name string, str string, b64 string) text {
+		return text{name, b64Name, b64, func() ([]byte, error) { return encodeANSIString(str, b64, true, codePage) }}
+	}
+	unicode := func(name string, b64Name string, str string, b64 string) text {
+		return text{name, b64Name, b64, func() ([]byte, error) { return encodeUnicodeString(str, b64, true) }}
+	}
+	fixedANSI := func(name string, b64Name string, str string, b64 string, size uint32) text {
+		return text{name, b64Name, b64, func() ([]byte, error) { return encodeFixedANSI(str, b64, int(size), codePage) }}
+	}
+	fixedUnicode := func(name string, b64Name string, str string, b64 string, minSize uint32) text {
+		return text{name, b64Name, b64, func() ([]byte, error) { return encodeFixedUnicode(str, b64, int(minSize)) }}
+	}
+
+	stringData := shellLinkParsed.StringData
+	texts := []text{
+		stringItem("NameString", stringData.NameString, stringData.NameStringBase64),
+		stringItem("RelativePath", stringData.RelativePath, stringData.RelativePathBase64),
+		stringItem("WorkingDir", stringData.WorkingDir, stringData.WorkingDirBase64),
+		stringItem("CommandLineArgs", stringData.CommandLineArgs, stringData.CommandLineArgsBase64),
+		stringItem("IconLocation", stringData.IconLocation, stringData.IconLocationBase64),
+	}
+	if linkInfo := shellLinkParsed.LinkInfo; linkInfo != nil {
+		volumeID := linkInfo.VolumeID
+		if volumeID.VolumeLabelOffset == VolumeLabelOffsetUnicodePresent || volumeID.VolumeLabelUnicode != "" {
+			texts = append(texts, unicode("LinkInfo.VolumeID.VolumeLabelUnicode", "VolumeLabelBase64", volumeID.VolumeLabelUnicode, volumeID.VolumeLableBase64))
+		} else {
+			texts = append(texts, ansi("LinkInfo.VolumeID.VolumeLabel", "VolumeLabelBase64", volumeID.VolumeLabel, volumeID.VolumeLableBase64))
+		}
+		texts = append(texts,
+			ansi("LinkInfo.LocalBasePath", "LocalBasePathBase64", linkInfo.LocalBasePath, linkInfo.LocalBasePathBase64),
+			ansi("LinkInfo.CommonPathSuffix", "CommonPathSuffixBase64", linkInfo.CommonPathSuffix, linkInfo.CommonPathSuffixBase64),
+			unicode("LinkInfo.LocalBasePathUnicode", "LocalBasePathUnicodeBase64", linkInfo.LocalBasePathUnicode, linkInfo.LocalBasePathUnicodeBase64),
+			unicode("LinkInfo.CommonPathSuffixUnicode", "CommonPathSuffixUnicodeBase64", linkInfo.CommonPathSuffixUnicode, linkInfo.CommonPathSuffixUnicodeBase64),
+		)
+		if link := linkInfo.CommonNetworkRelativeLink; link != nil {
+			texts = append(texts,
+				ansi("LinkInfo.CommonNetworkRelativeLink.NetName", "NetNameBase64", link.NetName, link.NetNameBase64),
+				ansi("LinkInfo.CommonNetworkRelativeLink.DeviceName", "DeviceNameBase64", link.DeviceName, link.DeviceNameBase64),
+				unicode("LinkInfo.CommonNetworkRelativeLink.NetNameUnicode", "NetNameUnicodeBase64", link.NetNameUnicode, link.NetNameUnicodeBase64),
+				unicode("LinkInfo.CommonNetworkRelativeLink.DeviceNameUnicode", "DeviceNameUnicodeBase64", link.DeviceNameUnicode, link.DeviceNameUnicodeBase64),
+			)
+		}
+	}
+	for i, block := range shellLinkParsed.ExtraData {
+		prefix := fmt.Sprintf("ExtraData[%d] ", i)
+		switch {
+		case block.DarwinDataBlock != nil:
+			darwin := block.DarwinDataBlock
+			texts = append(texts,
+				fixedANSI(prefix+"DarwinDataAnsi", "DarwinDataAnsiBase64", darwin.DarwinDataAnsi, darwin.DarwinDataAnsiBase64, ExtraDataAnsiStringSize),
+				fixedUnicode(prefix+"DarwinDataUnicode", "DarwinDataUnicodeBase64", darwin.DarwinDataUnicode, darwin.DarwinDataUnicodeBase64, ExtraDataUnicodeStringSize),
+			)
+		case block.EnvironmentVariableDataBlock != nil:
+			environment := block.EnvironmentVariableDataBlock
+			texts = append(texts,
+				fixedANSI(prefix+"TargetAnsi", "TargetAnsiBase64", environment.TargetAnsi, environment.TargetAnsiBase64, ExtraDataAnsiStringSize),
+				fixedUnicode(prefix+"TargetUnicode", "TargetUnicodeBase64", environment.TargetUnicode, environment.TargetUnicodeBase64, ExtraDataUnicodeStringSize),
+			)
+		case block.IconEnvironmentDataBlock != nil:
+			icon := block.IconEnvironmentDataBlock
+			texts = append(texts,
+				fixedANSI(prefix+"TargetAnsi", "TargetAnsiBase64", icon.TargetAnsi, icon.TargetAnsiBase64, ExtraDataAnsiStringSize),
+				fixedUnicode(prefix+"TargetUnicode", "TargetUnicodeBase64", icon.TargetUnicode, icon.TargetUnicodeBase64, ExtraDataUnicodeStringSize),
+			)
+		case block.ShimDataBlock != nil:
+			shim := block.ShimDataBlock
+			texts = append(texts, fixedUnicode(prefix+"LayerName", "LayerNameBase64", shim.LayerName, shim.LayerNameBase64, ShimDataBlockSizeMin-ExtraDataBlockSizeMin))
+		case block.TrackerDataBlock != nil:
+			tracker := block.TrackerDataBlock
+			texts = append(texts, fixedANSI(prefix+"MachineID", "MachineIDBase64", tracker.MachineID, tracker.MachineIDBase64, 16))
+		}
+	}
+
+	for _, text := range texts {
+		if err := checkText(text.name, text.b64Name, text.b64, text.encode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkText reports a value that does not encode to the bytes in its *Base64 field b64, an empty b64 is not checked.
+func checkText(name string, b64Name string, b64 string, encode func() ([]byte, error)) error {
+	if b64 == "" {
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return fmt.Errorf("%v %v: %w", name, b64Name, err)
+	}
+	if encoded, err := encode(); err != nil || !bytes.Equal(encoded, raw) {
+		return fmt.Errorf("%v disagrees with %v, clear %v to write %v", name, b64Name, b64Name, name)
+	}
+	return nil
+}
+
+// checkLinkInfoBase64 reports a LinkInfoBase64 that holds other values than the LinkInfo fields. Sizes and offsets
+// are not compared and neither are strings whose *Base64 field was cleared, LinkInfoBase64 is not used for them.
+func checkLinkInfoBase64(linkInfo *LinkInfo, codePage *CodePage) error {
+	raw, err := base64.StdEncoding.DecodeString(linkInfo.LinkInfoBase64)
+	if err != nil {
+		return fmt.Errorf("LinkInfoBase64: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	parsed, err := ParseLinkInfo(bytes.NewReader(raw), codePage)
+	if err != nil {
+		// a damaged LinkInfo is rebuilt from the fields
+		return nil
+	}
+	type value struct {
+		name           string
+		field, encoded interface{}
+	}
+	values := []value{
+		{"LinkInfoFlags", linkInfo.LinkInfoFlags, parsed.LinkInfoFlags},
+		{"VolumeID.DriveType", linkInfo.VolumeID.DriveType, parsed.VolumeID.DriveType},
+		{"VolumeID.DriveSerialNumber", linkInfo.VolumeID.DriveSerialNumber, parsed.VolumeID.DriveSerialNumber},
+	}
+	strs := []value{
+		{"VolumeID.VolumeLabelBase64", linkInfo.VolumeID.VolumeLableBase64, parsed.VolumeID.VolumeLableBase64},
+		{"LocalBasePathBase64", linkInfo.LocalBasePathBase64, parsed.LocalBasePathBase64},
+		{"CommonPathSuffixBase64", linkInfo.CommonPathSuffixBase64, parsed.CommonPathSuffixBase64},
+		{"LocalBasePathUnicodeBase64", linkInfo.LocalBasePathUnicodeBase64, parsed.LocalBasePathUnicodeBase64},
+		{"CommonPathSuffixUnicodeBase64", linkInfo.CommonPathSuffixUnicodeBase64, parsed.CommonPathSuffixUnicodeBase64},
+	}
+	if link, parsedLink := linkInfo.CommonNetworkRelativeLink, parsed.CommonNetworkRelativeLink; link != nil && parsedLink != nil {
+		values = append(values,
+			value{"CommonNetworkRelativeLink.CommonNetworkRelativeLinkFlags", link.CommonNetworkRelativeLinkFlags, parsedLink.CommonNetworkRelativeLinkFlags},
+			value{"CommonNetworkRelativeLink.NetworkProviderType", link.NetworkProviderType, parsedLink.NetworkProviderType},
+		)
+		strs = append(strs,
+			value{"CommonNetworkRelativeLink.NetNameBase64", link.NetNameBase64, parsedLink.NetNameBase64},
+			value{"CommonNetworkRelativeLink.DeviceNameBase64", link.DeviceNameBase64, parsedLink.DeviceNameBase64},
+			value{"CommonNetworkRelativeLink.NetNameUnicodeBase64", link.NetNameUnicodeBase64, parsedLink.NetNameUnicodeBase64},
+			value{"CommonNetworkRelativeLink.DeviceNameUnicodeBase64", link.DeviceNameUnicodeBase64, parsedLink.DeviceNameUnicodeBase64},
+		)
+	}
+	for _, str := range strs {
+		if str.field != "" {
+			values = append(values, str)
+		}
+	}
+	for _, v := range values {
+		if v.field != v.encoded {
+			return fmt.Errorf("%v disagrees with LinkInfoBase64, clear LinkInfoBase64 to write the fields", v.name)
+		}
+	}
+	return nil
+}
+
+// checkItemIDs reports a ShellItem that was edited, the ItemID is written from ItemIDDataBase64 only.
+func checkItemIDs(itemIDs []ItemID, codePage *CodePage) error {
+	for i, itemID := range itemIDs {
+		data, err := base64.StdEncoding.DecodeString(itemID.ItemIDDataBase64)
+		if err != nil {
+			return fmt.Errorf("ItemIDs[%d] ItemIDDataBase64: %w", i, err)
+		}
+		if reflect.ValueOf(itemID.ShellItem).IsZero() {
+			continue
+		}
+		if !sameJSON(itemID.ShellItem, ParseShellItem(data, codePage)) {
+			return fmt.Errorf("ItemIDs[%d] ShellItem differs from ItemIDDataBase64, edit ItemIDDataBase64 instead", i)
+		}
+	}
+	return nil
+}
+
+// differentFields lists the json names of the fields in which two structs of the same type differ.
+func differentFields(a interface{}, b interface{}) []string {
+	var fields []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !sameJSON(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, strings.Split(va.Type().Field(i).Tag.Get("json"), ",")[0])
+		}
+	}
+	return fields
+}
+
+// sameJSON compares values by their JSON, which is all a value read from JSON can be compared by.
+func sameJSON(a interface{}, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_Compile(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		modify  func(*ShellLinkParsed)
+		json    func([]byte) []byte
+		want    func(*testing.T, []byte)
+		wantErr string
+	}{
+		{
+			name: "unmodified",
+			want: func(t *testing.T, got []byte) {
+				if !bytes.Equal(got, fixture) {
+					t.Errorf("Compile() differs from the parsed file, got %v bytes, want %v", len(got), len(fixture))
+				}
+			},
+		},
+		{
+			name: "arguments edited",
+			modify: func(s *ShellLinkParsed) {
+				s.StringData.CommandLineArgs = "/p C:\\Temp\\report.txt"
+				s.StringData.CommandLineArgsBase64 = ""
+				s.LinkInfo.LinkInfoSize = 0
+			},
+			want: func(t *testing.T, got []byte) {
+				reparsed, err := ParseData(bytes.NewReader(got))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reparsed.StringData.CommandLineArgs != "/p C:\\Temp\\report.txt" {
+					t.Errorf("CommandLineArgs = %q", reparsed.StringData.CommandLineArgs)
+				}
+			},
+		},
+		{
+			name: "LocalBasePath edited",
+			modify: func(s *ShellLinkParsed) {
+				s.LinkInfo.LocalBasePath = "C:\\Windows\\System32\\notepad.exe"
+				s.LinkInfo.LocalBasePathBase64 = ""
+			},
+			want: func(t *testing.T, got []byte) {
+				reparsed, err := ParseData(bytes.NewReader(got))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reparsed.LinkInfo.LocalBasePath != "C:\\Windows\\System32\\notepad.exe" {
+					t.Errorf("LocalBasePath = %q", reparsed.LinkInfo.LocalBasePath)
+				}
+			},
+		},
+		{
+			name:    "text edited, Base64 kept",
+			modify:  func(s *ShellLinkParsed) { s.StringData.CommandLineArgs = "/p" },
+			wantErr: "StringData.CommandLineArgs disagrees with CommandLineArgsBase64, clear CommandLineArgsBase64",
+		},
+		{
+			name: "Base64 edited, text kept",
+			modify: func(s *ShellLinkParsed) {
+				s.StringData.CommandLineArgsBase64 = base64.StdEncoding.EncodeToString(encodeUTF16LE("/p"))
+			},
+			wantErr: "StringData.CommandLineArgs disagrees with CommandLineArgsBase64",
+		},
+		{
+			name: "LinkInfo field edited, LinkInfoBase64 kept",
+			modify: func(s *ShellLinkParsed) {
+				s.LinkInfo.VolumeID.DriveSerialNumber++
+				s.LinkInfo.VolumeID.DriveSerialNumberFormatted = ""
+			},
+			wantErr: "LinkInfo.VolumeID.DriveSerialNumber disagrees with LinkInfoBase64, clear LinkInfoBase64",
+		},
+		{
+			name: "stdout file result",
+			json: func(data []byte) []byte {
+				return []byte(`{"Path":"x.lnk","ShellLink":` + string(data) + `}`)
+			},
+			wantErr: `unknown field "Path"`,
+		},
+		{
+			name:    "LinkFlags disagree",
+			modify:  func(s *ShellLinkParsed) { s.LinkFlagsParsed.HasName = !s.LinkFlagsParsed.HasName },
+			wantErr: "LinkFlags HasName disagree with ShellLinkHeader.LinkFlags",
+		},
+		{
+			name:    "FileAttributes disagree",
+			modify:  func(s *ShellLinkParsed) { s.FileAttributesParsed.FileAttributeHidden = true },
+			wantErr: "FileAttributes FileAttributeHidden disagree",
+		},
+		{
+			name:    "ShowCommand disagrees",
+			modify:  func(s *ShellLinkParsed) { s.HeaderParsed.ShowCommand = "SW_SHOWMAXIMIZED" },
+			wantErr: "ShellLinkHeaderParsed ShowCommand disagree with ShellLinkHeader",
+		},
+		{
+			name: "WriteTime disagrees",
+			modify: func(s *ShellLinkParsed) {
+				writeTime := s.HeaderParsed.WriteTime.AddDate(1, 0, 0)
+				s.HeaderParsed.WriteTime = &writeTime
+			},
+			wantErr: "ShellLinkHeaderParsed WriteTime disagree",
+		},
+		{
+			name:    "DriveTypeName disagrees",
+			modify:  func(s *ShellLinkParsed) { s.LinkInfo.VolumeID.DriveTypeName = "DRIVE_REMOTE" },
+			wantErr: "LinkInfo.VolumeID DriveTypeName \"DRIVE_REMOTE\" disagrees with DriveType 0x3",
+		},
+		{
+			name: "SpecialFolderName disagrees",
+			modify: func(s *ShellLinkParsed) {
+				for _, block := range s.ExtraData {
+					if block.SpecialFolderDataBlock != nil {
+						block.SpecialFolderDataBlock.SpecialFolderName = "CSIDL_DESKTOP"
+					}
+				}
+			},
+			wantErr: "SpecialFolderName \"CSIDL_DESKTOP\" disagrees with SpecialFolderID",
+		},
+		{
+			name: "TrackerDataBlock Moved disagrees",
+			modify: func(s *ShellLinkParsed) {
+				for _, block := range s.ExtraData {
+					if block.TrackerDataBlock != nil {
+						block.TrackerDataBlock.Moved = !block.TrackerDataBlock.Moved
+					}
+				}
+			},
+			wantErr: "TrackerDataBlock Moved disagree with the droids",
+		},
+		{
+			name: "string without LinkFlag",
+			modify: func(s *ShellLinkParsed) {
+				s.Header.LinkFlags &^= HasArguments
+				s.LinkFlagsParsed.HasArguments = false
+				s.StringData.CommandLineArgs = "x"
+			},
+			wantErr: "StringData.CommandLineArgs is set but LinkFlag 'HasArguments' is not",
+		},
+		{
+			name:    "ShellItem edited",
+			modify:  func(s *ShellLinkParsed) { s.LinkTargetIDList.IDListData.ItemIDs[1].ShellItem.ClassTypeName = "edited" },
+			wantErr: "LinkTargetIDList.ItemIDs[1] ShellItem differs from ItemIDDataBase64",
+		},
+		{
+			name: "string outside code page",
+			modify: func(s *ShellLinkParsed) {
+				s.LinkInfo.LocalBasePath = "C:\\日本.exe"
+				s.LinkInfo.LocalBasePathBase64 = ""
+			},
+			wantErr: "encode LinkInfo: LocalBasePath",
+		},
+		{
+			name: "unsupported code page",
+			json: func(data []byte) []byte {
+				return bytes.Replace(data, []byte(`"CodePage":{"ID":1252`), []byte(`"CodePage":{"ID":1`), 1)
+			},
+			wantErr: `unsupported code page "1"`,
+		},
+		{
+			name:    "not JSON",
+			json:    func([]byte) []byte { return []byte("L\x00\x00\x00") },
+			wantErr: "compile: invalid character",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shellLinkParsed, err := ParseData(bytes.NewReader(fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.modify != nil {
+				tt.modify(&shellLinkParsed)
+			}
+			data, err := json.Marshal(shellLinkParsed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.json != nil {
+				data = tt.json(data)
+			}
+			got, err := Compile(bytes.NewReader(data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Compile() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			tt.want(t, got)
+		})
+	}
+}
@@ -1,6 +1,6 @@
-// LnkToJson project doc.go
-
-/*
-LnkToJson document
-*/
-package main
+// LnkToJson project doc.go
+
+/*
+LnkToJson document
+*/
+package main
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ShortcutEditor changes fields of an existing shortcut and writes it back. Structures whose fields were not
+// changed keep their original bytes, including unknown ExtraData blocks and shell items that are not decoded.
+type ShortcutEditor struct {
+	ShellLink ShellLinkParsed // The shortcut to edit, fields can also be changed directly
+	raw       encodedSections // Sections as read from the file
+	encoded   encodedSections // Sections as Encode writes the unmodified shortcut
+	tail      []byte          // TerminalBlock and any data after it
+}
+
+// EditFields lists the fields ShortcutEditor.Set accepts.
+var EditFields = []string{"args", "workdir", "icon", "description", "target", "flags"}
+
+// OpenShortcut parses a shortcut for editing, a damaged file is not accepted even with opts.Lenient.
+func OpenShortcut(data []byte, opts ParseOptions) (*ShortcutEditor, error) {
+	opts.Lenient = false
+	shellLinkParsed, err := ParseDataWithOptions(bytes.NewReader(data), opts)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := encodeSections(&shellLinkParsed)
+	if err != nil {
+		return nil, err
+	}
+	editor := &ShortcutEditor{ShellLink: shellLinkParsed, encoded: encoded}
+	editor.raw, editor.tail = splitSections(data, ParseLinkFlags(shellLinkParsed.Header.LinkFlags))
+	return editor, nil
+}
+
+// splitSections cuts a parsed file into its sections, the sizes were checked when the file was parsed.
+func splitSections(data []byte, linkFlagsParsed LinkFlagsParsed) (encodedSections, []byte) {
+	var sections encodedSections
+	offset := int(HeaderSizeExpected)
+	next := func(size int) []byte {
+		section := data[offset : offset+size]
+		offset += size
+		return section
+	}
+	sections.header = data[:offset]
+	if linkFlagsParsed.HasLinkTargetIDList {
+		sections.linkTargetIDList = next(2 + int(binary.LittleEndian.Uint16(data[offset:])))
+	}
+	if linkFlagsParsed.HasLinkInfo {
+		sections.linkInfo = next(int(binary.LittleEndian.Uint32(data[offset:])))
+	}
+
+	start := offset
+	for _, present := range []bool{linkFlagsParsed.HasName, linkFlagsParsed.HasRelativePath, linkFlagsParsed.HasWorkingDir,
+		linkFlagsParsed.HasArguments, linkFlagsParsed.HasIconLocation} {
+		if !present {
+			continue
+		}
+		size := int(binary.LittleEndian.Uint16(data[offset:]))
+		if linkFlagsParsed.IsUnicode {
+			size *= 2
+		}
+		offset += 2 + size
+	}
+	sections.stringData = data[start:offset]
+
+	for len(data)-offset >= 4 {
+		blockSize := binary.LittleEndian.Uint32(data[offset:])
+		if blockSize < ExtraDataBlockSizeMin {
+			break
+		}
+		sections.extraData = append(sections.extraData, next(int(blockSize)))
+	}
+	return sections, data[offset:]
+}
+
+// Bytes returns the edited shortcut. A section keeps its original bytes if it encodes as it did when opened,
+// an ExtraData block if it encodes as any original block that was not used yet.
+func (e *ShortcutEditor) Bytes() ([]byte, error) {
+	sections, err := encodeSections(&e.ShellLink)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	for _, section := range []struct{ encoded, unmodified, raw []byte }{
+		{sections.header, e.encoded.header, e.raw.header},
+		{sections.linkTargetIDList, e.encoded.linkTargetIDList, e.raw.linkTargetIDList},
+		{sections.linkInfo, e.encoded.linkInfo, e.raw.linkInfo},
+		{sections.stringData, e.encoded.stringData, e.raw.stringData},
+	} {
+		if section.encoded != nil && bytes.Equal(section.encoded, section.unmodified) {
+			b = append(b, section.raw...)
+		} else {
+			b = append(b, section.encoded...)
+		}
+	}
+
+	used := make([]bool, len(e.encoded.extraData))
+	extraDataChanged := len(sections.extraData) != len(e.encoded.extraData)
+	for i, block := range sections.extraData {
+		j := 0
+		for ; j < len(used); j++ {
+			if !used[j] && bytes.Equal(block, e.encoded.extraData[j]) {
+				break
+			}
+		}
+		if j < len(used) {
+			used[j] = true
+			extraDataChanged = extraDataChanged || i != j
+			b = append(b, e.raw.extraData[j]...)
+		} else {
+			extraDataChanged = true
+			b = append(b, block...)
+		}
+	}
+	if len(e.tail) == 0 && extraDataChanged {
+		// the original file ended without TerminalBlock
+		return putUint32(b, 0), nil
+	}
+	return append(b, e.tail...), nil
+}
+
+// SetArguments sets the command line arguments, an empty string removes them.
+func (e *ShortcutEditor) SetArguments(arguments string) {
+	e.setString(&e.ShellLink.StringData.CommandLineArgs, HasArguments, arguments)
+}
+
+// SetWorkingDir sets the working directory, an empty string removes it.
+func (e *ShortcutEditor) SetWorkingDir(workingDir string) {
+	e.setString(&e.ShellLink.StringData.WorkingDir, HasWorkingDir, workingDir)
+}
+
+// SetDescription sets the description stored as NameString, an empty string removes it.
+func (e *ShortcutEditor) SetDescription(description string) {
+	e.setString(&e.ShellLink.StringData.NameString, HasName, description)
+}
+
+// SetIconLocation sets the icon location and the index of the icon in it, an empty location removes it.
+func (e *ShortcutEditor) SetIconLocation(iconLocation string, iconIndex int32) {
+	e.setString(&e.ShellLink.StringData.IconLocation, HasIconLocation, iconLocation)
+	e.ShellLink.Header.IconIndex = iconIndex
+}
+
+func (e *ShortcutEditor) setString(field *string, linkFlag uint32, value string) {
+	*field = value
+	if value == "" {
+		e.ShellLink.Header.LinkFlags &^= linkFlag
+	} else {
+		e.ShellLink.Header.LinkFlags |= linkFlag
+	}
+}
+
+// SetTargetPath points the shortcut to a local or UNC path by replacing its LinkInfo, the VolumeID of a
+// local target is kept. The LinkTargetIDList, RelativePath and the ExtraData blocks that locate the old
+// target (Darwin, EnvironmentVariable, KnownFolder, SpecialFolder and VistaAndAboveIDList) are removed.
+// The PropertyStoreDataBlock is kept with its other properties, a stale target path in it shows as Target Conflict.
+func (e *ShortcutEditor) SetTargetPath(targetPath string) error {
+	shellLink := &e.ShellLink
+	volumeID := VolumeID{DriveType: DriveFixed}
+	if shellLink.LinkInfo != nil && shellLink.LinkInfo.LinkInfoFlags&VolumeIDAndLocalBasePathPresent != 0 {
+		volumeID = shellLink.LinkInfo.VolumeID
+	}
+	linkInfo, err := linkInfoForPath(strings.ReplaceAll(targetPath, "/", `\`), volumeID, shellLink.CodePage)
+	if err != nil {
+		return err
+	}
+
+	shellLink.LinkInfo = linkInfo
+	shellLink.LinkTargetIDList = nil
+	shellLink.StringData.RelativePath = ""
+	shellLink.Header.LinkFlags |= HasLinkInfo
+	shellLink.Header.LinkFlags &^= HasLinkTargetIDList | HasRelativePath | ForceNoLinkInfo | HasExpString | HasDarwinID
+	var extraData = []ExtraDataBlock{}
+	for _, block := range shellLink.ExtraData {
+		if block.DarwinDataBlock == nil && block.EnvironmentVariableDataBlock == nil && block.KnownFolderDataBlock == nil &&
+			block.SpecialFolderDataBlock == nil && block.VistaAndAboveIDListDataBlock == nil {
+			extraData = append(extraData, block)
+		}
+	}
+	shellLink.ExtraData = extraData
+	return nil
+}
+
+// SetLinkFlags sets and clears LinkFlags, flags controlling a section add or remove that section.
+func (e *ShortcutEditor) SetLinkFlags(set uint32, clear uint32) {
+	e.ShellLink.Header.LinkFlags = e.ShellLink.Header.LinkFlags&^clear | set
+}
+
+// Set changes a field by name, see EditFields:
+//
+//	args, workdir, description  the string, empty to remove it
+//	icon                        "path,index" as in ShellLinkHeaderParsed.IconLocation, "path" keeps the IconIndex
+//	target                      a local or UNC path, see SetTargetPath
+//	flags                       LinkFlags names separated by commas, "-" clears a flag, e.g. "RunAsUser,-HasExpIcon"
+func (e *ShortcutEditor) Set(field string, value string) error {
+	switch field {
+	case "args":
+		e.SetArguments(value)
+	case "workdir":
+		e.SetWorkingDir(value)
+	case "description":
+		e.SetDescription(value)
+	case "icon":
+		iconLocation, iconIndex := value, e.ShellLink.Header.IconIndex
+		if i := strings.LastIndex(value, ","); i >= 0 {
+			index, err := strconv.ParseInt(value[i+1:], 10, 32)
+			if err != nil {
+				return fmt.Errorf("icon index %q is not an integer", value[i+1:])
+			}
+			iconLocation, iconIndex = value[:i], int32(index)
+		}
+		e.SetIconLocation(iconLocation, iconIndex)
+	case "target":
+		return e.SetTargetPath(value)
+	case "flags":
+		set, clear, err := parseLinkFlagChanges(value)
+		if err != nil {
+			return err
+		}
+		e.SetLinkFlags(set, clear)
+	default:
+		return fmt.Errorf("unknown field %q, expected one of %v", field, strings.Join(EditFields, ", "))
+	}
+	return nil
+}
+
+// parseLinkFlagChanges parses LinkFlags names like "RunAsUser,-HasExpIcon" into the flags to set and to clear.
+func parseLinkFlagChanges(value string) (set uint32, clear uint32, err error) {
+	// the fields of LinkFlagsParsed are in bit order
+	flagsType := reflect.TypeOf(LinkFlagsParsed{})
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		target := &set
+		if strings.HasPrefix(name, "-") {
+			target = &clear
+		}
+		name = strings.TrimLeft(name, "+-")
+		found := false
+		for i := 0; i < flagsType.NumField(); i++ {
+			if strings.EqualFold(flagsType.Field(i).Tag.Get("json"), name) {
+				*target |= 1 << i
+				found = true
+			}
+		}
+		if !found {
+			return 0, 0, errors.New("unknown LinkFlag " + strconv.Quote(name))
+		}
+	}
+	return set, clear, nil
+}
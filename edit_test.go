@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_ShortcutEditor(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fixture sections: StringData 0x17A-0x1DE with CommandLineArgs at 0x1C6, ExtraData PropertyStore and Tracker 0x51E-0x67C
+	withTrailer := append(append([]byte{}, fixture...), 0xDE, 0xAD)
+	// LinkInfo 0x135-0x17A replaced by one with LinkInfoHeaderSize 0x28
+	withHeaderExtension := append(append(append([]byte{}, fixture[:0x135]...), linkInfoHeaderExtension...), fixture[0x17A:]...)
+	headerSizeBeyondLinkInfo := append([]byte{}, fixture...)
+	headerSizeBeyondLinkInfo[0x13C] = 0x90
+	tests := []struct {
+		name    string
+		data    []byte
+		sets    [][2]string
+		check   func(*testing.T, []byte, ShellLinkParsed)
+		wantErr bool
+	}{
+		{
+			name: "unmodified",
+			data: fixture,
+			check: func(t *testing.T, got []byte, _ ShellLinkParsed) {
+				if !bytes.Equal(got, fixture) {
+					t.Errorf("Bytes() differs from the opened file")
+				}
+			},
+		},
+		{
+			name: "arguments",
+			data: withTrailer,
+			sets: [][2]string{{"args", "/P report.txt"}},
+			check: func(t *testing.T, got []byte, s ShellLinkParsed) {
+				if s.StringData.CommandLineArgs != "/P report.txt" {
+					t.Errorf("CommandLineArgs = %q", s.StringData.CommandLineArgs)
+				}
+				if !bytes.Equal(got[:0x1C6], withTrailer[:0x1C6]) || !bytes.Equal(got[len(got)-0x4A4:], withTrailer[len(withTrailer)-0x4A4:]) {
+					t.Errorf("Bytes() changed more than CommandLineArgs")
+				}
+			},
+		},
+		{
+			name: "icon and description",
+			data: fixture,
+			sets: [][2]string{{"icon", `C:\Windows\System32\shell32.dll,-3`}, {"description", "Notepad"}},
+			check: func(t *testing.T, got []byte, s ShellLinkParsed) {
+				if s.HeaderParsed.IconLocation != `C:\Windows\System32\shell32.dll,-3` || s.StringData.NameString != "Notepad" {
+					t.Errorf("IconLocation = %q, NameString = %q", s.HeaderParsed.IconLocation, s.StringData.NameString)
+				}
+				if !s.LinkFlagsParsed.HasIconLocation || !s.LinkFlagsParsed.HasName {
+					t.Errorf("LinkFlags = 0x%08X", s.Header.LinkFlags)
+				}
+				if !bytes.Equal(got[0x4C:0x17A], fixture[0x4C:0x17A]) {
+					t.Errorf("Bytes() changed LinkTargetIDList or LinkInfo")
+				}
+			},
+		},
+		{
+			name: "icon without index",
+			data: fixture,
+			sets: [][2]string{{"icon", `C:\Windows\System32\shell32.dll`}},
+			check: func(t *testing.T, _ []byte, s ShellLinkParsed) {
+				if s.HeaderParsed.IconLocation != `C:\Windows\System32\shell32.dll,2` {
+					t.Errorf("IconLocation = %q", s.HeaderParsed.IconLocation)
+				}
+			},
+		},
+		{
+			name:    "icon index not an integer",
+			data:    fixture,
+			sets:    [][2]string{{"icon", "foo.dll,x"}},
+			wantErr: true,
+		},
+		{
+			name: "working directory removed",
+			data: fixture,
+			sets: [][2]string{{"workdir", ""}},
+			check: func(t *testing.T, _ []byte, s ShellLinkParsed) {
+				if s.LinkFlagsParsed.HasWorkingDir || s.StringData.WorkingDir != "" {
+					t.Errorf("WorkingDir = %q, LinkFlags = 0x%08X", s.StringData.WorkingDir, s.Header.LinkFlags)
+				}
+			},
+		},
+		{
+			name: "target",
+			data: fixture,
+			sets: [][2]string{{"target", `D:\Tools\notepad++.exe`}},
+			check: func(t *testing.T, got []byte, s ShellLinkParsed) {
+				if s.Target == nil || s.Target.Path != `D:\Tools\notepad++.exe` || s.Target.Source != "LinkInfo" {
+					t.Errorf("Target = %+v", s.Target)
+				}
+				if s.LinkTargetIDList != nil || s.StringData.RelativePath != "" || len(s.ExtraData) != 2 {
+					t.Errorf("LinkTargetIDList = %v, RelativePath = %q, ExtraData = %v", s.LinkTargetIDList, s.StringData.RelativePath, len(s.ExtraData))
+				}
+				if s.LinkInfo.VolumeID.DriveSerialNumberFormatted != "1234-ABCD" {
+					t.Errorf("VolumeID = %+v", s.LinkInfo.VolumeID)
+				}
+				if !bytes.HasSuffix(got, fixture[0x51E:]) {
+					t.Errorf("Bytes() changed PropertyStore or Tracker")
+				}
+			},
+		},
+		{
+			name: "flags",
+			data: fixture,
+			sets: [][2]string{{"flags", "RunAsUser, -EnableTargetMetadata"}},
+			check: func(t *testing.T, got []byte, s ShellLinkParsed) {
+				if s.Header.LinkFlags != 0x000022BB {
+					t.Errorf("LinkFlags = 0x%08X", s.Header.LinkFlags)
+				}
+				if !bytes.Equal(got[HeaderSizeExpected:], fixture[HeaderSizeExpected:]) {
+					t.Errorf("Bytes() changed more than the header")
+				}
+			},
+		},
+		{
+			name:    "unknown flag",
+			data:    fixture,
+			sets:    [][2]string{{"flags", "RunAsAdmin"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			data:    fixture,
+			sets:    [][2]string{{"hotkey", "Ctrl+A"}},
+			wantErr: true,
+		},
+		{
+			name:    "relative target",
+			data:    fixture,
+			sets:    [][2]string{{"target", `notepad.exe`}},
+			wantErr: true,
+		},
+		{
+			name: "arguments with LinkInfoHeaderSize 0x28",
+			data: withHeaderExtension,
+			sets: [][2]string{{"args", "/P report.txt"}},
+			check: func(t *testing.T, got []byte, s ShellLinkParsed) {
+				if s.StringData.CommandLineArgs != "/P report.txt" || s.LinkInfo.LocalBasePath != `C:\` {
+					t.Errorf("CommandLineArgs = %q, LocalBasePath = %q", s.StringData.CommandLineArgs, s.LinkInfo.LocalBasePath)
+				}
+				if !bytes.Equal(got[:0x173], withHeaderExtension[:0x173]) {
+					t.Errorf("Bytes() changed the header, LinkTargetIDList or LinkInfo")
+				}
+			},
+		},
+		{
+			name:    "arguments with LinkInfoHeaderSize beyond LinkInfoSize",
+			data:    headerSizeBeyondLinkInfo,
+			sets:    [][2]string{{"args", "/P report.txt"}},
+			wantErr: true,
+		},
+		{
+			name:    "damaged file",
+			data:    fixture[:0x150],
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor, err := OpenShortcut(tt.data, ParseOptions{})
+			for _, set := range tt.sets {
+				if err != nil {
+					break
+				}
+				err = editor.Set(set[0], set[1])
+			}
+			var got []byte
+			if err == nil {
+				got, err = editor.Bytes()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ShortcutEditor error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			reparsed, err := ParseData(bytes.NewReader(got))
+			if err != nil {
+				t.Fatalf("ParseData() of edited file error = %v", err)
+			}
+			tt.check(t, got, reparsed)
+		})
+	}
+}
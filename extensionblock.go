@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// ExtensionBlock represents an extension block of a shell item, FileEntry and ShellFolderID are
+// set for the signatures that are decoded, the data of the others is kept as base64.
+type ExtensionBlock struct {
+	Size          uint16                   `json:"Size"`
+	Version       uint16                   `json:"Version"`
+	Signature     uint32                   `json:"Signature"`
+	SignatureName string                   `json:"SignatureName"`
+	FileEntry     *FileEntryExtensionBlock `json:"FileEntry,omitempty"`
+	ShellFolderID *GUID                    `json:"ShellFolderID,omitempty"`
+	DataBase64    string                   `json:"DataBase64,omitempty"`
+}
+
+// FileEntryExtensionBlock represents the 0xBEEF0004 extension block of a file entry shell item.
+type FileEntryExtensionBlock struct {
+	CreationTimeRaw                  uint32             `json:"CreationTimeRaw"`
+	CreationTime                     *time.Time         `json:"CreationTime"`
+	AccessTimeRaw                    uint32             `json:"AccessTimeRaw"`
+	AccessTime                       *time.Time         `json:"AccessTime"`
+	Identifier                       uint16             `json:"Identifier"`
+	IdentifierName                   string             `json:"IdentifierName,omitempty"`
+	NTFSFileReference                *NTFSFileReference `json:"NTFSFileReference,omitempty"` // Version 7 and above
+	LongStringSize                   uint16             `json:"LongStringSize"`              // Version 3 and above
+	LongName                         string             `json:"LongName"`
+	LocalizedName                    string             `json:"LocalizedName,omitempty"`
+	FirstExtensionBlockVersionOffset uint16             `json:"FirstExtensionBlockVersionOffset"`
+}
+
+// NTFSFileReference is the MFT entry and sequence number of a file on NTFS.
+type NTFSFileReference struct {
+	FileReference  uint64 `json:"FileReference"`
+	MFTEntry       uint64 `json:"MFTEntry"`
+	SequenceNumber uint16 `json:"SequenceNumber"`
+}
+
+// ExtensionBlock signatures
+const (
+	ExtensionBlockSignatureMask          uint32 = 0xFFFF0000
+	ExtensionBlockSignaturePrefix        uint32 = 0xBEEF0000
+	ShellFolderIDExtensionBlockSignature uint32 = 0xBEEF0003
+	FileEntryExtensionBlockSignature     uint32 = 0xBEEF0004
+)
+
+// ExtensionBlock layout
+const (
+	ExtensionBlockHeaderSize              int    = 8
+	ShellFolderIDExtensionBlockSize       int    = 26
+	FileEntryExtensionBlockSizeMin        int    = 20
+	FileEntryExtensionBlockNTFSOffset     int    = 20
+	FileEntryExtensionBlockVersionOffset  int    = 36
+	FileEntryExtensionBlockNTFSVersionMin uint16 = 7
+)
+
+// extensionBlockNames maps the extension block signatures with known contents, other signatures are shown in hex.
+var extensionBlockNames = map[uint32]string{
+	ShellFolderIDExtensionBlockSignature: "ShellFolderIdentifier",
+	FileEntryExtensionBlockSignature:     "FileEntry",
+	0xBEEF0025:                           "Timestamps0025",
+	0xBEEF0026:                           "Timestamps0026",
+}
+
+// fileEntryIdentifierNames maps the 0xBEEF0004 identifier to the Windows version that wrote it.
+var fileEntryIdentifierNames = map[uint16]string{
+	0x14: "Windows XP, 2003",
+	0x26: "Windows Vista",
+	0x2A: "Windows 2008, 7, 8.0",
+	0x2E: "Windows 8.1, 10, 11",
+}
+
+// ParseExtensionBlocks decodes the extension blocks that follow the primary name of a
+// file entry shell item. Data that is not an extension block is skipped.
+func ParseExtensionBlocks(data []byte, codePage *CodePage) []ExtensionBlock {
+	var extensionBlocks []ExtensionBlock
+	offset := findExtensionBlock(data)
+	for offset >= 0 && offset+ExtensionBlockHeaderSize <= len(data) {
+		size := int(binary.LittleEndian.Uint16(data[offset:]))
+		signature := binary.LittleEndian.Uint32(data[offset+4:])
+		if size < ExtensionBlockHeaderSize || offset+size > len(data) || signature&ExtensionBlockSignatureMask != ExtensionBlockSignaturePrefix {
+			break
+		}
+		extensionBlocks = append(extensionBlocks, parseExtensionBlock(data[offset:offset+size], codePage))
+		offset += size
+	}
+	return extensionBlocks
+}
+
+// findExtensionBlock returns the offset of the first extension block, the primary name may
+// be followed by alignment padding or a secondary name.
+func findExtensionBlock(data []byte) int {
+	for offset := 0; offset+ExtensionBlockHeaderSize <= len(data); offset++ {
+		size := int(binary.LittleEndian.Uint16(data[offset:]))
+		signature := binary.LittleEndian.Uint32(data[offset+4:])
+		if signature&ExtensionBlockSignatureMask == ExtensionBlockSignaturePrefix && size >= ExtensionBlockHeaderSize && offset+size <= len(data) {
+			return offset
+		}
+	}
+	return -1
+}
+
+func parseExtensionBlock(data []byte, codePage *CodePage) ExtensionBlock {
+	extensionBlock := ExtensionBlock{
+		Size:      binary.LittleEndian.Uint16(data),
+		Version:   binary.LittleEndian.Uint16(data[2:]),
+		Signature: binary.LittleEndian.Uint32(data[4:]),
+	}
+	extensionBlock.SignatureName = extensionBlockNames[extensionBlock.Signature]
+	if extensionBlock.SignatureName == "" {
+		extensionBlock.SignatureName = fmt.Sprintf("0x%08X", extensionBlock.Signature)
+	}
+
+	switch {
+	case extensionBlock.Signature == FileEntryExtensionBlockSignature && len(data) >= FileEntryExtensionBlockSizeMin:
+		extensionBlock.FileEntry = parseFileEntryExtensionBlock(data, extensionBlock.Version, codePage)
+	case extensionBlock.Signature == ShellFolderIDExtensionBlockSignature && len(data) >= ShellFolderIDExtensionBlockSize:
+		var shellFolderID GUID
+		copy(shellFolderID[:], data[ExtensionBlockHeaderSize:])
+		extensionBlock.ShellFolderID = &shellFolderID
+	default:
+		extensionBlock.DataBase64 = base64.StdEncoding.EncodeToString(data[ExtensionBlockHeaderSize:])
+	}
+	return extensionBlock
+}
+
+// parseFileEntryExtensionBlock decodes a 0xBEEF0004 block, its layout depends on the version.
+func parseFileEntryExtensionBlock(data []byte, version uint16, codePage *CodePage) *FileEntryExtensionBlock {
+	fileEntry := FileEntryExtensionBlock{
+		CreationTimeRaw:                  binary.LittleEndian.Uint32(data[8:]),
+		AccessTimeRaw:                    binary.LittleEndian.Uint32(data[12:]),
+		Identifier:                       binary.LittleEndian.Uint16(data[16:]),
+		FirstExtensionBlockVersionOffset: binary.LittleEndian.Uint16(data[len(data)-2:]),
+	}
+	fileEntry.CreationTime = fatToTime(fileEntry.CreationTimeRaw)
+	fileEntry.AccessTime = fatToTime(fileEntry.AccessTimeRaw)
+	fileEntry.IdentifierName = fileEntryIdentifierNames[fileEntry.Identifier]
+
+	// the names end before the trailing FirstExtensionBlockVersionOffset
+	body := data[:len(data)-2]
+	offset := 18
+	if version >= FileEntryExtensionBlockNTFSVersionMin {
+		if len(body) < FileEntryExtensionBlockVersionOffset {
+			return &fileEntry
+		}
+		fileReference := binary.LittleEndian.Uint64(data[FileEntryExtensionBlockNTFSOffset:])
+		fileEntry.NTFSFileReference = &NTFSFileReference{
+			FileReference:  fileReference,
+			MFTEntry:       fileReference & 0x0000FFFFFFFFFFFF,
+			SequenceNumber: uint16(fileReference >> 48),
+		}
+		offset = FileEntryExtensionBlockVersionOffset
+	}
+	if version >= 3 {
+		if len(body) < offset+2 {
+			return &fileEntry
+		}
+		fileEntry.LongStringSize = binary.LittleEndian.Uint16(body[offset:])
+		offset += 2
+	}
+	if version >= 9 {
+		offset += 4
+	}
+	if version >= 8 {
+		offset += 4
+	}
+	if offset > len(body) {
+		return &fileEntry
+	}
+
+	var n int
+	fileEntry.LongName, n = cutZeroTerminatedUnicode(body[offset:])
+	offset += n
+	if version >= 3 && fileEntry.LongStringSize > 0 && offset < len(body) {
+		if version >= 7 {
+			fileEntry.LocalizedName, _ = cutZeroTerminatedUnicode(body[offset:])
+		} else {
+			fileEntry.LocalizedName, _ = cutZeroTerminatedANSI(body[offset:], codePage)
+		}
+	}
+	return &fileEntry
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ParseExtensionBlocks(t *testing.T) {
+	created := time.Date(2019, 12, 7, 9, 9, 2, 0, time.UTC)
+	accessed := time.Date(2023, 3, 14, 10, 20, 30, 0, time.UTC)
+	type args struct {
+		data []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want []ExtensionBlock
+	}{
+		{
+			name: "version 9 with NTFS reference",
+			args: args{data: []byte{
+				0x00, // alignment padding after the primary name
+				0x40, 0x00, 0x09, 0x00, 0x04, 0x00, 0xEF, 0xBE,
+				0x87, 0x4F, 0x21, 0x49, 0x6E, 0x56, 0x8F, 0x52, 0x2E, 0x00,
+				0x00, 0x00, 0x9B, 0x1D, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+				'W', 0x00, 'i', 0x00, 'n', 0x00, 'd', 0x00, 'o', 0x00, 'w', 0x00, 's', 0x00, 0x00, 0x00,
+				0x16, 0x00,
+			}},
+			want: []ExtensionBlock{
+				{
+					Size:          0x40,
+					Version:       9,
+					Signature:     FileEntryExtensionBlockSignature,
+					SignatureName: "FileEntry",
+					FileEntry: &FileEntryExtensionBlock{
+						CreationTimeRaw:                  0x49214F87,
+						CreationTime:                     &created,
+						AccessTimeRaw:                    0x528F566E,
+						AccessTime:                       &accessed,
+						Identifier:                       0x2E,
+						IdentifierName:                   "Windows 8.1, 10, 11",
+						NTFSFileReference:                &NTFSFileReference{FileReference: 0x0001000000001D9B, MFTEntry: 0x1D9B, SequenceNumber: 1},
+						LongName:                         "Windows",
+						FirstExtensionBlockVersionOffset: 0x16,
+					},
+				},
+			},
+		},
+		{
+			name: "version 3 with localized name and unknown block",
+			args: args{data: []byte{
+				0x1E, 0x00, 0x03, 0x00, 0x04, 0x00, 0xEF, 0xBE,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x14, 0x00,
+				0x01, 0x00,
+				'a', 0x00, 0x00, 0x00,
+				'L', 0x00, 0x00, 0x00,
+				0x14, 0x00,
+				0x0A, 0x00, 0x01, 0x00, 0x99, 0x00, 0xEF, 0xBE, 0x01, 0x02,
+			}},
+			want: []ExtensionBlock{
+				{
+					Size:          0x1E,
+					Version:       3,
+					Signature:     FileEntryExtensionBlockSignature,
+					SignatureName: "FileEntry",
+					FileEntry: &FileEntryExtensionBlock{
+						Identifier:                       0x14,
+						IdentifierName:                   "Windows XP, 2003",
+						LongStringSize:                   1,
+						LongName:                         "a",
+						LocalizedName:                    "L",
+						FirstExtensionBlockVersionOffset: 0x14,
+					},
+				},
+				{
+					Size:          0x0A,
+					Version:       1,
+					Signature:     0xBEEF0099,
+					SignatureName: "0xBEEF0099",
+					DataBase64:    "AQI=",
+				},
+			},
+		},
+		{
+			name: "timestamps block without decoder",
+			args: args{data: []byte{0x0A, 0x00, 0x01, 0x00, 0x26, 0x00, 0xEF, 0xBE, 0x01, 0x02}},
+			want: []ExtensionBlock{
+				{
+					Size:          0x0A,
+					Version:       1,
+					Signature:     0xBEEF0026,
+					SignatureName: "Timestamps0026",
+					DataBase64:    "AQI=",
+				},
+			},
+		},
+		{
+			name: "no extension block",
+			args: args{data: []byte{0x00, 0x01, 0x02, 0x03}},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseExtensionBlocks(tt.args.data, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExtensionBlocks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ParseExtraData reads ExtraData blocks until the TerminalBlock or the end of data.
+func ParseExtraData(r *bytes.Reader, codePage *CodePage) ([]ExtraDataBlock, error) {
+	extraData, _, err := parseExtraData(r, codePage, nil)
+	return extraData, err
+}
+
+// parseExtraData reads ExtraData blocks and returns the value of the TerminalBlock, nil if the data ends without one.
+// If warnings is not nil a block that fails to parse is kept as far as it was decoded, its error is appended to
+// warnings and the next block is read.
+func parseExtraData(r *bytes.Reader, codePage *CodePage, warnings *[]ParseWarning) ([]ExtraDataBlock, *uint32, error) {
+	var extraData = []ExtraDataBlock{}
+	for r.Len() > 0 {
+		start := readerOffset(r)
+		section := fmt.Sprintf("ExtraData[%d]", len(extraData))
+		err := checkRemaining(r, section, 4)
+		if err != nil {
+			return extraData, nil, err
+		}
+		var blockSize uint32
+		err = binary.Read(r, binary.LittleEndian, &blockSize)
+		if err != nil {
+			return extraData, nil, err
+		}
+
+		// TerminalBlock
+		if blockSize < ExtraDataBlockSizeMin {
+			return extraData, &blockSize, nil
+		}
+
+		if uint64(blockSize)-4 > uint64(r.Len()) {
+			return extraData, nil, &ParseError{Section: section, Offset: start, Expected: uint64(blockSize), Available: uint64(r.Len()) + 4}
+		}
+		blockData := make([]byte, blockSize)
+		binary.LittleEndian.PutUint32(blockData, blockSize)
+		_, err = io.ReadFull(r, blockData[4:])
+		if err != nil {
+			return extraData, nil, err
+		}
+
+		block, err := parseExtraDataBlock(blockData, codePage)
+		if err != nil {
+			err = rebaseParseError(err, section, start)
+			if warnings == nil {
+				return extraData, nil, err
+			}
+			*warnings = append(*warnings, newParseWarning(err, section, start))
+		}
+		extraData = append(extraData, block)
+	}
+	return extraData, nil, nil
+}
+
+// parseExtraDataBlock decodes a single block, BlockSize and BlockSignature included, following its BlockSignature.
+func parseExtraDataBlock(blockRaw []byte, codePage *CodePage) (ExtraDataBlock, error) {
+	var block ExtraDataBlock
+	var err error
+	blockSize := binary.LittleEndian.Uint32(blockRaw)
+	blockSignature := binary.LittleEndian.Uint32(blockRaw[4:])
+	blockData := blockRaw[ExtraDataBlockSizeMin:]
+	r := bytes.NewReader(blockRaw)
+
+	switch blockSignature {
+	case ConsoleDataBlockSignature:
+		var consoleDataBlock ConsoleDataBlock
+		err = readBlockFields(r, &consoleDataBlock, ConsoleDataBlockSize)
+		block.ConsoleDataBlock = &consoleDataBlock
+	case ConsoleFEDataBlockSignature:
+		var consoleFEDataBlock ConsoleFEDataBlock
+		err = readBlockFields(r, &consoleFEDataBlock, ConsoleFEDataBlockSize)
+		block.ConsoleFEDataBlock = &consoleFEDataBlock
+	case DarwinDataBlockSignature:
+		ansi, ansiBase64, unicode, unicodeBase64, e := readAnsiUnicodeTarget(blockData, blockSignature, codePage)
+		block.DarwinDataBlock = &DarwinDataBlock{
+			BlockSize:               blockSize,
+			BlockSignature:          blockSignature,
+			DarwinDataAnsi:          ansi,
+			DarwinDataAnsiBase64:    ansiBase64,
+			DarwinDataUnicode:       unicode,
+			DarwinDataUnicodeBase64: unicodeBase64,
+		}
+		err = e
+	case EnviromentVariableDataBlockSignature:
+		ansi, ansiBase64, unicode, unicodeBase64, e := readAnsiUnicodeTarget(blockData, blockSignature, codePage)
+		block.EnvironmentVariableDataBlock = &EnvironmentVariableDataBlock{
+			BlockSize:           blockSize,
+			BlockSignature:      blockSignature,
+			TargetAnsi:          ansi,
+			TargetAnsiBase64:    ansiBase64,
+			TargetUnicode:       unicode,
+			TargetUnicodeBase64: unicodeBase64,
+		}
+		err = e
+	case IconEnviromentDataBlockSignature:
+		ansi, ansiBase64, unicode, unicodeBase64, e := readAnsiUnicodeTarget(blockData, blockSignature, codePage)
+		block.IconEnvironmentDataBlock = &IconEnvironmentDataBlock{
+			BlockSize:           blockSize,
+			BlockSignature:      blockSignature,
+			TargetAnsi:          ansi,
+			TargetAnsiBase64:    ansiBase64,
+			TargetUnicode:       unicode,
+			TargetUnicodeBase64: unicodeBase64,
+		}
+		err = e
+	case KnownFolderDataBlockSignature:
+		var fields struct {
+			BlockSize      uint32
+			BlockSignature uint32
+			KnownFolderID  GUID
+			Offset         int32
+		}
+		err = readBlockFields(r, &fields, KnownFolderDataBlockSize)
+		block.KnownFolderDataBlock = &KnownFolderDataBlock{
+			BlockSize:       fields.BlockSize,
+			BlockSignature:  fields.BlockSignature,
+			KnownFolderID:   fields.KnownFolderID,
+			KnownFolderName: KnownFolderName(fields.KnownFolderID),
+			Offset:          fields.Offset,
+		}
+	case PropertyStoreDataBlockSignature:
+		propertyStorages, e := ParsePropertyStore(blockData)
+		e = rebaseParseError(e, "PropertyStore", int64(ExtraDataBlockSizeMin))
+		block.PropertyStoreDataBlock = &PropertyStoreDataBlock{
+			BlockSize:           blockSize,
+			BlockSignature:      blockSignature,
+			PropertyStoreBase64: base64.StdEncoding.EncodeToString(blockData),
+			PropertyStore:       blockData,
+			PropertyStorages:    propertyStorages,
+		}
+		err = e
+	case ShimDataBlockSignature:
+		block.ShimDataBlock = &ShimDataBlock{
+			BlockSize:       blockSize,
+			BlockSignature:  blockSignature,
+			LayerName:       decodeFixedUnicode(blockData),
+			LayerNameBase64: base64.StdEncoding.EncodeToString(blockData),
+		}
+		if blockSize < ShimDataBlockSizeMin {
+			err = &ConstMismatchError{
+				At:       "ExtraData BlockSize",
+				Is:       fmt.Sprintf("0x%X", blockSize),
+				Expected: fmt.Sprintf(">= 0x%X", ShimDataBlockSizeMin),
+			}
+		}
+	case SpecialFolderDataBlockSignature:
+		var fields struct {
+			BlockSize       uint32
+			BlockSignature  uint32
+			SpecialFolderID uint32
+			Offset          int32
+		}
+		err = readBlockFields(r, &fields, SpecialFolderDataBlockSize)
+		block.SpecialFolderDataBlock = &SpecialFolderDataBlock{
+			BlockSize:         fields.BlockSize,
+			BlockSignature:    fields.BlockSignature,
+			SpecialFolderID:   fields.SpecialFolderID,
+			SpecialFolderName: SpecialFolderName(fields.SpecialFolderID),
+			Offset:            fields.Offset,
+		}
+	case TrackerDataBlockSignature:
+		trackerDataBlock := TrackerDataBlock{
+			BlockSize:      blockSize,
+			BlockSignature: blockSignature,
+		}
+		err = readTrackerDataBlock(bytes.NewReader(blockData), &trackerDataBlock, codePage)
+		block.TrackerDataBlock = &trackerDataBlock
+	case VistaAndAboveIDListDataBlockSignature:
+		itemIDs, e := ParseIDList(blockData, codePage)
+		e = rebaseParseError(e, "IDList", int64(ExtraDataBlockSizeMin))
+		block.VistaAndAboveIDListDataBlock = &VistaAndAboveIDListDataBlock{
+			BlockSize:      blockSize,
+			BlockSignature: blockSignature,
+			IDListBase64:   base64.StdEncoding.EncodeToString(blockData),
+			IDList:         blockData,
+			IDListData:     newIDList(blockData, itemIDs),
+		}
+		err = e
+	default:
+		block.UnknownDataBlock = &UnknownDataBlock{
+			BlockSize:       blockSize,
+			BlockSignature:  blockSignature,
+			BlockDataBase64: base64.StdEncoding.EncodeToString(blockData),
+			BlockData:       blockData,
+		}
+	}
+
+	if size, ok := fixedBlockSizes[blockSignature]; ok && blockSize > size {
+		block.ExcessDataBase64 = base64.StdEncoding.EncodeToString(blockRaw[size:])
+	}
+	if err != nil {
+		return block, fmt.Errorf("ExtraData block 0x%08X: %w", blockSignature, err)
+	}
+	return block, nil
+}
+
+// fixedBlockSizes lists the BlockSize of the blocks that have only fixed size fields.
+var fixedBlockSizes = map[uint32]uint32{
+	ConsoleDataBlockSignature:            ConsoleDataBlockSize,
+	ConsoleFEDataBlockSignature:          ConsoleFEDataBlockSize,
+	DarwinDataBlockSignature:             DarwinDataBlockSize,
+	EnviromentVariableDataBlockSignature: EnvironmentVariableDataBlockSize,
+	IconEnviromentDataBlockSignature:     IconEnvironmentDataBlockSize,
+	KnownFolderDataBlockSignature:        KnownFolderDataBlockSize,
+	SpecialFolderDataBlockSignature:      SpecialFolderDataBlockSize,
+	TrackerDataBlockSignature:            TrackerDataBlockSize,
+}
+
+// readBlockFields fills a fixed size block struct, BlockSize and BlockSignature included.
+func readBlockFields(r *bytes.Reader, block interface{}, expectedSize uint32) error {
+	if uint32(r.Len()) < expectedSize {
+		return &ConstMismatchError{
+			At:       "ExtraData BlockSize",
+			Is:       fmt.Sprintf("0x%X", r.Len()),
+			Expected: fmt.Sprintf("0x%X", expectedSize),
+		}
+	}
+	return binary.Read(r, binary.LittleEndian, block)
+}
+
+// readAnsiUnicodeTarget reads the 260 bytes ANSI and 520 bytes UTF-16LE strings shared by
+// the DarwinDataBlock, EnvironmentVariableDataBlock and IconEnvironmentDataBlock, the base64
+// of each keeps the whole field including the bytes after the terminator.
+func readAnsiUnicodeTarget(blockData []byte, blockSignature uint32, codePage *CodePage) (ansi string, ansiBase64 string, unicode string, unicodeBase64 string, err error) {
+	if uint32(len(blockData)) < ExtraDataAnsiStringSize+ExtraDataUnicodeStringSize {
+		return "", "", "", "", &ConstMismatchError{
+			At:       fmt.Sprintf("ExtraData block 0x%08X BlockSize", blockSignature),
+			Is:       fmt.Sprintf("0x%X", uint32(len(blockData))+ExtraDataBlockSizeMin),
+			Expected: fmt.Sprintf("0x%X", ExtraDataAnsiStringSize+ExtraDataUnicodeStringSize+ExtraDataBlockSizeMin),
+		}
+	}
+	ansiData := blockData[:ExtraDataAnsiStringSize]
+	unicodeData := blockData[ExtraDataAnsiStringSize : ExtraDataAnsiStringSize+ExtraDataUnicodeStringSize]
+	return decodeFixedANSI(ansiData, codePage), base64.StdEncoding.EncodeToString(ansiData),
+		decodeFixedUnicode(unicodeData), base64.StdEncoding.EncodeToString(unicodeData), nil
+}
+
+func readTrackerDataBlock(r *bytes.Reader, trackerDataBlock *TrackerDataBlock, codePage *CodePage) error {
+	if uint32(r.Len()) < TrackerDataBlockSize-ExtraDataBlockSizeMin {
+		return &ConstMismatchError{
+			At:       "ExtraData BlockSize",
+			Is:       fmt.Sprintf("0x%X", uint32(r.Len())+ExtraDataBlockSizeMin),
+			Expected: fmt.Sprintf("0x%X", TrackerDataBlockSize),
+		}
+	}
+	err := binary.Read(r, binary.LittleEndian, &trackerDataBlock.Length)
+	if err != nil {
+		return err
+	}
+	err = binary.Read(r, binary.LittleEndian, &trackerDataBlock.Version)
+	if err != nil {
+		return err
+	}
+	machineID := make([]byte, 16)
+	_, err = io.ReadFull(r, machineID)
+	if err != nil {
+		return err
+	}
+	trackerDataBlock.MachineID = decodeFixedANSI(machineID, codePage)
+	trackerDataBlock.MachineIDBase64 = base64.StdEncoding.EncodeToString(machineID)
+	for _, droid := range []*GUID{
+		&trackerDataBlock.DroidVolume,
+		&trackerDataBlock.DroidFile,
+		&trackerDataBlock.BirthDroidVolume,
+		&trackerDataBlock.BirthDroidFile,
+	} {
+		_, err = io.ReadFull(r, droid[:])
+		if err != nil {
+			return err
+		}
+	}
+	trackerDataBlock.DroidFileUUIDv1 = trackerDataBlock.DroidFile.UUIDv1()
+	trackerDataBlock.BirthDroidFileUUIDv1 = trackerDataBlock.BirthDroidFile.UUIDv1()
+	trackerDataBlock.Moved = trackerDataBlock.DroidVolume != trackerDataBlock.BirthDroidVolume ||
+		trackerDataBlock.DroidFile != trackerDataBlock.BirthDroidFile
+	return nil
+}
+
+// decodeFixedANSI decodes a null-terminated ANSI string stored in a fixed size field.
+func decodeFixedANSI(b []byte, codePage *CodePage) string {
+	str, _ := cutZeroTerminatedANSI(b, codePage)
+	return str
+}
+
+// decodeFixedUnicode decodes a null-terminated UTF-16LE string stored in a fixed size field.
+func decodeFixedUnicode(b []byte) string {
+	str, _ := cutZeroTerminatedUnicode(b)
+	return str
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func Test_ParseExtraData(t *testing.T) {
+	type args struct {
+		reader *bytes.Reader
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []ExtraDataBlock
+		wantErr bool
+	}{
+		{
+			name:    "terminal block only",
+			args:    args{reader: bytes.NewReader([]byte{0x00, 0x00, 0x00, 0x00})},
+			want:    []ExtraDataBlock{},
+			wantErr: false,
+		},
+		{
+			name: "known and unknown blocks",
+			args: args{reader: bytes.NewReader([]byte{
+				0x0C, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0xA0, 0xE3, 0x04, 0x00, 0x00,
+				0x0A, 0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0xA0, 0x01, 0x02,
+				0x10, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0xA0, 0x24, 0x00, 0x00, 0x00, 0x2C, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+			})},
+			want: []ExtraDataBlock{
+				{ConsoleFEDataBlock: &ConsoleFEDataBlock{BlockSize: 0x0C, BlockSignature: ConsoleFEDataBlockSignature, CodePage: 1251}},
+				{UnknownDataBlock: &UnknownDataBlock{BlockSize: 0x0A, BlockSignature: 0xA00000FF, BlockDataBase64: "AQI=", BlockData: []byte{0x01, 0x02}}},
+				{SpecialFolderDataBlock: &SpecialFolderDataBlock{BlockSize: 0x10, BlockSignature: SpecialFolderDataBlockSignature, SpecialFolderID: 0x24, SpecialFolderName: "CSIDL_WINDOWS", Offset: 0x2C}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "shim layer",
+			args: args{reader: bytes.NewReader(append([]byte{
+				0x88, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0xA0, 'W', 0x00, 'i', 0x00, 'n', 0x00,
+			}, make([]byte, 0x7A)...))},
+			want: []ExtraDataBlock{
+				{ShimDataBlock: &ShimDataBlock{BlockSize: 0x88, BlockSignature: ShimDataBlockSignature, LayerName: "Win",
+					LayerNameBase64: base64.StdEncoding.EncodeToString(append([]byte{'W', 0x00, 'i', 0x00, 'n', 0x00}, make([]byte, 0x7A)...))}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "shim layer too short",
+			args: args{reader: bytes.NewReader([]byte{
+				0x10, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0xA0, 'W', 0x00, 'i', 0x00, 'n', 0x00, 0x00, 0x00,
+			})},
+			want:    []ExtraDataBlock{},
+			wantErr: true,
+		},
+		{
+			name: "block size beyond data",
+			args: args{reader: bytes.NewReader([]byte{
+				0x60, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0xA0, 0x58, 0x00,
+			})},
+			want:    []ExtraDataBlock{},
+			wantErr: true,
+		},
+		{
+			name: "fixed size block too short",
+			args: args{reader: bytes.NewReader([]byte{
+				0x0A, 0x00, 0x00, 0x00, 0x0B, 0x00, 0x00, 0xA0, 0x00, 0x00,
+			})},
+			want:    []ExtraDataBlock{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExtraData(tt.args.reader, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseExtraData() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExtraData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readTrackerDataBlock(t *testing.T) {
+	volume := GUID{0x6A, 0x4C, 0xB9, 0x94, 0x41, 0x8E, 0x2A, 0x4B, 0x9D, 0x8B, 0x4C, 0x4B, 0x6A, 0x9B, 0x1F, 0x10}
+	file := GUID{0x80, 0x7E, 0x6F, 0xED, 0x51, 0xC2, 0xED, 0x11, 0x9A, 0x2B, 0x08, 0x00, 0x27, 0x6A, 0x8B, 0x9C}
+	birthFile := GUID{0x81, 0x7E, 0x6F, 0xED, 0x51, 0xC2, 0xED, 0x11, 0x9A, 0x2B, 0x08, 0x00, 0x27, 0x6A, 0x8B, 0x9C}
+	trackerData := func(birthFile GUID) []byte {
+		data := []byte{0x58, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'p', 'c', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+		for _, droid := range []GUID{volume, file, volume, birthFile} {
+			data = append(data, droid[:]...)
+		}
+		return data
+	}
+	tests := []struct {
+		name      string
+		data      []byte
+		wantMoved bool
+	}{
+		{
+			name:      "not moved",
+			data:      trackerData(file),
+			wantMoved: false,
+		},
+		{
+			name:      "moved",
+			data:      trackerData(birthFile),
+			wantMoved: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got TrackerDataBlock
+			if err := readTrackerDataBlock(bytes.NewReader(tt.data), &got, nil); err != nil {
+				t.Fatalf("readTrackerDataBlock() error = %v", err)
+			}
+			if got.MachineID != "pc" || got.DroidFile != file {
+				t.Errorf("readTrackerDataBlock() = %v", got)
+			}
+			if got.Moved != tt.wantMoved {
+				t.Errorf("readTrackerDataBlock() Moved = %v, want %v", got.Moved, tt.wantMoved)
+			}
+			if got.DroidFileUUIDv1 == nil || got.DroidFileUUIDv1.MACAddress != "08:00:27:6a:8b:9c" {
+				t.Errorf("readTrackerDataBlock() DroidFileUUIDv1 = %v", got.DroidFileUUIDv1)
+			}
+		})
+	}
+}
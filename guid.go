@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GUID is a GUID as stored on disk: Data1, Data2 and Data3 little-endian, Data4 as is.
+type GUID [16]byte
+
+func (g GUID) String() string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(g[0:4]),
+		binary.LittleEndian.Uint16(g[4:6]),
+		binary.LittleEndian.Uint16(g[6:8]),
+		g[8:10],
+		g[10:16])
+}
+
+func (g GUID) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+func (g *GUID) UnmarshalText(text []byte) error {
+	parsed, err := ParseGUID(string(text))
+	if err != nil {
+		return err
+	}
+	*g = parsed
+	return nil
+}
+
+// ParseGUID parses a GUID in the xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form, braces are optional.
+func ParseGUID(s string) (GUID, error) {
+	var g GUID
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	parts := strings.Split(s, "-")
+	if len(parts) != 5 || len(parts[0]) != 8 || len(parts[1]) != 4 || len(parts[2]) != 4 || len(parts[3]) != 4 || len(parts[4]) != 12 {
+		return g, fmt.Errorf("invalid GUID %q", s)
+	}
+	b, err := hex.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		return g, fmt.Errorf("invalid GUID %q: %w", s, err)
+	}
+	binary.LittleEndian.PutUint32(g[0:4], binary.BigEndian.Uint32(b[0:4]))
+	binary.LittleEndian.PutUint16(g[4:6], binary.BigEndian.Uint16(b[4:6]))
+	binary.LittleEndian.PutUint16(g[6:8], binary.BigEndian.Uint16(b[6:8]))
+	copy(g[8:], b[8:])
+	return g, nil
+}
+
+// mustParseGUID is ParseGUID for the built-in tables.
+func mustParseGUID(s string) GUID {
+	g, err := ParseGUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// UUID versions
+const (
+	UUIDVersionTimeBased uint16 = 1
+	UUIDVersionRandom    uint16 = 4
+)
+
+// uuidEpochOffset is the number of 100ns intervals between 1582-10-15 and 1970-01-01.
+const uuidEpochOffset uint64 = 0x01B21DD213814000
+
+// UUIDv1 represents the decoded fields of a time-based (version 1) UUID.
+type UUIDv1 struct {
+	Timestamp     time.Time `json:"Timestamp"`
+	ClockSequence uint16    `json:"ClockSequence"`
+	MACAddress    string    `json:"MACAddress"`
+}
+
+// Version returns the UUID version, 0 if the GUID is not an RFC 4122 variant.
+func (g GUID) Version() uint16 {
+	if g[8]&0xC0 != 0x80 {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(g[6:8]) >> 12
+}
+
+// UUIDv1 decodes the timestamp, clock sequence and node of a version 1 UUID, nil for any other version.
+func (g GUID) UUIDv1() *UUIDv1 {
+	if g.Version() != UUIDVersionTimeBased {
+		return nil
+	}
+	timestamp := uint64(binary.LittleEndian.Uint16(g[6:8])&0x0FFF)<<48 |
+		uint64(binary.LittleEndian.Uint16(g[4:6]))<<32 |
+		uint64(binary.LittleEndian.Uint32(g[0:4]))
+	unix100ns := int64(timestamp) - int64(uuidEpochOffset)
+	return &UUIDv1{
+		Timestamp:     time.Unix(unix100ns/10000000, (unix100ns%10000000)*100).UTC(),
+		ClockSequence: uint16(g[8]&0x3F)<<8 | uint16(g[9]),
+		MACAddress:    fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", g[10], g[11], g[12], g[13], g[14], g[15]),
+	}
+}
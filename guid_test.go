@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGUID_String(t *testing.T) {
+	tests := []struct {
+		name string
+		g    GUID
+		want string
+	}{
+		{
+			name: "my computer",
+			g:    GUID{0xE0, 0x4F, 0xD0, 0x20, 0xEA, 0x3A, 0x69, 0x10, 0xA2, 0xD8, 0x08, 0x00, 0x2B, 0x30, 0x30, 0x9D},
+			want: "20d04fe0-3aea-1069-a2d8-08002b30309d",
+		},
+		{
+			name: "zero",
+			g:    GUID{},
+			want: "00000000-0000-0000-0000-000000000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.g.String(); got != tt.want {
+				t.Errorf("GUID.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGUID_UUIDv1(t *testing.T) {
+	tests := []struct {
+		name string
+		g    GUID
+		want *UUIDv1
+	}{
+		{
+			name: "time based",
+			// ed6f7e80-c251-11ed-9a2b-0800276a8b9c
+			g: GUID{0x80, 0x7E, 0x6F, 0xED, 0x51, 0xC2, 0xED, 0x11, 0x9A, 0x2B, 0x08, 0x00, 0x27, 0x6A, 0x8B, 0x9C},
+			want: &UUIDv1{
+				Timestamp:     time.Date(2023, 3, 14, 10, 21, 5, 0, time.UTC),
+				ClockSequence: 0x1A2B,
+				MACAddress:    "08:00:27:6a:8b:9c",
+			},
+		},
+		{
+			name: "random",
+			// 94b94c6a-8e41-4b2a-9d8b-4c4b6a9b1f10
+			g:    GUID{0x6A, 0x4C, 0xB9, 0x94, 0x41, 0x8E, 0x2A, 0x4B, 0x9D, 0x8B, 0x4C, 0x4B, 0x6A, 0x9B, 0x1F, 0x10},
+			want: nil,
+		},
+		{
+			name: "not RFC 4122 variant",
+			g:    GUID{0x80, 0x7E, 0x6F, 0xED, 0x51, 0xC2, 0xED, 0x11, 0x1A, 0x2B, 0x08, 0x00, 0x27, 0x6A, 0x8B, 0x9C},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.g.UUIDv1(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GUID.UUIDv1() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
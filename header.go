@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShowCommand values
+const (
+	SW_SHOWNORMAL      uint32 = 0x00000001
+	SW_SHOWMAXIMIZED   uint32 = 0x00000003
+	SW_SHOWMINNOACTIVE uint32 = 0x00000007
+)
+
+// HotKeyFlags modifier keys, stored in the high byte of HotKey
+const (
+	HOTKEYF_SHIFT   uint8 = 0x01
+	HOTKEYF_CONTROL uint8 = 0x02
+	HOTKEYF_ALT     uint8 = 0x04
+)
+
+// ShellLinkHeaderParsed holds the human-readable header fields, the raw values stay in ShellLinkHeader.
+type ShellLinkHeaderParsed struct {
+	LinkCLSID    GUID       `json:"LinkCLSID"`
+	CreationTime *time.Time `json:"CreationTime"` // null if not set
+	AccessTime   *time.Time `json:"AccessTime"`
+	WriteTime    *time.Time `json:"WriteTime"`
+	ShowCommand  string     `json:"ShowCommand"`
+	HotKey       string     `json:"HotKey,omitempty"`
+	IconLocation string     `json:"IconLocation,omitempty"` // IconLocation and IconIndex as "path,index", present if LinkFlag 'HasIconLocation' is set
+}
+
+// hotKeyNames maps the virtual key codes allowed in HotKeyFlags, other than digits, letters and function keys.
+var hotKeyNames = map[uint8]string{
+	0x90: "NumLock",
+	0x91: "ScrollLock",
+}
+
+// ParseHeaderFields decodes the FILETIMEs, ShowCommand and HotKey of the header.
+func ParseHeaderFields(head ShellLinkHeader) ShellLinkHeaderParsed {
+	return ShellLinkHeaderParsed{
+		LinkCLSID:    GUID(head.LinkCLSID),
+		CreationTime: filetimeToTimePtr(head.CreationTime),
+		AccessTime:   filetimeToTimePtr(head.AccessTime),
+		WriteTime:    filetimeToTimePtr(head.WriteTime),
+		ShowCommand:  ShowCommandName(head.ShowCommand),
+		HotKey:       HotKeyName(head.HotKey),
+	}
+}
+
+// ShowCommandName returns the SW_* name of a ShowCommand, other values are treated as SW_SHOWNORMAL.
+func ShowCommandName(showCommand uint32) string {
+	switch showCommand {
+	case SW_SHOWMAXIMIZED:
+		return "SW_SHOWMAXIMIZED"
+	case SW_SHOWMINNOACTIVE:
+		return "SW_SHOWMINNOACTIVE"
+	}
+	return "SW_SHOWNORMAL"
+}
+
+// HotKeyName formats HotKeyFlags like "Ctrl+Alt+F5", empty if no key is assigned.
+func HotKeyName(hotKey uint16) string {
+	key := uint8(hotKey)
+	modifiers := uint8(hotKey >> 8)
+	if key == 0 {
+		return ""
+	}
+
+	var parts []string
+	if modifiers&HOTKEYF_CONTROL != 0 {
+		parts = append(parts, "Ctrl")
+	}
+	if modifiers&HOTKEYF_ALT != 0 {
+		parts = append(parts, "Alt")
+	}
+	if modifiers&HOTKEYF_SHIFT != 0 {
+		parts = append(parts, "Shift")
+	}
+
+	switch {
+	case key >= '0' && key <= '9', key >= 'A' && key <= 'Z':
+		parts = append(parts, string(rune(key)))
+	case key >= 0x70 && key <= 0x87:
+		parts = append(parts, fmt.Sprintf("F%d", key-0x70+1))
+	case hotKeyNames[key] != "":
+		parts = append(parts, hotKeyNames[key])
+	default:
+		parts = append(parts, fmt.Sprintf("0x%02X", key))
+	}
+	return strings.Join(parts, "+")
+}
+
+// ParseHotKey parses a hot key formatted like HotKeyName, e.g. "Ctrl+Alt+F5", into HotKeyFlags.
+func ParseHotKey(name string) (uint16, error) {
+	parts := strings.Split(name, "+")
+	var modifiers uint8
+	for _, part := range parts[:len(parts)-1] {
+		switch strings.ToLower(part) {
+		case "ctrl":
+			modifiers |= HOTKEYF_CONTROL
+		case "alt":
+			modifiers |= HOTKEYF_ALT
+		case "shift":
+			modifiers |= HOTKEYF_SHIFT
+		default:
+			return 0, fmt.Errorf("invalid hot key %q: unknown modifier %q", name, part)
+		}
+	}
+
+	keyName := strings.ToUpper(parts[len(parts)-1])
+	var key uint8
+	if len(keyName) == 1 && (keyName[0] >= '0' && keyName[0] <= '9' || keyName[0] >= 'A' && keyName[0] <= 'Z') {
+		key = keyName[0]
+	} else if n, err := strconv.ParseUint(strings.TrimPrefix(keyName, "F"), 10, 8); err == nil && keyName[0] == 'F' && n >= 1 && n <= 24 {
+		key = uint8(0x70 + n - 1)
+	} else if n, err := strconv.ParseUint(strings.TrimPrefix(keyName, "0X"), 16, 8); err == nil && strings.HasPrefix(keyName, "0X") {
+		key = uint8(n)
+	} else {
+		for code, hotKeyName := range hotKeyNames {
+			if strings.EqualFold(hotKeyName, keyName) {
+				key = code
+			}
+		}
+	}
+	if key == 0 {
+		return 0, fmt.Errorf("invalid hot key %q: unknown key %q", name, parts[len(parts)-1])
+	}
+	return uint16(modifiers)<<8 | uint16(key), nil
+}
+
+// filetimeToTimePtr converts a FILETIME to UTC time, nil for zero.
+func filetimeToTimePtr(ft uint64) *time.Time {
+	if ft == 0 {
+		return nil
+	}
+	t := filetimeToTime(ft)
+	return &t
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_HotKeyName(t *testing.T) {
+	tests := []struct {
+		name   string
+		hotKey uint16
+		want   string
+	}{
+		{name: "none", hotKey: 0x0000, want: ""},
+		{name: "modifiers without key", hotKey: 0x0600, want: ""},
+		{name: "function key", hotKey: 0x0674, want: "Ctrl+Alt+F5"},
+		{name: "letter", hotKey: 0x0341, want: "Ctrl+Shift+A"},
+		{name: "digit", hotKey: 0x0439, want: "Alt+9"},
+		{name: "F24", hotKey: 0x0287, want: "Ctrl+F24"},
+		{name: "scroll lock", hotKey: 0x0091, want: "ScrollLock"},
+		{name: "other key", hotKey: 0x02BA, want: "Ctrl+0xBA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HotKeyName(tt.hotKey); got != tt.want {
+				t.Errorf("HotKeyName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseHotKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		hotKey  string
+		want    uint16
+		wantErr bool
+	}{
+		{name: "function key", hotKey: "Ctrl+Alt+F5", want: 0x0674},
+		{name: "letter lower case", hotKey: "ctrl+shift+a", want: 0x0341},
+		{name: "digit", hotKey: "Alt+9", want: 0x0439},
+		{name: "F24", hotKey: "Ctrl+F24", want: 0x0287},
+		{name: "scroll lock", hotKey: "ScrollLock", want: 0x0091},
+		{name: "other key", hotKey: "Ctrl+0xBA", want: 0x02BA},
+		{name: "F25", hotKey: "Ctrl+F25", wantErr: true},
+		{name: "unknown modifier", hotKey: "Win+A", wantErr: true},
+		{name: "empty", hotKey: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHotKey(tt.hotKey)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseHotKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseHotKey() = 0x%04X, want 0x%04X", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ShowCommandName(t *testing.T) {
+	tests := []struct {
+		name        string
+		showCommand uint32
+		want        string
+	}{
+		{name: "normal", showCommand: SW_SHOWNORMAL, want: "SW_SHOWNORMAL"},
+		{name: "maximized", showCommand: SW_SHOWMAXIMIZED, want: "SW_SHOWMAXIMIZED"},
+		{name: "minimized", showCommand: SW_SHOWMINNOACTIVE, want: "SW_SHOWMINNOACTIVE"},
+		{name: "other values", showCommand: 0x02, want: "SW_SHOWNORMAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShowCommandName(tt.showCommand); got != tt.want {
+				t.Errorf("ShowCommandName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseHeaderFields(t *testing.T) {
+	writeTime := time.Date(2019, 12, 7, 9, 9, 4, 0, time.UTC)
+	head := ShellLinkHeader{
+		LinkCLSID:   LinkCLSIDExpected,
+		WriteTime:   132201833440000000,
+		ShowCommand: SW_SHOWMAXIMIZED,
+		HotKey:      0x0674,
+	}
+	want := ShellLinkHeaderParsed{
+		LinkCLSID:   mustParseGUID("00021401-0000-0000-C000-000000000046"),
+		WriteTime:   &writeTime,
+		ShowCommand: "SW_SHOWMAXIMIZED",
+		HotKey:      "Ctrl+Alt+F5",
+	}
+	if got := ParseHeaderFields(head); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHeaderFields() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// IDListComparison compares the LinkTargetIDList with the VistaAndAboveIDListDataBlock IDList,
+// which Windows prefers when both are present. A mismatch may reveal a rewritten shortcut.
+type IDListComparison struct {
+	LinkTargetIDListPath    string             `json:"LinkTargetIDListPath"`
+	VistaAndAboveIDListPath string             `json:"VistaAndAboveIDListPath"`
+	Match                   bool               `json:"Match"`
+	Differences             []IDListDifference `json:"Differences,omitempty"`
+}
+
+// IDListDifference describes a pair of ItemIDs at the same index whose data differ,
+// an empty side means the IDList has no ItemID at that index.
+type IDListDifference struct {
+	Index               int    `json:"Index"`
+	LinkTargetIDList    string `json:"LinkTargetIDList"`
+	VistaAndAboveIDList string `json:"VistaAndAboveIDList"`
+}
+
+// CompareIDLists compares the ItemIDs of both lists by index, linkTargetIDList is nil if absent.
+func CompareIDLists(linkTargetIDList *LinkTargetIDList, vistaAndAboveIDList IDList) IDListComparison {
+	var primary IDList
+	if linkTargetIDList != nil {
+		primary = linkTargetIDList.IDListData
+	}
+	comparison := IDListComparison{
+		LinkTargetIDListPath:    primary.Path,
+		VistaAndAboveIDListPath: vistaAndAboveIDList.Path,
+	}
+
+	count := len(primary.ItemIDs)
+	if len(vistaAndAboveIDList.ItemIDs) > count {
+		count = len(vistaAndAboveIDList.ItemIDs)
+	}
+	for i := 0; i < count; i++ {
+		var difference IDListDifference
+		var primaryData, alternateData []byte
+		if i < len(primary.ItemIDs) {
+			primaryData = primary.ItemIDs[i].ItemIDData
+			difference.LinkTargetIDList = describeItemID(primary.ItemIDs[i])
+		}
+		if i < len(vistaAndAboveIDList.ItemIDs) {
+			alternateData = vistaAndAboveIDList.ItemIDs[i].ItemIDData
+			difference.VistaAndAboveIDList = describeItemID(vistaAndAboveIDList.ItemIDs[i])
+		}
+		if i < len(primary.ItemIDs) && i < len(vistaAndAboveIDList.ItemIDs) && bytes.Equal(primaryData, alternateData) {
+			continue
+		}
+		difference.Index = i
+		comparison.Differences = append(comparison.Differences, difference)
+	}
+	comparison.Match = len(comparison.Differences) == 0
+	return comparison
+}
+
+// describeItemID summarizes an ItemID by its class type and name.
+func describeItemID(itemID ItemID) string {
+	shellItem := itemID.ShellItem
+	switch {
+	case shellItem.RootFolder != nil:
+		return strings.TrimSpace(fmt.Sprintf("RootFolder %v %v", shellItem.RootFolder.ShellFolderID, shellItem.RootFolder.ShellFolderName))
+	case shellItem.Volume != nil && shellItem.Volume.ShellFolderID != nil:
+		return strings.TrimSpace(fmt.Sprintf("Volume %v %v", *shellItem.Volume.ShellFolderID, shellItem.Volume.ShellFolderName))
+	case shellItem.Volume != nil:
+		return "Volume " + shellItem.Volume.Name
+	case shellItem.FileEntry != nil:
+		return "FileEntry " + shellItem.FileEntry.Name()
+	case shellItem.NetworkLocation != nil:
+		return "NetworkLocation " + shellItem.NetworkLocation.Location
+	}
+	return fmt.Sprintf("%v 0x%02X, %v bytes", shellItem.ClassTypeName, shellItem.ClassType, len(itemID.ItemIDData))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_CompareIDLists(t *testing.T) {
+	volume, _ := ParseIDList([]byte{0x06, 0x00, 0x2F, 'C', ':', 0x00, 0x00, 0x00}, nil)
+	otherVolume, _ := ParseIDList([]byte{0x06, 0x00, 0x2F, 'D', ':', 0x00, 0x00, 0x00}, nil)
+	unknown, _ := ParseIDList([]byte{0x04, 0x00, 0x71, 0x00, 0x00, 0x00}, nil)
+	type args struct {
+		linkTargetIDList    *LinkTargetIDList
+		vistaAndAboveIDList IDList
+	}
+	tests := []struct {
+		name string
+		args args
+		want IDListComparison
+	}{
+		{
+			name: "identical",
+			args: args{
+				linkTargetIDList:    &LinkTargetIDList{IDListData: IDList{ItemIDs: volume, Path: "C:"}},
+				vistaAndAboveIDList: IDList{ItemIDs: volume, Path: "C:"},
+			},
+			want: IDListComparison{LinkTargetIDListPath: "C:", VistaAndAboveIDListPath: "C:", Match: true},
+		},
+		{
+			name: "different item",
+			args: args{
+				linkTargetIDList:    &LinkTargetIDList{IDListData: IDList{ItemIDs: volume, Path: "C:"}},
+				vistaAndAboveIDList: IDList{ItemIDs: otherVolume, Path: "D:"},
+			},
+			want: IDListComparison{
+				LinkTargetIDListPath:    "C:",
+				VistaAndAboveIDListPath: "D:",
+				Differences:             []IDListDifference{{Index: 0, LinkTargetIDList: "Volume C:", VistaAndAboveIDList: "Volume D:"}},
+			},
+		},
+		{
+			name: "no LinkTargetIDList",
+			args: args{
+				linkTargetIDList:    nil,
+				vistaAndAboveIDList: IDList{ItemIDs: unknown},
+			},
+			want: IDListComparison{
+				Differences: []IDListDifference{{Index: 0, VistaAndAboveIDList: "Unknown 0x71, 2 bytes"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareIDLists(tt.args.linkTargetIDList, tt.args.vistaAndAboveIDList); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareIDLists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+)
+
+// KnownFolderPath splits the LinkTargetIDList at the Offset of a KnownFolderDataBlock or
+// SpecialFolderDataBlock into the ItemIDs of the folder and the relative remainder.
+type KnownFolderPath struct {
+	Source         string `json:"Source"` // KnownFolderDataBlock or SpecialFolderDataBlock
+	FolderName     string `json:"FolderName"`
+	FolderItemIDs  int    `json:"FolderItemIDs"` // Count of leading ItemIDs that belong to the folder
+	FolderIDList   string `json:"FolderIDList"`
+	RelativeIDList string `json:"RelativeIDList"`
+	Path           string `json:"Path"`
+}
+
+// knownFolder is a KNOWNFOLDERID or CSIDL canonical name with the short name used in paths.
+type knownFolder struct {
+	Name      string
+	ShortName string
+}
+
+// knownFolderNames maps KNOWNFOLDERID values to their canonical names.
+var knownFolderNames = map[GUID]knownFolder{
+	mustParseGUID("DE61D971-5EBC-4F02-A3A9-6C82895E5C04"): {"FOLDERID_AddNewPrograms", "AddNewPrograms"},
+	mustParseGUID("724EF170-A42D-4FEF-9F26-B60E846FBA4F"): {"FOLDERID_AdminTools", "AdminTools"},
+	mustParseGUID("A520A1A4-1780-4FF6-BD18-167343C5AF16"): {"FOLDERID_AppDataLow", "AppDataLow"},
+	mustParseGUID("A305CE99-F527-492B-8B1A-7E76FA98D6E4"): {"FOLDERID_AppUpdates", "AppUpdates"},
+	mustParseGUID("9E52AB10-F80D-49DF-ACB8-4330F5687855"): {"FOLDERID_CDBurning", "CDBurning"},
+	mustParseGUID("DF7266AC-9274-4867-8D55-3BD661DE872D"): {"FOLDERID_ChangeRemovePrograms", "ChangeRemovePrograms"},
+	mustParseGUID("D0384E7D-BAC3-4797-8F14-CBA229B392B5"): {"FOLDERID_CommonAdminTools", "CommonAdminTools"},
+	mustParseGUID("C1BAE2D0-10DF-4334-BEDD-7AA20B227A9D"): {"FOLDERID_CommonOEMLinks", "CommonOEMLinks"},
+	mustParseGUID("0139D44E-6AFE-49F2-8690-3DAFCAE6FFB8"): {"FOLDERID_CommonPrograms", "CommonPrograms"},
+	mustParseGUID("A4115719-D62E-491D-AA7C-E74B8BE3B067"): {"FOLDERID_CommonStartMenu", "CommonStartMenu"},
+	mustParseGUID("82A5EA35-D9CD-47C5-9629-E15D2F714E6E"): {"FOLDERID_CommonStartup", "CommonStartup"},
+	mustParseGUID("B94237E7-57AC-4347-9151-B08C6C32D1F7"): {"FOLDERID_CommonTemplates", "CommonTemplates"},
+	mustParseGUID("0AC0837C-BBF8-452A-850D-79D08E667CA7"): {"FOLDERID_ComputerFolder", "ComputerFolder"},
+	mustParseGUID("4BFEFB45-347D-4006-A5BE-AC0CB0567192"): {"FOLDERID_ConflictFolder", "ConflictFolder"},
+	mustParseGUID("6F0CD92B-2E97-45D1-88FF-B0D186B8DEDD"): {"FOLDERID_ConnectionsFolder", "ConnectionsFolder"},
+	mustParseGUID("56784854-C6CB-462B-8169-88E350ACB882"): {"FOLDERID_Contacts", "Contacts"},
+	mustParseGUID("82A74AEB-AEB4-465C-A014-D097EE346D63"): {"FOLDERID_ControlPanelFolder", "ControlPanelFolder"},
+	mustParseGUID("2B0F765D-C0E9-4171-908E-08A611B84FF6"): {"FOLDERID_Cookies", "Cookies"},
+	mustParseGUID("B4BFCC3A-DB2C-424C-B029-7FE99A87C641"): {"FOLDERID_Desktop", "Desktop"},
+	mustParseGUID("FDD39AD0-238F-46AF-ADB4-6C85480369C7"): {"FOLDERID_Documents", "Documents"},
+	mustParseGUID("374DE290-123F-4565-9164-39C4925E467B"): {"FOLDERID_Downloads", "Downloads"},
+	mustParseGUID("1777F761-68AD-4D8A-87BD-30B759FA33DD"): {"FOLDERID_Favorites", "Favorites"},
+	mustParseGUID("FD228CB7-AE11-4AE3-864C-16F3910AB8FE"): {"FOLDERID_Fonts", "Fonts"},
+	mustParseGUID("D9DC8A3B-B784-432E-A781-5A1130A75963"): {"FOLDERID_History", "History"},
+	mustParseGUID("4D9F7874-4E0C-4904-967B-40B0D20C3E4B"): {"FOLDERID_InternetFolder", "InternetFolder"},
+	mustParseGUID("352481E8-33BE-4251-BA85-6007CAEDCF9D"): {"FOLDERID_InternetCache", "InternetCache"},
+	mustParseGUID("BFB9D5E0-C6A9-404C-B2B2-AE6DB6AF4968"): {"FOLDERID_Links", "Links"},
+	mustParseGUID("F1B32785-6FBA-4FCF-9D55-7B8E7F157091"): {"FOLDERID_LocalAppData", "LocalAppData"},
+	mustParseGUID("2A00375E-224C-49DE-B8D1-440DF7EF3DDC"): {"FOLDERID_LocalizedResourcesDir", "LocalizedResourcesDir"},
+	mustParseGUID("4BD8D571-6D19-48D3-BE97-422220080E43"): {"FOLDERID_Music", "Music"},
+	mustParseGUID("C5ABBF53-E17F-4121-8900-86626FC2C973"): {"FOLDERID_NetHood", "NetHood"},
+	mustParseGUID("D20BEEC4-5CA8-4905-AE3B-BF251EA09B53"): {"FOLDERID_NetworkFolder", "NetworkFolder"},
+	mustParseGUID("2C36C0AA-5812-4B87-BFD0-4CD0DFB19B39"): {"FOLDERID_OriginalImages", "OriginalImages"},
+	mustParseGUID("69D2CF90-FC33-4FB7-9A0C-EBB0F0FCB43C"): {"FOLDERID_PhotoAlbums", "PhotoAlbums"},
+	mustParseGUID("33E28130-4E1E-4676-835A-98395C3BC3BB"): {"FOLDERID_Pictures", "Pictures"},
+	mustParseGUID("DE92C1C7-837F-4F69-A3BB-86E631204A23"): {"FOLDERID_Playlists", "Playlists"},
+	mustParseGUID("76FC4E2D-D6AD-4519-A663-37BD56068185"): {"FOLDERID_PrintersFolder", "PrintersFolder"},
+	mustParseGUID("9274BD8D-CFD1-41C3-B35E-B13F55A758F4"): {"FOLDERID_PrintHood", "PrintHood"},
+	mustParseGUID("5E6C858F-0E22-4760-9AFE-EA3317B67173"): {"FOLDERID_Profile", "Profile"},
+	mustParseGUID("62AB5D82-FDC1-4DC3-A9DD-070D1D495D97"): {"FOLDERID_ProgramData", "ProgramData"},
+	mustParseGUID("905E63B6-C1BF-494E-B29C-65B732D3D21A"): {"FOLDERID_ProgramFiles", "ProgramFiles"},
+	mustParseGUID("6D809377-6AF0-444B-8957-A3773F02200E"): {"FOLDERID_ProgramFilesX64", "ProgramFilesX64"},
+	mustParseGUID("7C5A40EF-A0FB-4BFC-874A-C0F2E0B9FA8E"): {"FOLDERID_ProgramFilesX86", "ProgramFilesX86"},
+	mustParseGUID("F7F1ED05-9F6D-47A2-AAAE-29D317C6F066"): {"FOLDERID_ProgramFilesCommon", "ProgramFilesCommon"},
+	mustParseGUID("6365D5A7-0F0D-45E5-87F6-0DA56B6A4F7D"): {"FOLDERID_ProgramFilesCommonX64", "ProgramFilesCommonX64"},
+	mustParseGUID("DE974D24-D9C6-4D3E-BF91-F4455120B917"): {"FOLDERID_ProgramFilesCommonX86", "ProgramFilesCommonX86"},
+	mustParseGUID("A77F5D77-2E2B-44C3-A6A2-ABA601054A51"): {"FOLDERID_Programs", "Programs"},
+	mustParseGUID("DFDF76A2-C82A-4D63-906A-5644AC457385"): {"FOLDERID_Public", "Public"},
+	mustParseGUID("C4AA340D-F20F-4863-AFEF-F87EF2E6BA25"): {"FOLDERID_PublicDesktop", "PublicDesktop"},
+	mustParseGUID("ED4824AF-DCE4-45A8-81E2-FC7965083634"): {"FOLDERID_PublicDocuments", "PublicDocuments"},
+	mustParseGUID("3D644C9B-1FB8-4F30-9B45-F670235F79C0"): {"FOLDERID_PublicDownloads", "PublicDownloads"},
+	mustParseGUID("3214FAB5-9757-4298-BB61-92A9DEAA44FF"): {"FOLDERID_PublicMusic", "PublicMusic"},
+	mustParseGUID("B6EBFB86-6907-413C-9AF7-4FC2ABF07CC5"): {"FOLDERID_PublicPictures", "PublicPictures"},
+	mustParseGUID("2400183A-6185-49FB-A2D8-4A392A602BA3"): {"FOLDERID_PublicVideos", "PublicVideos"},
+	mustParseGUID("52A4F021-7B75-48A9-9F6B-4B87A210BC8F"): {"FOLDERID_QuickLaunch", "QuickLaunch"},
+	mustParseGUID("AE50C081-EBD2-438A-8655-8A092E34987A"): {"FOLDERID_Recent", "Recent"},
+	mustParseGUID("B7534046-3ECB-4C18-BE4E-64CD4CB7D6AC"): {"FOLDERID_RecycleBinFolder", "RecycleBinFolder"},
+	mustParseGUID("8AD10C31-2ADB-4296-A8F7-E4701232C972"): {"FOLDERID_ResourceDir", "ResourceDir"},
+	mustParseGUID("3EB685DB-65F9-4CF6-A03A-E3EF65729F3D"): {"FOLDERID_RoamingAppData", "RoamingAppData"},
+	mustParseGUID("B250C668-F57D-4EE1-A63C-290EE7D1AA1F"): {"FOLDERID_SampleMusic", "SampleMusic"},
+	mustParseGUID("C4900540-2379-4C75-844B-64E6FAF8716B"): {"FOLDERID_SamplePictures", "SamplePictures"},
+	mustParseGUID("4C5C32FF-BB9D-43B0-B5B4-2D72E54EAAA4"): {"FOLDERID_SavedGames", "SavedGames"},
+	mustParseGUID("7D1D3A04-DEBB-4115-95CF-2F29DA2920DA"): {"FOLDERID_SavedSearches", "SavedSearches"},
+	mustParseGUID("8983036C-27C0-404B-8F08-102D10DCFD74"): {"FOLDERID_SendTo", "SendTo"},
+	mustParseGUID("625B53C3-AB48-4EC1-BA1F-A1EF4146FC19"): {"FOLDERID_StartMenu", "StartMenu"},
+	mustParseGUID("B97D20BB-F46A-4C97-BA10-5E3608430854"): {"FOLDERID_Startup", "Startup"},
+	mustParseGUID("43668BF8-C14E-49B2-97C9-747784D784B7"): {"FOLDERID_SyncManagerFolder", "SyncManagerFolder"},
+	mustParseGUID("1AC14E77-02E7-4E5D-B744-2EB1AE5198B7"): {"FOLDERID_System", "System"},
+	mustParseGUID("D65231B0-B2F1-4857-A4CE-A8E7C6EA7D27"): {"FOLDERID_SystemX86", "SystemX86"},
+	mustParseGUID("A63293E8-664E-48DB-A079-DF759E0509F7"): {"FOLDERID_Templates", "Templates"},
+	mustParseGUID("9E3995AB-1F9C-4F13-B827-48B24B6C7174"): {"FOLDERID_UserPinned", "UserPinned"},
+	mustParseGUID("0762D272-C50A-4BB0-A382-697DCD729B80"): {"FOLDERID_UserProfiles", "UserProfiles"},
+	mustParseGUID("5CD7AEE2-2219-4A67-B85D-6C9CE15660CB"): {"FOLDERID_UserProgramFiles", "UserProgramFiles"},
+	mustParseGUID("F3CE0F7C-4901-4ACC-8648-D5D44B04EF8F"): {"FOLDERID_UsersFiles", "UsersFiles"},
+	mustParseGUID("A302545D-DEFF-464B-ABE8-61C8648D939B"): {"FOLDERID_UsersLibraries", "UsersLibraries"},
+	mustParseGUID("18989B1D-99B5-455B-841C-AB7C74E4DDFC"): {"FOLDERID_Videos", "Videos"},
+	mustParseGUID("F38BF404-1D43-42F2-9305-67DE0B28FC23"): {"FOLDERID_Windows", "Windows"},
+	mustParseGUID("1B3EA5DC-B587-4786-B4EF-BD1DC332AEAE"): {"FOLDERID_Libraries", "Libraries"},
+	mustParseGUID("7B0DB17D-9CD2-4A93-9733-46CC89022E7C"): {"FOLDERID_DocumentsLibrary", "DocumentsLibrary"},
+	mustParseGUID("2112AB0A-C86A-4FFE-A368-0DE96E47012E"): {"FOLDERID_MusicLibrary", "MusicLibrary"},
+	mustParseGUID("A990AE9F-A03B-4E80-94BC-9912D7504104"): {"FOLDERID_PicturesLibrary", "PicturesLibrary"},
+	mustParseGUID("491E922F-5643-4AF4-A7EB-4E7A138D8174"): {"FOLDERID_VideosLibrary", "VideosLibrary"},
+	mustParseGUID("A52BBA46-E9E1-435F-B3D9-28DAA648C0F6"): {"FOLDERID_OneDrive", "OneDrive"},
+	mustParseGUID("31C0DD25-9439-4F12-BF41-7FF4EDA38722"): {"FOLDERID_Objects3D", "Objects3D"},
+	mustParseGUID("B7BEDE81-DF94-4682-A7D8-57A52620B86F"): {"FOLDERID_Screenshots", "Screenshots"},
+	mustParseGUID("AB5FB87B-7CE2-4F83-915D-550846C9537B"): {"FOLDERID_CameraRoll", "CameraRoll"},
+	mustParseGUID("5B3749AD-B49F-49C1-83EB-15370FBD4882"): {"FOLDERID_AccountPictures", "AccountPictures"},
+	mustParseGUID("BCB5256F-79F6-4CEE-B725-DC34E402FD46"): {"FOLDERID_ImplicitAppShortcuts", "ImplicitAppShortcuts"},
+	mustParseGUID("054FAE61-4DD8-4787-80B6-090220C4B700"): {"FOLDERID_GameTasks", "GameTasks"},
+}
+
+// specialFolderNames maps CSIDL values to their canonical names.
+var specialFolderNames = map[uint32]knownFolder{
+	0x00: {"CSIDL_DESKTOP", "Desktop"},
+	0x01: {"CSIDL_INTERNET", "InternetFolder"},
+	0x02: {"CSIDL_PROGRAMS", "Programs"},
+	0x03: {"CSIDL_CONTROLS", "ControlPanelFolder"},
+	0x04: {"CSIDL_PRINTERS", "PrintersFolder"},
+	0x05: {"CSIDL_PERSONAL", "Documents"},
+	0x06: {"CSIDL_FAVORITES", "Favorites"},
+	0x07: {"CSIDL_STARTUP", "Startup"},
+	0x08: {"CSIDL_RECENT", "Recent"},
+	0x09: {"CSIDL_SENDTO", "SendTo"},
+	0x0A: {"CSIDL_BITBUCKET", "RecycleBinFolder"},
+	0x0B: {"CSIDL_STARTMENU", "StartMenu"},
+	0x0D: {"CSIDL_MYMUSIC", "Music"},
+	0x0E: {"CSIDL_MYVIDEO", "Videos"},
+	0x10: {"CSIDL_DESKTOPDIRECTORY", "Desktop"},
+	0x11: {"CSIDL_DRIVES", "ComputerFolder"},
+	0x12: {"CSIDL_NETWORK", "NetworkFolder"},
+	0x13: {"CSIDL_NETHOOD", "NetHood"},
+	0x14: {"CSIDL_FONTS", "Fonts"},
+	0x15: {"CSIDL_TEMPLATES", "Templates"},
+	0x16: {"CSIDL_COMMON_STARTMENU", "CommonStartMenu"},
+	0x17: {"CSIDL_COMMON_PROGRAMS", "CommonPrograms"},
+	0x18: {"CSIDL_COMMON_STARTUP", "CommonStartup"},
+	0x19: {"CSIDL_COMMON_DESKTOPDIRECTORY", "PublicDesktop"},
+	0x1A: {"CSIDL_APPDATA", "RoamingAppData"},
+	0x1B: {"CSIDL_PRINTHOOD", "PrintHood"},
+	0x1C: {"CSIDL_LOCAL_APPDATA", "LocalAppData"},
+	0x1D: {"CSIDL_ALTSTARTUP", "Startup"},
+	0x1E: {"CSIDL_COMMON_ALTSTARTUP", "CommonStartup"},
+	0x1F: {"CSIDL_COMMON_FAVORITES", "Favorites"},
+	0x20: {"CSIDL_INTERNET_CACHE", "InternetCache"},
+	0x21: {"CSIDL_COOKIES", "Cookies"},
+	0x22: {"CSIDL_HISTORY", "History"},
+	0x23: {"CSIDL_COMMON_APPDATA", "ProgramData"},
+	0x24: {"CSIDL_WINDOWS", "Windows"},
+	0x25: {"CSIDL_SYSTEM", "System"},
+	0x26: {"CSIDL_PROGRAM_FILES", "ProgramFiles"},
+	0x27: {"CSIDL_MYPICTURES", "Pictures"},
+	0x28: {"CSIDL_PROFILE", "Profile"},
+	0x29: {"CSIDL_SYSTEMX86", "SystemX86"},
+	0x2A: {"CSIDL_PROGRAM_FILESX86", "ProgramFilesX86"},
+	0x2B: {"CSIDL_PROGRAM_FILES_COMMON", "ProgramFilesCommon"},
+	0x2C: {"CSIDL_PROGRAM_FILES_COMMONX86", "ProgramFilesCommonX86"},
+	0x2D: {"CSIDL_COMMON_TEMPLATES", "CommonTemplates"},
+	0x2E: {"CSIDL_COMMON_DOCUMENTS", "PublicDocuments"},
+	0x2F: {"CSIDL_COMMON_ADMINTOOLS", "CommonAdminTools"},
+	0x30: {"CSIDL_ADMINTOOLS", "AdminTools"},
+	0x31: {"CSIDL_CONNECTIONS", "ConnectionsFolder"},
+	0x35: {"CSIDL_COMMON_MUSIC", "PublicMusic"},
+	0x36: {"CSIDL_COMMON_PICTURES", "PublicPictures"},
+	0x37: {"CSIDL_COMMON_VIDEO", "PublicVideos"},
+	0x38: {"CSIDL_RESOURCES", "ResourceDir"},
+	0x39: {"CSIDL_RESOURCES_LOCALIZED", "LocalizedResourcesDir"},
+	0x3A: {"CSIDL_COMMON_OEM_LINKS", "CommonOEMLinks"},
+	0x3B: {"CSIDL_CDBURN_AREA", "CDBurning"},
+	0x3D: {"CSIDL_COMPUTERSNEARME", "NetworkFolder"},
+}
+
+// KnownFolderName returns the canonical name of a KNOWNFOLDERID, empty if unknown.
+func KnownFolderName(knownFolderID GUID) string {
+	return knownFolderNames[knownFolderID].Name
+}
+
+// SpecialFolderName returns the canonical name of a CSIDL value, empty if unknown.
+func SpecialFolderName(specialFolderID uint32) string {
+	return specialFolderNames[specialFolderID].Name
+}
+
+// SplitKnownFolderPath splits the LinkTargetIDList at the folder Offset, the KnownFolderDataBlock
+// is preferred over the SpecialFolderDataBlock. Returns nil if neither block is present or the
+// Offset does not point at the start of an ItemID.
+func SplitKnownFolderPath(linkTargetIDList *LinkTargetIDList, extraData []ExtraDataBlock) *KnownFolderPath {
+	if linkTargetIDList == nil {
+		return nil
+	}
+	var source, name, shortName string
+	var offset int32
+	for _, block := range extraData {
+		if block.KnownFolderDataBlock != nil {
+			source = "KnownFolderDataBlock"
+			folder, ok := knownFolderNames[block.KnownFolderDataBlock.KnownFolderID]
+			name, shortName = folder.Name, folder.ShortName
+			if !ok {
+				name = block.KnownFolderDataBlock.KnownFolderID.String()
+				shortName = name
+			}
+			offset = block.KnownFolderDataBlock.Offset
+			break
+		}
+		if block.SpecialFolderDataBlock != nil && source == "" {
+			source = "SpecialFolderDataBlock"
+			folder, ok := specialFolderNames[block.SpecialFolderDataBlock.SpecialFolderID]
+			name, shortName = folder.Name, folder.ShortName
+			if !ok {
+				name = fmt.Sprintf("CSIDL 0x%02X", block.SpecialFolderDataBlock.SpecialFolderID)
+				shortName = name
+			}
+			offset = block.SpecialFolderDataBlock.Offset
+		}
+	}
+	if source == "" {
+		return nil
+	}
+
+	itemIDs := linkTargetIDList.IDListData.ItemIDs
+	index := splitItemIDs(itemIDs, offset)
+	if index < 0 {
+		return nil
+	}
+	relative := IDListPath(itemIDs[index:])
+	path := "{" + shortName + "}"
+	if relative != "" {
+		path += "\\" + relative
+	}
+	return &KnownFolderPath{
+		Source:         source,
+		FolderName:     name,
+		FolderItemIDs:  index,
+		FolderIDList:   IDListPath(itemIDs[:index]),
+		RelativeIDList: relative,
+		Path:           path,
+	}
+}
+
+// splitItemIDs returns the index of the ItemID starting at offset bytes into the IDList,
+// len(itemIDs) if offset is the terminator, -1 if offset is not on an ItemID boundary.
+func splitItemIDs(itemIDs []ItemID, offset int32) int {
+	var position int64
+	for i, itemID := range itemIDs {
+		if position == int64(offset) {
+			return i
+		}
+		position += int64(itemID.ItemIDSize)
+	}
+	if position == int64(offset) {
+		return len(itemIDs)
+	}
+	return -1
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_SplitKnownFolderPath(t *testing.T) {
+	itemIDs := []ItemID{
+		{ItemIDSize: 0x0A, ShellItem: ShellItem{FileEntry: &FileEntryShellItem{PrimaryName: "sub"}}},
+		{ItemIDSize: 0x0E, ShellItem: ShellItem{FileEntry: &FileEntryShellItem{PrimaryName: "file.txt"}}},
+	}
+	linkTargetIDList := &LinkTargetIDList{IDListData: IDList{ItemIDs: itemIDs}}
+	documents := mustParseGUID("FDD39AD0-238F-46AF-ADB4-6C85480369C7")
+	type args struct {
+		linkTargetIDList *LinkTargetIDList
+		extraData        []ExtraDataBlock
+	}
+	tests := []struct {
+		name string
+		args args
+		want *KnownFolderPath
+	}{
+		{
+			name: "known folder at start",
+			args: args{
+				linkTargetIDList: linkTargetIDList,
+				extraData:        []ExtraDataBlock{{KnownFolderDataBlock: &KnownFolderDataBlock{KnownFolderID: documents, Offset: 0}}},
+			},
+			want: &KnownFolderPath{
+				Source:         "KnownFolderDataBlock",
+				FolderName:     "FOLDERID_Documents",
+				RelativeIDList: "sub\\file.txt",
+				Path:           "{Documents}\\sub\\file.txt",
+			},
+		},
+		{
+			name: "known folder preferred over special folder",
+			args: args{
+				linkTargetIDList: linkTargetIDList,
+				extraData: []ExtraDataBlock{
+					{SpecialFolderDataBlock: &SpecialFolderDataBlock{SpecialFolderID: 0x02, Offset: 0}},
+					{KnownFolderDataBlock: &KnownFolderDataBlock{KnownFolderID: documents, Offset: 0x0A}},
+				},
+			},
+			want: &KnownFolderPath{
+				Source:         "KnownFolderDataBlock",
+				FolderName:     "FOLDERID_Documents",
+				FolderItemIDs:  1,
+				FolderIDList:   "sub",
+				RelativeIDList: "file.txt",
+				Path:           "{Documents}\\file.txt",
+			},
+		},
+		{
+			name: "special folder at terminator",
+			args: args{
+				linkTargetIDList: linkTargetIDList,
+				extraData:        []ExtraDataBlock{{SpecialFolderDataBlock: &SpecialFolderDataBlock{SpecialFolderID: 0x02, Offset: 0x18}}},
+			},
+			want: &KnownFolderPath{
+				Source:        "SpecialFolderDataBlock",
+				FolderName:    "CSIDL_PROGRAMS",
+				FolderItemIDs: 2,
+				FolderIDList:  "sub\\file.txt",
+				Path:          "{Programs}",
+			},
+		},
+		{
+			name: "unknown special folder",
+			args: args{
+				linkTargetIDList: linkTargetIDList,
+				extraData:        []ExtraDataBlock{{SpecialFolderDataBlock: &SpecialFolderDataBlock{SpecialFolderID: 0xFF, Offset: 0x0A}}},
+			},
+			want: &KnownFolderPath{
+				Source:         "SpecialFolderDataBlock",
+				FolderName:     "CSIDL 0xFF",
+				FolderItemIDs:  1,
+				FolderIDList:   "sub",
+				RelativeIDList: "file.txt",
+				Path:           "{CSIDL 0xFF}\\file.txt",
+			},
+		},
+		{
+			name: "offset inside an ItemID",
+			args: args{
+				linkTargetIDList: linkTargetIDList,
+				extraData:        []ExtraDataBlock{{KnownFolderDataBlock: &KnownFolderDataBlock{KnownFolderID: documents, Offset: 4}}},
+			},
+			want: nil,
+		},
+		{
+			name: "no folder blocks",
+			args: args{linkTargetIDList: linkTargetIDList},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitKnownFolderPath(tt.args.linkTargetIDList, tt.args.extraData); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitKnownFolderPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testdata/knownfolder.lnk is testdata/notepad.lnk with the Offset of its SpecialFolderDataBlock and
+// KnownFolderDataBlock moved from 0x2C to 0x83, the ItemID of notepad.exe.
+func Test_Parse_knownFolder(t *testing.T) {
+	data, err := ReadLnkFile("testdata/knownfolder.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.KnownFolderPath == nil || got.KnownFolderPath.Path != "{Windows}\\notepad.exe" {
+		t.Errorf("Parse() KnownFolderPath = %v", got.KnownFolderPath)
+	}
+	if got.Target == nil || got.Target.Path != "C:\\Windows\\notepad.exe" {
+		t.Errorf("Parse() Target = %v", got.Target)
+	}
+}
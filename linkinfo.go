@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// driveTypeNames maps DriveType values to their DRIVE_* names.
+var driveTypeNames = map[uint32]string{
+	DriveUnknown:   "DRIVE_UNKNOWN",
+	DriveNoRootDir: "DRIVE_NO_ROOT_DIR",
+	DriveRemovable: "DRIVE_REMOVABLE",
+	DriveFixed:     "DRIVE_FIXED",
+	DriveRemote:    "DRIVE_REMOTE",
+	DriveCDROM:     "DRIVE_CDROM",
+	DriveRAMDisk:   "DRIVE_RAMDISK",
+}
+
+// DriveTypeName returns the DRIVE_* name of a DriveType, hex if unknown.
+func DriveTypeName(driveType uint32) string {
+	if name, ok := driveTypeNames[driveType]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%08X", driveType)
+}
+
+// FormatDriveSerialNumber formats a volume serial number as XXXX-XXXX.
+func FormatDriveSerialNumber(driveSerialNumber uint32) string {
+	return fmt.Sprintf("%04X-%04X", driveSerialNumber>>16, driveSerialNumber&0xFFFF)
+}
+
+// parseVolumeID decodes the VolumeID at offset in linkInfoData, its label offsets are relative to its own start.
+func parseVolumeID(linkInfoData []byte, offset uint32, codePage *CodePage, unicodeErrors *[]string) (VolumeID, error) {
+	var volumeID VolumeID
+	err := checkBounds(linkInfoData, "VolumeID", uint64(offset), 4)
+	if err != nil {
+		return volumeID, err
+	}
+	volumeID.VolumeIDSize = binary.LittleEndian.Uint32(linkInfoData[offset:])
+	if volumeID.VolumeIDSize <= VolumeIDSizeMin {
+		return volumeID, &ConstMismatchError{
+			At:       "VolumeIDSize",
+			Is:       fmt.Sprintf("0x%X", volumeID.VolumeIDSize),
+			Expected: fmt.Sprintf("> 0x%X", VolumeIDSizeMin),
+		}
+	}
+	err = checkBounds(linkInfoData, "VolumeID", uint64(offset), uint64(volumeID.VolumeIDSize))
+	if err != nil {
+		return volumeID, err
+	}
+	data := linkInfoData[offset : offset+volumeID.VolumeIDSize]
+	volumeID.DriveType = binary.LittleEndian.Uint32(data[4:])
+	volumeID.DriveTypeName = DriveTypeName(volumeID.DriveType)
+	volumeID.DriveSerialNumber = binary.LittleEndian.Uint32(data[8:])
+	volumeID.DriveSerialNumberFormatted = FormatDriveSerialNumber(volumeID.DriveSerialNumber)
+	volumeID.VolumeLabelOffset = binary.LittleEndian.Uint32(data[12:])
+
+	if volumeID.VolumeLabelOffset != VolumeLabelOffsetUnicodePresent {
+		volumeID.VolumeLabel, volumeID.VolumeLableBase64, err = readByteStringAt(data, volumeID.VolumeLabelOffset, "VolumeLabel", codePage)
+		return volumeID, rebaseParseError(err, "VolumeID", int64(offset))
+	}
+	err = checkBounds(data, "VolumeLabelOffsetUnicode", uint64(VolumeLabelOffsetUnicodePresent), 4)
+	if err != nil {
+		return volumeID, rebaseParseError(err, "VolumeID", int64(offset))
+	}
+	volumeID.VolumeLabelOffsetUnicode = binary.LittleEndian.Uint32(data[16:])
+	volumeID.VolumeLabelUnicode, volumeID.VolumeLableBase64, err = readUnicodeStringAt(data, volumeID.VolumeLabelOffsetUnicode, "VolumeLabelUnicode", unicodeErrors)
+	return volumeID, rebaseParseError(err, "VolumeID", int64(offset))
+}
+
+// networkProviderNames maps NetworkProviderType values to their WNNC_NET_* names.
+var networkProviderNames = map[uint32]string{
+	WNNC_NET_LANMAN:      "WNNC_NET_LANMAN",
+	WNNC_NET_AVID:        "WNNC_NET_AVID",
+	WNNC_NET_DOCUSPACE:   "WNNC_NET_DOCUSPACE",
+	WNNC_NET_MANGOSOFT:   "WNNC_NET_MANGOSOFT",
+	WNNC_NET_SERNET:      "WNNC_NET_SERNET",
+	WNNC_NET_RIVERFRONT1: "WNNC_NET_RIVERFRONT1",
+	WNNC_NET_RIVERFRONT2: "WNNC_NET_RIVERFRONT2",
+	WNNC_NET_DECORB:      "WNNC_NET_DECORB",
+	WNNC_NET_PROTSTOR:    "WNNC_NET_PROTSTOR",
+	WNNC_NET_FJ_REDIR:    "WNNC_NET_FJ_REDIR",
+	WNNC_NET_DISTINCT:    "WNNC_NET_DISTINCT",
+	WNNC_NET_TWINS:       "WNNC_NET_TWINS",
+	WNNC_NET_RDR2SAMPLE:  "WNNC_NET_RDR2SAMPLE",
+	WNNC_NET_CSC:         "WNNC_NET_CSC",
+	WNNC_NET_3IN1:        "WNNC_NET_3IN1",
+	WNNC_NET_EXTENDNET:   "WNNC_NET_EXTENDNET",
+	WNNC_NET_STAC:        "WNNC_NET_STAC",
+	WNNC_NET_FOXBAT:      "WNNC_NET_FOXBAT",
+	WNNC_NET_YAHOO:       "WNNC_NET_YAHOO",
+	WNNC_NET_EXIFS:       "WNNC_NET_EXIFS",
+	WNNC_NET_DAV:         "WNNC_NET_DAV",
+	WNNC_NET_KNOWARE:     "WNNC_NET_KNOWARE",
+	WNNC_NET_OBJECT_DIRE: "WNNC_NET_OBJECT_DIRE",
+	WNNC_NET_MASFAX:      "WNNC_NET_MASFAX",
+	WNNC_NET_HOB_NFS:     "WNNC_NET_HOB_NFS",
+	WNNC_NET_SHIVA:       "WNNC_NET_SHIVA",
+	WNNC_NET_IBMAL:       "WNNC_NET_IBMAL",
+	WNNC_NET_LOCK:        "WNNC_NET_LOCK",
+	WNNC_NET_TERMSRV:     "WNNC_NET_TERMSRV",
+	WNNC_NET_SRT:         "WNNC_NET_SRT",
+	WNNC_NET_QUINCY:      "WNNC_NET_QUINCY",
+	WNNC_NET_OPENAFS:     "WNNC_NET_OPENAFS",
+	WNNC_NET_AVID1:       "WNNC_NET_AVID1",
+	WNNC_NET_DFS:         "WNNC_NET_DFS",
+	WNNC_NET_KWNP:        "WNNC_NET_KWNP",
+	WNNC_NET_ZENWORKS:    "WNNC_NET_ZENWORKS",
+	WNNC_NET_DRIVEONWEB:  "WNNC_NET_DRIVEONWEB",
+	WNNC_NET_VMWARE:      "WNNC_NET_VMWARE",
+	WNNC_NET_RSFX:        "WNNC_NET_RSFX",
+	WNNC_NET_MFILES:      "WNNC_NET_MFILES",
+	WNNC_NET_MS_NFS:      "WNNC_NET_MS_NFS",
+	WNNC_NET_GOOGLE:      "WNNC_NET_GOOGLE",
+}
+
+// NetworkProviderName returns the WNNC_NET_* name of a NetworkProviderType, hex if unknown.
+func NetworkProviderName(networkProviderType uint32) string {
+	if name, ok := networkProviderNames[networkProviderType]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%08X", networkProviderType)
+}
+
+// Name returns NetNameUnicode if present and NetName otherwise.
+func (c *CommonNetworkRelativeLink) Name() string {
+	if c.NetNameUnicode != "" {
+		return c.NetNameUnicode
+	}
+	return c.NetName
+}
+
+// parseCommonNetworkRelativeLink decodes the CommonNetworkRelativeLink at offset in linkInfoData,
+// its string offsets are relative to its own start.
+func parseCommonNetworkRelativeLink(linkInfoData []byte, offset uint32, codePage *CodePage, unicodeErrors *[]string) (*CommonNetworkRelativeLink, error) {
+	var link CommonNetworkRelativeLink
+	err := checkBounds(linkInfoData, "CommonNetworkRelativeLink", uint64(offset), 4)
+	if err != nil {
+		return nil, err
+	}
+	link.CommonNetworkRelativeLinkSize = binary.LittleEndian.Uint32(linkInfoData[offset:])
+	if link.CommonNetworkRelativeLinkSize < CommonNetworkRelativeLinkUnicodeMinOffsets {
+		return nil, &ConstMismatchError{
+			At:       "CommonNetworkRelativeLinkSize",
+			Is:       fmt.Sprintf("0x%X", link.CommonNetworkRelativeLinkSize),
+			Expected: fmt.Sprintf(">= 0x%X", CommonNetworkRelativeLinkUnicodeMinOffsets),
+		}
+	}
+	err = checkBounds(linkInfoData, "CommonNetworkRelativeLink", uint64(offset), uint64(link.CommonNetworkRelativeLinkSize))
+	if err != nil {
+		return nil, err
+	}
+	data := linkInfoData[offset : offset+link.CommonNetworkRelativeLinkSize]
+	r := bytes.NewReader(data[4:])
+
+	for _, field := range []*uint32{
+		&link.CommonNetworkRelativeLinkFlags,
+		&link.NetNameOffset,
+		&link.DeviceNameOffset,
+		&link.NetworkProviderType,
+	} {
+		err := binary.Read(r, binary.LittleEndian, field)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if link.NetNameOffset > CommonNetworkRelativeLinkUnicodeMinOffsets {
+		if len(data) < int(CommonNetworkRelativeLinkUnicodeMinOffsets)+8 {
+			return nil, &ParseError{Section: "CommonNetworkRelativeLink", Offset: int64(offset), Expected: uint64(CommonNetworkRelativeLinkUnicodeMinOffsets) + 8, Available: uint64(len(data))}
+		}
+		for _, field := range []*uint32{&link.NetNameOffsetUnicode, &link.DeviceNameOffsetUnicode} {
+			err := binary.Read(r, binary.LittleEndian, field)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	link.ValidDevice = link.CommonNetworkRelativeLinkFlags&ValidDevice != 0
+	link.ValidNetType = link.CommonNetworkRelativeLinkFlags&ValidNetType != 0
+	if link.ValidNetType {
+		link.NetworkProviderName = NetworkProviderName(link.NetworkProviderType)
+	}
+
+	if link.NetNameOffset != 0 {
+		link.NetName, link.NetNameBase64, err = readByteStringAt(data, link.NetNameOffset, "NetName", codePage)
+		if err != nil {
+			return nil, rebaseParseError(err, "CommonNetworkRelativeLink", int64(offset))
+		}
+	}
+	if link.ValidDevice && link.DeviceNameOffset != 0 {
+		link.DeviceName, link.DeviceNameBase64, err = readByteStringAt(data, link.DeviceNameOffset, "DeviceName", codePage)
+		if err != nil {
+			return nil, rebaseParseError(err, "CommonNetworkRelativeLink", int64(offset))
+		}
+	}
+	if link.NetNameOffsetUnicode != 0 {
+		link.NetNameUnicode, link.NetNameUnicodeBase64, err = readUnicodeStringAt(data, link.NetNameOffsetUnicode, "NetNameUnicode", unicodeErrors)
+		if err != nil {
+			return nil, rebaseParseError(err, "CommonNetworkRelativeLink", int64(offset))
+		}
+	}
+	if link.ValidDevice && link.DeviceNameOffsetUnicode != 0 {
+		link.DeviceNameUnicode, link.DeviceNameUnicodeBase64, err = readUnicodeStringAt(data, link.DeviceNameOffsetUnicode, "DeviceNameUnicode", unicodeErrors)
+		if err != nil {
+			return nil, rebaseParseError(err, "CommonNetworkRelativeLink", int64(offset))
+		}
+	}
+	return &link, nil
+}
+
+// readByteStringAt reads a null-terminated ANSI string at offset in data, a missing terminator is a ParseError.
+func readByteStringAt(data []byte, offset uint32, field string, codePage *CodePage) (str string, b64 string, err error) {
+	err = checkBounds(data, field, uint64(offset), 1)
+	if err != nil {
+		return "", "", err
+	}
+	str, b64, err = readByteStringZeroTerminated(bytes.NewReader(data[offset:]), codePage)
+	if err != nil {
+		available := uint64(len(data)) - uint64(offset)
+		return str, b64, &ParseError{Section: field, Offset: int64(offset), Expected: available + 1, Available: available}
+	}
+	return str, b64, nil
+}
+
+// readUnicodeStringAt reads a null-terminated UTF-16LE string at offset in data, a missing terminator is a ParseError.
+func readUnicodeStringAt(data []byte, offset uint32, field string, unicodeErrors *[]string) (str string, b64 string, err error) {
+	err = checkBounds(data, field, uint64(offset), 2)
+	if err != nil {
+		return "", "", err
+	}
+	str, b64, err = readUnicodeField(bytes.NewReader(data[offset:]), field, unicodeErrors)
+	if err != nil {
+		available := uint64(len(data)) - uint64(offset)
+		return str, b64, &ParseError{Section: field, Offset: int64(offset), Expected: available + 2, Available: available}
+	}
+	return str, b64, nil
+}
+
+// readUnicodeField reads a null-terminated UTF-16LE string, invalid UTF-16 is appended
+// to unicodeErrors and does not stop parsing.
+func readUnicodeField(r *bytes.Reader, field string, unicodeErrors *[]string) (str string, b64 string, err error) {
+	str, b64, err = readUnicodeStringZeroTerminated(r)
+	var invalidUTF16Error *InvalidUTF16Error
+	if errors.As(err, &invalidUTF16Error) {
+		*unicodeErrors = append(*unicodeErrors, field+": "+err.Error())
+		err = nil
+	}
+	return str, b64, err
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// commonNetworkRelativeLink builds a CommonNetworkRelativeLink, the Unicode strings are left out if unicode is false.
+func commonNetworkRelativeLink(flags uint32, netName string, deviceName string, unicode bool) []byte {
+	headerSize := 0x14
+	if unicode {
+		headerSize = 0x1C
+	}
+	data := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(data[4:], flags)
+	binary.LittleEndian.PutUint32(data[8:], uint32(len(data)))
+	data = append(append(data, netName...), 0x00)
+	binary.LittleEndian.PutUint32(data[12:], uint32(len(data)))
+	data = append(append(data, deviceName...), 0x00)
+	binary.LittleEndian.PutUint32(data[16:], WNNC_NET_LANMAN)
+	if unicode {
+		binary.LittleEndian.PutUint32(data[20:], uint32(len(data)))
+		data = append(data, utf16LE(netName)...)
+		binary.LittleEndian.PutUint32(data[24:], uint32(len(data)))
+		data = append(data, utf16LE(deviceName)...)
+	}
+	binary.LittleEndian.PutUint32(data, uint32(len(data)))
+	return data
+}
+
+// utf16LE encodes an ASCII string as null-terminated UTF-16LE.
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, c := range s {
+		b = append(b, byte(c), 0x00)
+	}
+	return append(b, 0x00, 0x00)
+}
+
+func Test_parseCommonNetworkRelativeLink(t *testing.T) {
+	type args struct {
+		linkInfoData []byte
+		offset       uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *CommonNetworkRelativeLink
+		wantErr bool
+	}{
+		{
+			name: "ANSI only",
+			args: args{linkInfoData: append([]byte{0xFF, 0xFF}, commonNetworkRelativeLink(ValidDevice|ValidNetType, "\\\\server\\share", "Z:", false)...), offset: 2},
+			want: &CommonNetworkRelativeLink{
+				CommonNetworkRelativeLinkSize:  0x26,
+				CommonNetworkRelativeLinkFlags: ValidDevice | ValidNetType,
+				ValidDevice:                    true,
+				ValidNetType:                   true,
+				NetNameOffset:                  0x14,
+				DeviceNameOffset:               0x23,
+				NetworkProviderType:            WNNC_NET_LANMAN,
+				NetworkProviderName:            "WNNC_NET_LANMAN",
+				NetName:                        "\\\\server\\share",
+				NetNameBase64:                  "XFxzZXJ2ZXJcc2hhcmUA",
+				DeviceName:                     "Z:",
+				DeviceNameBase64:               "WjoA",
+			},
+		},
+		{
+			name: "Unicode without valid device and net type",
+			args: args{linkInfoData: commonNetworkRelativeLink(0, "\\\\s\\x", "Z:", true), offset: 0},
+			want: &CommonNetworkRelativeLink{
+				CommonNetworkRelativeLinkSize: 0x37,
+				NetNameOffset:                 0x1C,
+				DeviceNameOffset:              0x22,
+				NetworkProviderType:           WNNC_NET_LANMAN,
+				NetNameOffsetUnicode:          0x25,
+				DeviceNameOffsetUnicode:       0x31,
+				NetName:                       "\\\\s\\x",
+				NetNameBase64:                 "XFxzXHgA",
+				NetNameUnicode:                "\\\\s\\x",
+				NetNameUnicodeBase64:          "XABcAHMAXAB4AAAA",
+			},
+		},
+		{
+			name:    "size too small",
+			args:    args{linkInfoData: []byte{0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, offset: 0},
+			wantErr: true,
+		},
+		{
+			name:    "size beyond LinkInfo",
+			args:    args{linkInfoData: commonNetworkRelativeLink(0, "\\\\s\\x", "", false)[:0x16], offset: 0},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unicodeErrors []string
+			got, err := parseCommonNetworkRelativeLink(tt.args.linkInfoData, tt.args.offset, nil, &unicodeErrors)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseCommonNetworkRelativeLink() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCommonNetworkRelativeLink() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_NetworkProviderName(t *testing.T) {
+	tests := []struct {
+		networkProviderType uint32
+		want                string
+	}{
+		{networkProviderType: WNNC_NET_DAV, want: "WNNC_NET_DAV"},
+		{networkProviderType: WNNC_NET_GOOGLE, want: "WNNC_NET_GOOGLE"},
+		{networkProviderType: 0x00990000, want: "0x00990000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := NetworkProviderName(tt.networkProviderType); got != tt.want {
+				t.Errorf("NetworkProviderName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseLinkInfo_network(t *testing.T) {
+	link := commonNetworkRelativeLink(ValidNetType, "\\\\server\\share", "", false)
+	data := make([]byte, LinkInfoHeaderSizeOptionalFieldsNotSpecified)
+	binary.LittleEndian.PutUint32(data[4:], LinkInfoHeaderSizeOptionalFieldsNotSpecified)
+	binary.LittleEndian.PutUint32(data[8:], CommonNetworkRelativeLinkAndPathSuffixPresent)
+	binary.LittleEndian.PutUint32(data[20:], uint32(len(data)))
+	data = append(data, link...)
+	binary.LittleEndian.PutUint32(data[24:], uint32(len(data)))
+	data = append(data, "docs\\file.txt\x00"...)
+	binary.LittleEndian.PutUint32(data, uint32(len(data)))
+
+	got, err := ParseLinkInfo(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("ParseLinkInfo() error = %v", err)
+	}
+	if got.CommonNetworkRelativeLink == nil || got.CommonNetworkRelativeLink.NetworkProviderName != "WNNC_NET_LANMAN" {
+		t.Errorf("ParseLinkInfo() CommonNetworkRelativeLink = %v", got.CommonNetworkRelativeLink)
+	}
+	if got.NetworkPath != "\\\\server\\share\\docs\\file.txt" {
+		t.Errorf("ParseLinkInfo() NetworkPath = %v", got.NetworkPath)
+	}
+}
+
+func Test_ParseLinkInfo_parseError(t *testing.T) {
+	link := commonNetworkRelativeLink(ValidNetType, "\\\\server\\share", "", false)
+	// drop the NetName and DeviceName terminators
+	link = link[:len(link)-2]
+	binary.LittleEndian.PutUint32(link, uint32(len(link)))
+	data := make([]byte, LinkInfoHeaderSizeOptionalFieldsNotSpecified)
+	binary.LittleEndian.PutUint32(data[4:], LinkInfoHeaderSizeOptionalFieldsNotSpecified)
+	binary.LittleEndian.PutUint32(data[8:], CommonNetworkRelativeLinkAndPathSuffixPresent)
+	binary.LittleEndian.PutUint32(data[20:], uint32(len(data)))
+	data = append(data, link...)
+	binary.LittleEndian.PutUint32(data, uint32(len(data)))
+
+	r := bytes.NewReader(append([]byte{0xFF, 0xFF, 0xFF}, data...))
+	r.Seek(3, io.SeekStart)
+	_, err := ParseLinkInfo(r, nil)
+	var parseError *ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("ParseLinkInfo() error = %v, want a ParseError", err)
+	}
+	want := ParseError{Section: "LinkInfo.CommonNetworkRelativeLink.NetName", Offset: 3 + 0x1C + 0x14, Expected: 0x0F, Available: 0x0E}
+	if *parseError != want {
+		t.Errorf("ParseLinkInfo() error = %+v, want %+v", *parseError, want)
+	}
+}
+
+func Test_parseVolumeID(t *testing.T) {
+	type args struct {
+		linkInfoData []byte
+		offset       uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    VolumeID
+		wantErr bool
+	}{
+		{
+			name: "label offset beyond VolumeID",
+			args: args{linkInfoData: []byte{
+				0xFF, 0xFF,
+				0x11, 0x00, 0x00, 0x00, // VolumeIDSize
+				0x02, 0x00, 0x00, 0x00, // DriveType
+				0xEF, 0xBE, 0xAD, 0xDE, // DriveSerialNumber
+				0x00, 0x01, 0x00, 0x00, // VolumeLabelOffset
+				0x00,
+			}, offset: 2},
+			wantErr: true,
+		},
+		{
+			name: "ANSI label",
+			args: args{linkInfoData: []byte{
+				0xFF, 0xFF,
+				0x16, 0x00, 0x00, 0x00, // VolumeIDSize
+				0x02, 0x00, 0x00, 0x00, // DriveType
+				0xEF, 0xBE, 0xAD, 0xDE, // DriveSerialNumber
+				0x12, 0x00, 0x00, 0x00, // VolumeLabelOffset
+				0x00, 0x00, 'U', 'S', 'B', 0x00,
+			}, offset: 2},
+			want: VolumeID{
+				VolumeIDSize:               0x16,
+				DriveType:                  DriveRemovable,
+				DriveTypeName:              "DRIVE_REMOVABLE",
+				DriveSerialNumber:          0xDEADBEEF,
+				DriveSerialNumberFormatted: "DEAD-BEEF",
+				VolumeLabelOffset:          0x12,
+				VolumeLabel:                "USB",
+				VolumeLableBase64:          "VVNCAA==",
+			},
+		},
+		{
+			name: "Unicode label",
+			args: args{linkInfoData: []byte{
+				0x1C, 0x00, 0x00, 0x00, // VolumeIDSize
+				0x07, 0x00, 0x00, 0x00, // DriveType
+				0x01, 0x00, 0x02, 0x00, // DriveSerialNumber
+				0x14, 0x00, 0x00, 0x00, // VolumeLabelOffset
+				0x18, 0x00, 0x00, 0x00, // VolumeLabelOffsetUnicode
+				0x00, 0x00, 0x00, 0x00,
+				'D', 0x00, 0x00, 0x00,
+			}},
+			want: VolumeID{
+				VolumeIDSize:               0x1C,
+				DriveType:                  7,
+				DriveTypeName:              "0x00000007",
+				DriveSerialNumber:          0x00020001,
+				DriveSerialNumberFormatted: "0002-0001",
+				VolumeLabelOffset:          VolumeLabelOffsetUnicodePresent,
+				VolumeLabelOffsetUnicode:   0x18,
+				VolumeLabelUnicode:         "D",
+				VolumeLableBase64:          "RAAAAA==",
+			},
+		},
+		{
+			name:    "size too small",
+			args:    args{linkInfoData: []byte{0x10, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00}},
+			want:    VolumeID{VolumeIDSize: 0x10},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unicodeErrors []string
+			got, err := parseVolumeID(tt.args.linkInfoData, tt.args.offset, nil, &unicodeErrors)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseVolumeID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVolumeID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,39 +4,53 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"strconv"
-	"unicode/utf8"
+	"time"
+	"unicode/utf16"
 )
 
-// ShellLinkParsed
+// ShellLinkParsed is the result of parsing a whole .lnk file.
 type ShellLinkParsed struct {
-	header               ShellLinkHeader
-	linkFlagsParsed      LinkFlagsParsed
-	fileAttributesParsed FileAttributesParsed
-	linkTargetIDList     LinkTargetIDList
-	linkInfo             LinkInfo
+	Header               ShellLinkHeader       `json:"ShellLinkHeader"`
+	HeaderParsed         ShellLinkHeaderParsed `json:"ShellLinkHeaderParsed"`
+	LinkFlagsParsed      LinkFlagsParsed       `json:"LinkFlags"`
+	FileAttributesParsed FileAttributesParsed  `json:"FileAttributes"`
+	Target               *Target               `json:"Target,omitempty"`           // Resolved from the structures below, nil if none records a path
+	LinkTargetIDList     *LinkTargetIDList     `json:"LinkTargetIDList,omitempty"` // Optional, present if LinkFlag 'HasLinkTargetIDList' is set
+	IDListComparison     *IDListComparison     `json:"IDListComparison,omitempty"` // Optional, present if there is a VistaAndAboveIDListDataBlock
+	KnownFolderPath      *KnownFolderPath      `json:"KnownFolderPath,omitempty"`  // Optional, present if there is a KnownFolderDataBlock or SpecialFolderDataBlock
+	LinkInfo             *LinkInfo             `json:"LinkInfo,omitempty"`         // Optional, present if LinkFlag 'HasLinkInfo' is set
+	StringData           StringData            `json:"StringData"`
+	ExtraData            []ExtraDataBlock      `json:"ExtraData"`
+	TerminalBlockSize    uint32                `json:"TerminalBlockSize,omitempty"`    // TerminalBlock value below 0x8, Encode writes it back
+	TerminalBlockMissing bool                  `json:"TerminalBlockMissing,omitempty"` // The file ends without TerminalBlock, Encode writes none
+	TrailingDataBase64   string                `json:"TrailingDataBase64,omitempty"`   // Data after the TerminalBlock, Encode writes it back
+	CodePage             *CodePage             `json:"CodePage"`                       // Code page the ANSI strings were decoded in
+	CodePageSource       string                `json:"CodePageSource"`                 // "default", "ParseOptions" or "ConsoleFEDataBlock"
+	Warnings             []ParseWarning        `json:"Warnings,omitempty"`             // Problems skipped over, only in lenient mode
 }
 
 // ShellLinkHeader represents the header of a .lnk file.
 type ShellLinkHeader struct {
-	HeaderSize     uint32
-	LinkCLSID      [16]byte
-	LinkFlags      uint32
-	FileAttributes uint32
-	CreationTime   uint64
-	AccessTime     uint64
-	WriteTime      uint64
-	FileSize       uint32
-	IconIndex      int32
-	ShowCommand    uint32
-	HotKey         uint16
-	Reserved1      uint16
-	Reserved2      uint32
-	Reserved3      uint32
+	HeaderSize     uint32   `json:"HeaderSize"`
+	LinkCLSID      [16]byte `json:"LinkCLSID"`
+	LinkFlags      uint32   `json:"LinkFlags"`
+	FileAttributes uint32   `json:"FileAttributes"`
+	CreationTime   uint64   `json:"CreationTime"`
+	AccessTime     uint64   `json:"AccessTime"`
+	WriteTime      uint64   `json:"WriteTime"`
+	FileSize       uint32   `json:"FileSize"`
+	IconIndex      int32    `json:"IconIndex"`
+	ShowCommand    uint32   `json:"ShowCommand"`
+	HotKey         uint16   `json:"HotKey"`
+	Reserved1      uint16   `json:"Reserved1"`
+	Reserved2      uint32   `json:"Reserved2"`
+	Reserved3      uint32   `json:"Reserved3"`
 }
 
 // LinkFlags
@@ -88,38 +102,38 @@ const (
 
 // LinkFlagsParsed
 type LinkFlagsParsed struct {
-	HasLinkTargetIDList         bool //uint32 = 0x00000001
-	HasLinkInfo                 bool //uint32 = 0x00000002
-	HasName                     bool //uint32 = 0x00000004
-	HasRelativePath             bool //uint32 = 0x00000008
-	HasWorkingDir               bool //uint32 = 0x00000010
-	HasArguments                bool //uint32 = 0x00000020
-	HasIconLocation             bool //uint32 = 0x00000040
-	IsUnicode                   bool //uint32 = 0x00000080
-	ForceNoLinkInfo             bool //uint32 = 0x00000100
-	HasExpString                bool //uint32 = 0x00000200
-	RunInSeparateProcess        bool //uint32 = 0x00000400
-	Unused1                     bool //uint32 = 0x00000800
-	HasDarwinID                 bool //uint32 = 0x00001000
-	RunAsUser                   bool //uint32 = 0x00002000
-	HasExpIcon                  bool //uint32 = 0x00004000
-	NoPidlAlias                 bool //uint32 = 0x00008000
-	Unused2                     bool //uint32 = 0x00010000
-	RunWithShimLayer            bool //uint32 = 0x00020000
-	ForceNoLinkTrack            bool //uint32 = 0x00040000
-	EnableTargetMetadata        bool //uint32 = 0x00080000
-	DisableLinkPathTracking     bool //uint32 = 0x00100000
-	DisableKnownFolderTracking  bool //uint32 = 0x00200000
-	DisableKnownFolderAlias     bool //uint32 = 0x00400000
-	AllowLinkToLink             bool //uint32 = 0x00800000
-	UnaliasOnSave               bool //uint32 = 0x01000000
-	PreferEnvironmentPath       bool //uint32 = 0x02000000
-	KeepLocalIDListForUNCTarget bool //uint32 = 0x04000000
-	Reserved                    bool //uint32 = 0x08000000
-	HTMLNoSubDirCreation        bool //uint32 = 0x10000000
-	DisallowUserView            bool //uint32 = 0x20000000
-	ForcePerceivedTypeSystem    bool //uint32 = 0x40000000
-	IncludeSlowInfo             bool //uint32 = 0x80000000
+	HasLinkTargetIDList         bool `json:"HasLinkTargetIDList"`         //uint32 = 0x00000001
+	HasLinkInfo                 bool `json:"HasLinkInfo"`                 //uint32 = 0x00000002
+	HasName                     bool `json:"HasName"`                     //uint32 = 0x00000004
+	HasRelativePath             bool `json:"HasRelativePath"`             //uint32 = 0x00000008
+	HasWorkingDir               bool `json:"HasWorkingDir"`               //uint32 = 0x00000010
+	HasArguments                bool `json:"HasArguments"`                //uint32 = 0x00000020
+	HasIconLocation             bool `json:"HasIconLocation"`             //uint32 = 0x00000040
+	IsUnicode                   bool `json:"IsUnicode"`                   //uint32 = 0x00000080
+	ForceNoLinkInfo             bool `json:"ForceNoLinkInfo"`             //uint32 = 0x00000100
+	HasExpString                bool `json:"HasExpString"`                //uint32 = 0x00000200
+	RunInSeparateProcess        bool `json:"RunInSeparateProcess"`        //uint32 = 0x00000400
+	Unused1                     bool `json:"Unused1"`                     //uint32 = 0x00000800
+	HasDarwinID                 bool `json:"HasDarwinID"`                 //uint32 = 0x00001000
+	RunAsUser                   bool `json:"RunAsUser"`                   //uint32 = 0x00002000
+	HasExpIcon                  bool `json:"HasExpIcon"`                  //uint32 = 0x00004000
+	NoPidlAlias                 bool `json:"NoPidlAlias"`                 //uint32 = 0x00008000
+	Unused2                     bool `json:"Unused2"`                     //uint32 = 0x00010000
+	RunWithShimLayer            bool `json:"RunWithShimLayer"`            //uint32 = 0x00020000
+	ForceNoLinkTrack            bool `json:"ForceNoLinkTrack"`            //uint32 = 0x00040000
+	EnableTargetMetadata        bool `json:"EnableTargetMetadata"`        //uint32 = 0x00080000
+	DisableLinkPathTracking     bool `json:"DisableLinkPathTracking"`     //uint32 = 0x00100000
+	DisableKnownFolderTracking  bool `json:"DisableKnownFolderTracking"`  //uint32 = 0x00200000
+	DisableKnownFolderAlias     bool `json:"DisableKnownFolderAlias"`     //uint32 = 0x00400000
+	AllowLinkToLink             bool `json:"AllowLinkToLink"`             //uint32 = 0x00800000
+	UnaliasOnSave               bool `json:"UnaliasOnSave"`               //uint32 = 0x01000000
+	PreferEnvironmentPath       bool `json:"PreferEnvironmentPath"`       //uint32 = 0x02000000
+	KeepLocalIDListForUNCTarget bool `json:"KeepLocalIDListForUNCTarget"` //uint32 = 0x04000000
+	Reserved                    bool `json:"Reserved"`                    //uint32 = 0x08000000
+	HTMLNoSubDirCreation        bool `json:"HTMLNoSubDirCreation"`        //uint32 = 0x10000000
+	DisallowUserView            bool `json:"DisallowUserView"`            //uint32 = 0x20000000
+	ForcePerceivedTypeSystem    bool `json:"ForcePerceivedTypeSystem"`    //uint32 = 0x40000000
+	IncludeSlowInfo             bool `json:"IncludeSlowInfo"`             //uint32 = 0x80000000
 }
 
 // FileAttributes
@@ -142,60 +156,85 @@ const (
 
 // FileAttributesParsed
 type FileAttributesParsed struct {
-	FileAttributeReadOnly          bool //uint32 = 0x00000001
-	FileAttributeHidden            bool //uint32 = 0x00000002
-	FileAttributeSystem            bool //uint32 = 0x00000004
-	FileAttributeVolumeLabel       bool //uint32 = 0x00000008 // Not used in link files
-	FileAttributeDirectory         bool //uint32 = 0x00000010
-	FileAttributeArchive           bool //uint32 = 0x00000020
-	FileAttributeNormal            bool //uint32 = 0x00000080
-	FileAttributeTemporary         bool //uint32 = 0x00000100
-	FileAttributeSparseFile        bool //uint32 = 0x00000200
-	FileAttributeReparsePoint      bool //uint32 = 0x00000400
-	FileAttributeCompressed        bool //uint32 = 0x00000800
-	FileAttributeOffline           bool //uint32 = 0x00001000
-	FileAttributeNotContentIndexed bool //uint32 = 0x00002000
-	FileAttributeEncrypted         bool //uint32 = 0x00004000
+	FileAttributeReadOnly          bool `json:"FileAttributeReadOnly"`          //uint32 = 0x00000001
+	FileAttributeHidden            bool `json:"FileAttributeHidden"`            //uint32 = 0x00000002
+	FileAttributeSystem            bool `json:"FileAttributeSystem"`            //uint32 = 0x00000004
+	FileAttributeVolumeLabel       bool `json:"FileAttributeVolumeLabel"`       //uint32 = 0x00000008 // Not used in link files
+	FileAttributeDirectory         bool `json:"FileAttributeDirectory"`         //uint32 = 0x00000010
+	FileAttributeArchive           bool `json:"FileAttributeArchive"`           //uint32 = 0x00000020
+	FileAttributeNormal            bool `json:"FileAttributeNormal"`            //uint32 = 0x00000080
+	FileAttributeTemporary         bool `json:"FileAttributeTemporary"`         //uint32 = 0x00000100
+	FileAttributeSparseFile        bool `json:"FileAttributeSparseFile"`        //uint32 = 0x00000200
+	FileAttributeReparsePoint      bool `json:"FileAttributeReparsePoint"`      //uint32 = 0x00000400
+	FileAttributeCompressed        bool `json:"FileAttributeCompressed"`        //uint32 = 0x00000800
+	FileAttributeOffline           bool `json:"FileAttributeOffline"`           //uint32 = 0x00001000
+	FileAttributeNotContentIndexed bool `json:"FileAttributeNotContentIndexed"` //uint32 = 0x00002000
+	FileAttributeEncrypted         bool `json:"FileAttributeEncrypted"`         //uint32 = 0x00004000
 }
 
 // LinkTargetIDList represents the item ID list of a .lnk file.
 type LinkTargetIDList struct {
-	IDListSize uint16
-	IDListData IDList
+	IDListSize uint16 `json:"IDListSize"`
+	IDListData IDList `json:"IDListData"`
 }
 
 // IDList represents the IDList structure, which is a sequence of ItemID structures.
 type IDList struct {
-	ItemIDs []ItemID
+	ItemIDs []ItemID `json:"ItemIDs"`
 	//ends with uint16 \0
+	Path               string `json:"Path,omitempty"`               // Composed from the decoded shell items
+	Unterminated       bool   `json:"Unterminated,omitempty"`       // The IDList ends without TerminalID, Encode writes none
+	TrailingDataBase64 string `json:"TrailingDataBase64,omitempty"` // Data after the ItemIDs and TerminalID, Encode writes it back
 }
 
 // ItemID represents an ItemID structure in the IDList.
 type ItemID struct {
-	ItemIDSize       uint16
-	ItemIDDataBase64 string
-	ItemIDData       []byte
+	ItemIDSize       uint16    `json:"ItemIDSize"`
+	ItemIDDataBase64 string    `json:"ItemIDDataBase64"`
+	ItemIDData       []byte    `json:"-"`
+	ShellItem        ShellItem `json:"ShellItem"`
 }
 
 // LinkInfo represents the link information of a .lnk file.
 type LinkInfo struct {
-	LinkInfoSize                    uint32
-	LinkInfoHeaderSize              uint32
-	LinkInfoFlags                   uint32
-	VolumeIDOffset                  uint32
-	LocalBasePathOffset             uint32
-	CommonNetworkRelativeLinkOffset uint32
-	CommonPathSuffixOffset          uint32
-	LocalBasePathOffsetUnicode      uint32
-	CommonPathSuffixOffsetUnicode   uint32
-	VolumeID                        VolumeID
-	LocalBasePath                   string
-	LocalBasePathBase64             string
-	CommonNetworkRelativeLink       CommonNetworkRelativeLink
-	CommonPathSuffix                string
-	CommonPathSuffixBase64          string
-	LocalBasePathUnicode            string // Optional, present if LinkFlag 'IsUnicode' is set
-	CommonPathSuffixUnicode         string // Optional, present if LinkFlag 'IsUnicode' is set
+	LinkInfoSize                    uint32                     `json:"LinkInfoSize"`
+	LinkInfoHeaderSize              uint32                     `json:"LinkInfoHeaderSize"`
+	LinkInfoFlags                   uint32                     `json:"LinkInfoFlags"`
+	VolumeIDOffset                  uint32                     `json:"VolumeIDOffset"`
+	LocalBasePathOffset             uint32                     `json:"LocalBasePathOffset"`
+	CommonNetworkRelativeLinkOffset uint32                     `json:"CommonNetworkRelativeLinkOffset"`
+	CommonPathSuffixOffset          uint32                     `json:"CommonPathSuffixOffset"`
+	LocalBasePathOffsetUnicode      uint32                     `json:"LocalBasePathOffsetUnicode"`
+	CommonPathSuffixOffsetUnicode   uint32                     `json:"CommonPathSuffixOffsetUnicode"`
+	VolumeID                        VolumeID                   `json:"VolumeID"`
+	LocalBasePath                   string                     `json:"LocalBasePath"`
+	LocalBasePathBase64             string                     `json:"LocalBasePathBase64"`
+	CommonNetworkRelativeLink       *CommonNetworkRelativeLink `json:"CommonNetworkRelativeLink,omitempty"` // Optional, present if LinkInfoFlag 'CommonNetworkRelativeLinkAndPathSuffixPresent' is set
+	CommonPathSuffix                string                     `json:"CommonPathSuffix"`
+	CommonPathSuffixBase64          string                     `json:"CommonPathSuffixBase64"`
+	LocalBasePathUnicode            string                     `json:"LocalBasePathUnicode"` // Optional, present if LinkFlag 'IsUnicode' is set
+	LocalBasePathUnicodeBase64      string                     `json:"LocalBasePathUnicodeBase64"`
+	CommonPathSuffixUnicode         string                     `json:"CommonPathSuffixUnicode"` // Optional, present if LinkFlag 'IsUnicode' is set
+	CommonPathSuffixUnicodeBase64   string                     `json:"CommonPathSuffixUnicodeBase64"`
+	UnicodeErrors                   []string                   `json:"UnicodeErrors,omitempty"` // Invalid UTF-16 sequences in the *Unicode fields, replaced by U+FFFD
+	NetworkPath                     string                     `json:"NetworkPath,omitempty"`   // UNC path of NetName and CommonPathSuffix
+	LinkInfoBase64                  string                     `json:"LinkInfoBase64"`          // The whole structure, Encode keeps it while it parses to the fields above
+}
+
+// BasePath returns LocalBasePathUnicode if present and LocalBasePath otherwise.
+func (l *LinkInfo) BasePath() string {
+	if l.LocalBasePathUnicode != "" {
+		return l.LocalBasePathUnicode
+	}
+	return l.LocalBasePath
+}
+
+// PathSuffix returns CommonPathSuffixUnicode if present and CommonPathSuffix otherwise.
+func (l *LinkInfo) PathSuffix() string {
+	if l.CommonPathSuffixUnicode != "" {
+		return l.CommonPathSuffixUnicode
+	}
+	return l.CommonPathSuffix
 }
 
 // LinkInfoHeaderSize
@@ -211,14 +250,16 @@ const (
 )
 
 type VolumeID struct {
-	VolumeIDSize             uint32
-	DriveType                uint32
-	DriveSerialNumber        uint32
-	VolumeLabelOffset        uint32
-	VolumeLabelOffsetUnicode uint32 // Optional, present if LinkFlag 'IsUnicode' is set
-	VolumeLabel              string
-	VolumeLabelUnicode       string // Optional, present if LinkFlag 'IsUnicode' is set
-	VolumeLableBase64        string
+	VolumeIDSize               uint32 `json:"VolumeIDSize"`
+	DriveType                  uint32 `json:"DriveType"`
+	DriveTypeName              string `json:"DriveTypeName"`
+	DriveSerialNumber          uint32 `json:"DriveSerialNumber"`
+	DriveSerialNumberFormatted string `json:"DriveSerialNumberFormatted"` // XXXX-XXXX as shown by the dir command
+	VolumeLabelOffset          uint32 `json:"VolumeLabelOffset"`
+	VolumeLabelOffsetUnicode   uint32 `json:"VolumeLabelOffsetUnicode"` // Optional, present if LinkFlag 'IsUnicode' is set
+	VolumeLabel                string `json:"VolumeLabel"`
+	VolumeLabelUnicode         string `json:"VolumeLabelUnicode"` // Optional, present if LinkFlag 'IsUnicode' is set
+	VolumeLableBase64          string `json:"VolumeLabelBase64"`
 }
 
 // VolumeLableOffset
@@ -240,19 +281,24 @@ const (
 
 // CommonNetworkRelativeLink represents the CommonNetworkRelativeLink structure in the LinkInfo.
 type CommonNetworkRelativeLink struct {
-	CommonNetworkRelativeLinkSize  uint32
-	CommonNetworkRelativeLinkFlags uint32
-	NetNameOffset                  uint32
-	DeviceNameOffset               uint32
-	NetworkProviderType            uint32
-	NetNameOffsetUnicode           uint32 // Optional, present if LinkFlag 'IsUnicode' is set
-	DeviceNameOffsetUnicode        uint32 // Optional, present if LinkFlag 'IsUnicode' is set
-	NetName                        string
-	NetNameBase64                  string
-	DeviceName                     string
-	DeviceNameBase64               string
-	NetNameUnicode                 string // Optional, present if LinkFlag 'IsUnicode' is set
-	DeviceNameUnicode              string // Optional, present if LinkFlag 'IsUnicode' is set
+	CommonNetworkRelativeLinkSize  uint32 `json:"CommonNetworkRelativeLinkSize"`
+	CommonNetworkRelativeLinkFlags uint32 `json:"CommonNetworkRelativeLinkFlags"`
+	ValidDevice                    bool   `json:"ValidDevice"`
+	ValidNetType                   bool   `json:"ValidNetType"`
+	NetNameOffset                  uint32 `json:"NetNameOffset"`
+	DeviceNameOffset               uint32 `json:"DeviceNameOffset"`
+	NetworkProviderType            uint32 `json:"NetworkProviderType"`
+	NetworkProviderName            string `json:"NetworkProviderName,omitempty"` // WNNC_NET_* name, present if ValidNetType is set
+	NetNameOffsetUnicode           uint32 `json:"NetNameOffsetUnicode"`          // Optional, present if LinkFlag 'IsUnicode' is set
+	DeviceNameOffsetUnicode        uint32 `json:"DeviceNameOffsetUnicode"`       // Optional, present if LinkFlag 'IsUnicode' is set
+	NetName                        string `json:"NetName"`
+	NetNameBase64                  string `json:"NetNameBase64"`
+	DeviceName                     string `json:"DeviceName"`
+	DeviceNameBase64               string `json:"DeviceNameBase64"`
+	NetNameUnicode                 string `json:"NetNameUnicode"` // Optional, present if LinkFlag 'IsUnicode' is set
+	NetNameUnicodeBase64           string `json:"NetNameUnicodeBase64"`
+	DeviceNameUnicode              string `json:"DeviceNameUnicode"` // Optional, present if LinkFlag 'IsUnicode' is set
+	DeviceNameUnicodeBase64        string `json:"DeviceNameUnicodeBase64"`
 }
 
 // CommonNetworkRelativeLinkFlags
@@ -264,6 +310,7 @@ const (
 
 // NetworkProviderType
 const (
+	WNNC_NET_LANMAN      uint32 = 0x00020000 // Not listed in MS-SHLLINK, used for SMB shares
 	WNNC_NET_AVID        uint32 = 0x001A0000
 	WNNC_NET_DOCUSPACE   uint32 = 0x001B0000
 	WNNC_NET_MANGOSOFT   uint32 = 0x001C0000
@@ -309,23 +356,42 @@ const (
 
 // StringData represents the string data section of a .lnk file.
 type StringData struct {
-	NameString            string
-	RelativePath          string
-	WorkingDir            string
-	CommandLineArgs       string
-	IconLocation          string
-	NameStringBase64      string
-	RelativePathBase64    string
-	WorkingDirBase64      string
-	CommandLineArgsBase64 string
-	IconLocationBase64    string
+	NameString            string `json:"NameString"`
+	RelativePath          string `json:"RelativePath"`
+	WorkingDir            string `json:"WorkingDir"`
+	CommandLineArgs       string `json:"CommandLineArgs"`
+	IconLocation          string `json:"IconLocation"`
+	NameStringBase64      string `json:"NameStringBase64"`
+	RelativePathBase64    string `json:"RelativePathBase64"`
+	WorkingDirBase64      string `json:"WorkingDirBase64"`
+	CommandLineArgsBase64 string `json:"CommandLineArgsBase64"`
+	IconLocationBase64    string `json:"IconLocationBase64"`
+}
+
+// ExtraDataBlock represents a single block of the extra data section of a .lnk file,
+// exactly one of the fields is set depending on the BlockSignature.
+type ExtraDataBlock struct {
+	ConsoleDataBlock             *ConsoleDataBlock             `json:"ConsoleDataBlock,omitempty"`
+	ConsoleFEDataBlock           *ConsoleFEDataBlock           `json:"ConsoleFEDataBlock,omitempty"`
+	DarwinDataBlock              *DarwinDataBlock              `json:"DarwinDataBlock,omitempty"`
+	EnvironmentVariableDataBlock *EnvironmentVariableDataBlock `json:"EnvironmentVariableDataBlock,omitempty"`
+	IconEnvironmentDataBlock     *IconEnvironmentDataBlock     `json:"IconEnvironmentDataBlock,omitempty"`
+	KnownFolderDataBlock         *KnownFolderDataBlock         `json:"KnownFolderDataBlock,omitempty"`
+	PropertyStoreDataBlock       *PropertyStoreDataBlock       `json:"PropertyStoreDataBlock,omitempty"`
+	ShimDataBlock                *ShimDataBlock                `json:"ShimDataBlock,omitempty"`
+	SpecialFolderDataBlock       *SpecialFolderDataBlock       `json:"SpecialFolderDataBlock,omitempty"`
+	TrackerDataBlock             *TrackerDataBlock             `json:"TrackerDataBlock,omitempty"`
+	VistaAndAboveIDListDataBlock *VistaAndAboveIDListDataBlock `json:"VistaAndAboveIDListDataBlock,omitempty"`
+	UnknownDataBlock             *UnknownDataBlock             `json:"UnknownDataBlock,omitempty"`
+	ExcessDataBase64             string                        `json:"ExcessDataBase64,omitempty"` // Data of a fixed size block beyond its size, written back after its fields
 }
 
-// ExtraData represents the extra data section of a .lnk file.
-type ExtraData struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	BlockData      []byte
+// UnknownDataBlock keeps an ExtraData block with an unknown BlockSignature as is.
+type UnknownDataBlock struct {
+	BlockSize       uint32 `json:"BlockSize"`
+	BlockSignature  uint32 `json:"BlockSignature"`
+	BlockDataBase64 string `json:"BlockDataBase64"`
+	BlockData       []byte `json:"-"`
 }
 
 // BlockSignature
@@ -345,31 +411,52 @@ const (
 
 // ConsoleDataBlock represents the ConsoleDataBlock structure in the ExtraData section.
 type ConsoleDataBlock struct {
-	BlockSignature         uint32
-	BlockSize              uint32
-	FillAttributes         uint16
-	PopupFillAttributes    uint16
-	ScreenBufferSizeX      uint16
-	ScreenBufferSizeY      uint16
-	WindowSizeX            uint16
-	WindowSizeY            uint16
-	WindowOriginX          uint16
-	WindowOriginY          uint16
-	Unused1                uint32
-	Unused2                uint32
-	FontSize               uint32
-	FontFamily             uint32
-	FontWeight             uint32
-	FaceName               [32]byte
-	CursorSize             uint32
-	FullSreen              uint32
-	QuickEdit              uint32
-	InsertMode             uint32
-	AutoPosition           uint32
-	HistoryBufferSize      uint32
-	NumberOfHistoryBuffers uint32
-	HistoryNoDup           uint32
-	ColorTable             [16]uint32
+	BlockSize              uint32     `json:"BlockSize"`
+	BlockSignature         uint32     `json:"BlockSignature"`
+	FillAttributes         uint16     `json:"FillAttributes"`
+	PopupFillAttributes    uint16     `json:"PopupFillAttributes"`
+	ScreenBufferSizeX      uint16     `json:"ScreenBufferSizeX"`
+	ScreenBufferSizeY      uint16     `json:"ScreenBufferSizeY"`
+	WindowSizeX            uint16     `json:"WindowSizeX"`
+	WindowSizeY            uint16     `json:"WindowSizeY"`
+	WindowOriginX          uint16     `json:"WindowOriginX"`
+	WindowOriginY          uint16     `json:"WindowOriginY"`
+	Unused1                uint32     `json:"Unused1"`
+	Unused2                uint32     `json:"Unused2"`
+	FontSize               uint32     `json:"FontSize"`
+	FontFamily             uint32     `json:"FontFamily"`
+	FontWeight             uint32     `json:"FontWeight"`
+	FaceName               FaceName   `json:"FaceName"`
+	CursorSize             uint32     `json:"CursorSize"`
+	FullSreen              uint32     `json:"FullScreen"`
+	QuickEdit              uint32     `json:"QuickEdit"`
+	InsertMode             uint32     `json:"InsertMode"`
+	AutoPosition           uint32     `json:"AutoPosition"`
+	HistoryBufferSize      uint32     `json:"HistoryBufferSize"`
+	NumberOfHistoryBuffers uint32     `json:"NumberOfHistoryBuffers"`
+	HistoryNoDup           uint32     `json:"HistoryNoDup"`
+	ColorTable             [16]uint32 `json:"ColorTable"`
+}
+
+// FaceName is the fixed size, null-terminated UTF-16LE font face name of the ConsoleDataBlock.
+type FaceName [64]byte
+
+func (f FaceName) String() string {
+	return decodeFixedUnicode(f[:])
+}
+
+func (f FaceName) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *FaceName) UnmarshalText(text []byte) error {
+	encoded := encodeUTF16LE(string(text))
+	if len(encoded) >= len(f) {
+		return fmt.Errorf("FaceName %q does not fit in %v bytes", text, len(f))
+	}
+	*f = FaceName{}
+	copy(f[:], encoded)
+	return nil
 }
 
 // FillAttributes
@@ -433,90 +520,125 @@ const (
 
 // ConsoleFEDataBlock represents the ConsoleFEDataBlock structure in the ExtraData section.
 type ConsoleFEDataBlock struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	CodePage       uint32
+	BlockSize      uint32 `json:"BlockSize"`
+	BlockSignature uint32 `json:"BlockSignature"`
+	CodePage       uint32 `json:"CodePage"`
 }
 
 // DarwinDataBlock represents the DarwinDataBlock structure in the ExtraData section.
 type DarwinDataBlock struct {
-	BlockSignature    uint32
-	BlockSize         uint32
-	DarwinDataAnsi    string
-	DarwinDataUnicode string
+	BlockSize               uint32 `json:"BlockSize"`
+	BlockSignature          uint32 `json:"BlockSignature"`
+	DarwinDataAnsi          string `json:"DarwinDataAnsi"`
+	DarwinDataAnsiBase64    string `json:"DarwinDataAnsiBase64"`
+	DarwinDataUnicode       string `json:"DarwinDataUnicode"`
+	DarwinDataUnicodeBase64 string `json:"DarwinDataUnicodeBase64"`
 }
 
 // EnvironmentVariableDataBlock represents the EnvironmentVariableDataBlock structure in the ExtraData section.
 type EnvironmentVariableDataBlock struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	TargetAnsi     string
-	TargetUnicode  string
+	BlockSize           uint32 `json:"BlockSize"`
+	BlockSignature      uint32 `json:"BlockSignature"`
+	TargetAnsi          string `json:"TargetAnsi"`
+	TargetAnsiBase64    string `json:"TargetAnsiBase64"`
+	TargetUnicode       string `json:"TargetUnicode"`
+	TargetUnicodeBase64 string `json:"TargetUnicodeBase64"`
 }
 
 // IconEnvironmentDataBlock represents the IconEnvironmentDataBlock structure in the ExtraData section.
 type IconEnvironmentDataBlock struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	TargetAnsi     string
-	TargetUnicode  string
+	BlockSize           uint32 `json:"BlockSize"`
+	BlockSignature      uint32 `json:"BlockSignature"`
+	TargetAnsi          string `json:"TargetAnsi"`
+	TargetAnsiBase64    string `json:"TargetAnsiBase64"`
+	TargetUnicode       string `json:"TargetUnicode"`
+	TargetUnicodeBase64 string `json:"TargetUnicodeBase64"`
 }
 
 // KnownFolderDataBlock represents the KnownFolderDataBlock structure in the ExtraData section.
 type KnownFolderDataBlock struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	KnownFolderID  [16]byte
-	Offset         int32
+	BlockSize       uint32 `json:"BlockSize"`
+	BlockSignature  uint32 `json:"BlockSignature"`
+	KnownFolderID   GUID   `json:"KnownFolderID"`
+	KnownFolderName string `json:"KnownFolderName,omitempty"`
+	Offset          int32  `json:"Offset"`
 }
 
 // PropertyStoreDataBlock represents the PropertyStoreDataBlock structure in the ExtraData section.
 type PropertyStoreDataBlock struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	PropertyStore  []byte
+	BlockSize           uint32            `json:"BlockSize"`
+	BlockSignature      uint32            `json:"BlockSignature"`
+	PropertyStoreBase64 string            `json:"PropertyStoreBase64"`
+	PropertyStore       []byte            `json:"-"`
+	PropertyStorages    []PropertyStorage `json:"PropertyStorages"`
 }
 
 // ShimDataBlock represents the ShimDataBlock structure in the ExtraData section.
 type ShimDataBlock struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	LayerName      string
+	BlockSize       uint32 `json:"BlockSize"`
+	BlockSignature  uint32 `json:"BlockSignature"`
+	LayerName       string `json:"LayerName"`
+	LayerNameBase64 string `json:"LayerNameBase64"`
 }
 
 // SpecialFolderDataBlock represents the SpecialFolderDataBlock structure in the ExtraData section.
 type SpecialFolderDataBlock struct {
-	BlockSignature  uint32
-	BlockSize       uint32
-	SpecialFolderID uint32
-	Offset          int32
+	BlockSize         uint32 `json:"BlockSize"`
+	BlockSignature    uint32 `json:"BlockSignature"`
+	SpecialFolderID   uint32 `json:"SpecialFolderID"`
+	SpecialFolderName string `json:"SpecialFolderName,omitempty"`
+	Offset            int32  `json:"Offset"`
 }
 
 // TrackerDataBlock represents the TrackerDataBlock structure in the ExtraData section.
 type TrackerDataBlock struct {
-	BlockSignature   uint32
-	BlockSize        uint32
-	Length           uint32
-	Version          uint32
-	MachineID        string
-	DroidVolume      [16]byte
-	DroidFile        [16]byte
-	BirthDroidVolume [16]byte
-	BirthDroidFile   [16]byte
+	BlockSize            uint32  `json:"BlockSize"`
+	BlockSignature       uint32  `json:"BlockSignature"`
+	Length               uint32  `json:"Length"`
+	Version              uint32  `json:"Version"`
+	MachineID            string  `json:"MachineID"`
+	MachineIDBase64      string  `json:"MachineIDBase64"`
+	DroidVolume          GUID    `json:"DroidVolume"`
+	DroidFile            GUID    `json:"DroidFile"`
+	BirthDroidVolume     GUID    `json:"BirthDroidVolume"`
+	BirthDroidFile       GUID    `json:"BirthDroidFile"`
+	DroidFileUUIDv1      *UUIDv1 `json:"DroidFileUUIDv1,omitempty"`      // Decoded, present if DroidFile is a version 1 UUID
+	BirthDroidFileUUIDv1 *UUIDv1 `json:"BirthDroidFileUUIDv1,omitempty"` // Decoded, present if BirthDroidFile is a version 1 UUID
+	Moved                bool    `json:"Moved"`                          // Birth droids differ from the current ones, the target was moved
 }
 
 // VistaAndAboveIDListDataBlock represents the VistaAndAboveIDListDataBlock structure in the ExtraData section.
 type VistaAndAboveIDListDataBlock struct {
-	BlockSignature uint32
-	BlockSize      uint32
-	IDList         []byte
+	BlockSize      uint32 `json:"BlockSize"`
+	BlockSignature uint32 `json:"BlockSignature"`
+	IDListBase64   string `json:"IDListBase64"`
+	IDList         []byte `json:"-"`
+	IDListData     IDList `json:"IDListData"`
 }
 
-//TODO: ExtraDataBlock sizes as consts
+// ExtraDataBlock sizes
+const (
+	ConsoleDataBlockSize                uint32 = 0x000000CC
+	ConsoleFEDataBlockSize              uint32 = 0x0000000C
+	DarwinDataBlockSize                 uint32 = 0x00000314
+	EnvironmentVariableDataBlockSize    uint32 = 0x00000314
+	IconEnvironmentDataBlockSize        uint32 = 0x00000314
+	KnownFolderDataBlockSize            uint32 = 0x0000001C
+	PropertyStoreDataBlockSizeMin       uint32 = 0x0000000C
+	ShimDataBlockSizeMin                uint32 = 0x00000088
+	SpecialFolderDataBlockSize          uint32 = 0x00000010
+	TrackerDataBlockSize                uint32 = 0x00000060
+	VistaAndAboveIDListDataBlockSizeMin uint32 = 0x0000000A
+	TrackerDataBlockLength              uint32 = 0x00000058
+	ExtraDataAnsiStringSize             uint32 = 260
+	ExtraDataUnicodeStringSize          uint32 = 520
+)
 
 // Expected const list
 const (
-	HeaderSizeExpected uint32 = 0x0000004C
+	HeaderSizeExpected    uint32 = 0x0000004C
+	ExtraDataBlockSizeMin uint32 = 0x00000008
+	TerminalBlockSizeMax  uint32 = 0x00000004 // MS-SHLLINK requires less, the parser accepts up to ExtraDataBlockSizeMin
 )
 
 var LinkCLSIDExpected = [16]byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
@@ -531,6 +653,82 @@ func (e *ConstMismatchError) Error() string {
 	return fmt.Sprintf("parse at %v: const mismatch: is: %v, expected: %v", e.At, e.Is, e.Expected)
 }
 
+// ParseError reports a section whose declared or required size exceeds the data left for it.
+type ParseError struct {
+	Section   string // e.g. "LinkInfo.VolumeID" or "ExtraData[2]"
+	Offset    int64  // absolute file offset of the section
+	Expected  uint64 // bytes the section needs
+	Available uint64 // bytes left for it
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse at %v (offset 0x%X): need 0x%X bytes, 0x%X available", e.Section, e.Offset, e.Expected, e.Available)
+}
+
+// Unwrap lets errors.Is(err, io.ErrUnexpectedEOF) match a ParseError.
+func (e *ParseError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// ParseWarning is a problem a lenient parse skipped over.
+type ParseWarning struct {
+	Section string `json:"Section"`
+	Offset  int64  `json:"Offset"` // absolute file offset
+	Message string `json:"Message"`
+}
+
+// newParseWarning describes err found in section at offset, a ParseError carries its own section and offset.
+func newParseWarning(err error, section string, offset int64) ParseWarning {
+	var parseError *ParseError
+	if errors.As(err, &parseError) {
+		section, offset = parseError.Section, parseError.Offset
+	}
+	return ParseWarning{Section: section, Offset: offset, Message: err.Error()}
+}
+
+// isSectionSizeError reports whether err is a ParseError for section itself rather than for a part of it,
+// the data after section cannot be located then.
+func isSectionSizeError(err error, section string) bool {
+	var parseError *ParseError
+	return errors.As(err, &parseError) && parseError.Section == section
+}
+
+// readerOffset returns the read position of r from the start of its data.
+func readerOffset(r *bytes.Reader) int64 {
+	return r.Size() - int64(r.Len())
+}
+
+// checkRemaining returns a ParseError if r has less than size bytes left.
+func checkRemaining(r *bytes.Reader, section string, size uint64) error {
+	if size > uint64(r.Len()) {
+		return &ParseError{Section: section, Offset: readerOffset(r), Expected: size, Available: uint64(r.Len())}
+	}
+	return nil
+}
+
+// checkBounds returns a ParseError if size bytes at offset do not fit in data.
+func checkBounds(data []byte, section string, offset uint64, size uint64) error {
+	var available uint64
+	if offset < uint64(len(data)) {
+		available = uint64(len(data)) - offset
+	}
+	if size > available {
+		return &ParseError{Section: section, Offset: int64(offset), Expected: size, Available: available}
+	}
+	return nil
+}
+
+// rebaseParseError makes the Offset of a ParseError from a nested parser relative to the enclosing data,
+// base being the offset of the nested data in it, and prefixes section to its Section.
+func rebaseParseError(err error, section string, base int64) error {
+	var parseError *ParseError
+	if errors.As(err, &parseError) {
+		parseError.Offset += base
+		parseError.Section = section + "." + parseError.Section
+	}
+	return err
+}
+
 // ReadLnkFile reads the content of a .lnk file and returns the data as a byte slice.
 func ReadLnkFile(filename string) ([]byte, error) {
 	file, err := os.Open(filename)
@@ -551,7 +749,11 @@ func ReadLnkFile(filename string) ([]byte, error) {
 func ParseShellLinkHeader(r *bytes.Reader) (ShellLinkHeader, error) {
 	var head ShellLinkHeader
 
-	err := binary.Read(r, binary.LittleEndian, &head)
+	err := checkRemaining(r, "ShellLinkHeader", uint64(HeaderSizeExpected))
+	if err != nil {
+		return head, err
+	}
+	err = binary.Read(r, binary.LittleEndian, &head)
 
 	if err != nil {
 		return head, err
@@ -645,15 +847,18 @@ func ParseFileAttributes(flagsRaw uint32) FileAttributesParsed {
 	}
 }
 
-func ParseIDList(idListData []byte) ([]ItemID, error) {
+// ParseIDList parses ItemIDs until the TerminalID or the end of data, ParseError offsets are relative to idListData.
+func ParseIDList(idListData []byte, codePage *CodePage) ([]ItemID, error) {
 	var itemIDList = []ItemID{}
 	reader := bytes.NewReader(idListData)
-	for {
-		var itemIDSize uint16
-		err := binary.Read(reader, binary.LittleEndian, &itemIDSize)
-		if err == io.EOF {
-			break
+	for reader.Len() > 0 {
+		section := fmt.Sprintf("ItemIDs[%d]", len(itemIDList))
+		err := checkRemaining(reader, section, 2)
+		if err != nil {
+			return itemIDList, err
 		}
+		var itemIDSize uint16
+		err = binary.Read(reader, binary.LittleEndian, &itemIDSize)
 		if err != nil {
 			return itemIDList, err
 		}
@@ -662,10 +867,18 @@ func ParseIDList(idListData []byte) ([]ItemID, error) {
 		if itemIDSize == 0 {
 			break
 		}
-		//TODO utf
-		dataSize := itemIDSize - 2
-		data := make([]byte, dataSize)
-		_, err = reader.Read(data)
+		if itemIDSize < 2 {
+			return itemIDList, &ConstMismatchError{
+				At:       section + " ItemIDSize",
+				Is:       fmt.Sprintf("0x%X", itemIDSize),
+				Expected: ">= 0x2",
+			}
+		}
+		if uint64(itemIDSize)-2 > uint64(reader.Len()) {
+			return itemIDList, &ParseError{Section: section, Offset: readerOffset(reader) - 2, Expected: uint64(itemIDSize), Available: uint64(reader.Len()) + 2}
+		}
+		data := make([]byte, itemIDSize-2)
+		_, err = io.ReadFull(reader, data)
 		if err != nil {
 			return itemIDList, err
 		}
@@ -674,120 +887,109 @@ func ParseIDList(idListData []byte) ([]ItemID, error) {
 			ItemIDSize:       itemIDSize,
 			ItemIDDataBase64: base64.StdEncoding.EncodeToString(data),
 			ItemIDData:       data,
+			ShellItem:        ParseShellItem(data, codePage),
 		})
 	}
 	return itemIDList, nil
 
 }
 
-func ParseLinkTargetIDList(r *bytes.Reader) (LinkTargetIDList, error) {
-	var idListSize uint16
+// newIDList returns the IDList of idListData with the ItemIDs parsed from it, the TerminalID and the data
+// after it are recorded so that Encode writes the IDList as it was read.
+func newIDList(idListData []byte, itemIDs []ItemID) IDList {
+	offset := 0
+	for _, itemID := range itemIDs {
+		offset += int(itemID.ItemIDSize)
+	}
+	rest := idListData[offset:]
+	idList := IDList{
+		ItemIDs:      itemIDs,
+		Path:         IDListPath(itemIDs),
+		Unterminated: len(rest) < 2 || binary.LittleEndian.Uint16(rest) != 0,
+	}
+	if !idList.Unterminated {
+		rest = rest[2:]
+	}
+	if len(rest) > 0 {
+		idList.TrailingDataBase64 = base64.StdEncoding.EncodeToString(rest)
+	}
+	return idList
+}
 
-	err := binary.Read(r, binary.LittleEndian, &idListSize)
-	//TODO redo
+// ParseLinkTargetIDList reads IDListSize and the IDList it declares.
+func ParseLinkTargetIDList(r *bytes.Reader, codePage *CodePage) (LinkTargetIDList, error) {
+	start := readerOffset(r)
+	err := checkRemaining(r, "LinkTargetIDList", 2)
 	if err != nil {
-		return LinkTargetIDList{
-			IDListSize: 0,
-			IDListData: IDList{
-				ItemIDs: []ItemID{
-					ItemID{
-						ItemIDSize: 0,
-						ItemIDData: []byte{},
-					},
-				},
-			},
-		}, err
-	}
-
-	if idListSize == 0 {
-		return LinkTargetIDList{
-			IDListSize: 0,
-			IDListData: IDList{
-				ItemIDs: []ItemID{
-					ItemID{
-						ItemIDSize: 0,
-						ItemIDData: []byte{},
-					},
-				},
-			},
-		}, nil
+		return LinkTargetIDList{}, err
 	}
-
-	idListData := make([]byte, idListSize)
-
-	_, err = r.Read(idListData)
+	var idListSize uint16
+	err = binary.Read(r, binary.LittleEndian, &idListSize)
 	if err != nil {
-		return LinkTargetIDList{
-			IDListSize: 0,
-			IDListData: IDList{
-				ItemIDs: []ItemID{
-					ItemID{
-						ItemIDSize: 0,
-						ItemIDData: []byte{},
-					},
-				},
-			},
-		}, err
+		return LinkTargetIDList{}, err
+	}
+	if uint64(idListSize) > uint64(r.Len()) {
+		return LinkTargetIDList{}, &ParseError{Section: "LinkTargetIDList", Offset: start, Expected: uint64(idListSize) + 2, Available: uint64(r.Len()) + 2}
 	}
 
-	itemID, err := ParseIDList(idListData)
-
+	idListData := make([]byte, idListSize)
+	_, err = io.ReadFull(r, idListData)
 	if err != nil {
-		return LinkTargetIDList{
-			IDListSize: 0,
-			IDListData: IDList{
-				ItemIDs: []ItemID{
-					ItemID{
-						ItemIDSize: 0,
-						ItemIDData: []byte{},
-					},
-				},
-			},
-		}, err
+		return LinkTargetIDList{}, err
 	}
 
+	// the ItemIDs before an error are kept
+	itemID, err := ParseIDList(idListData, codePage)
 	return LinkTargetIDList{
 		IDListSize: idListSize,
-		IDListData: IDList{
-			ItemIDs: itemID,
-		},
-	}, nil
+		IDListData: newIDList(idListData, itemID),
+	}, rebaseParseError(err, "LinkTargetIDList", start+2)
 
 }
 
-func readByteStringZeroTerminated(r *bytes.Reader) (str string, b64 string, err error) {
-	counter := 0
+func readByteStringZeroTerminated(r *bytes.Reader, codePage *CodePage) (str string, b64 string, err error) {
 	var byteString []byte
 	for {
 		b, err := r.ReadByte()
 		if err != nil {
 			byteString = append(byteString, 0x0)
-			return string(byteString), base64.StdEncoding.EncodeToString(byteString), err
+			return codePage.Decode(byteString[:len(byteString)-1]), base64.StdEncoding.EncodeToString(byteString), err
 		}
 		byteString = append(byteString, b)
 		if b == 0x0 {
 			break
 		}
-		counter++
-		if counter > 512 {
-			byteString = append(byteString, 0x0)
-			break
-		}
 	}
-	return string(byteString), base64.StdEncoding.EncodeToString(byteString), nil
+	// the terminator is kept in base64 only
+	return codePage.Decode(byteString[:len(byteString)-1]), base64.StdEncoding.EncodeToString(byteString), nil
 }
 
-func ParseLinkInfo(r *bytes.Reader) (LinkInfo, error) {
+func ParseLinkInfo(r *bytes.Reader, codePage *CodePage) (LinkInfo, error) {
 	var linkInfo LinkInfo
+	start := readerOffset(r)
+	err := checkRemaining(r, "LinkInfo", 4)
+	if err != nil {
+		return linkInfo, err
+	}
 	var linkInfoSize uint32
-	err := binary.Read(r, binary.LittleEndian, &linkInfoSize)
+	err = binary.Read(r, binary.LittleEndian, &linkInfoSize)
 	if err != nil {
 		return linkInfo, err
 	}
 	linkInfo.LinkInfoSize = linkInfoSize
+	if uint64(linkInfoSize) > uint64(r.Len())+4 {
+		return linkInfo, &ParseError{Section: "LinkInfo", Offset: start, Expected: uint64(linkInfoSize), Available: uint64(r.Len()) + 4}
+	}
+	if linkInfoSize < LinkInfoHeaderSizeOptionalFieldsNotSpecified {
+		return linkInfo, &ParseError{Section: "LinkInfo", Offset: start, Expected: uint64(LinkInfoHeaderSizeOptionalFieldsNotSpecified), Available: uint64(linkInfoSize)}
+	}
 
 	dataSize := linkInfoSize - 4
 	linkInfoData := make([]byte, dataSize)
+	_, err = io.ReadFull(r, linkInfoData)
+	if err != nil {
+		return linkInfo, err
+	}
 
 	linkInfoReader := bytes.NewReader(linkInfoData)
 
@@ -795,6 +997,9 @@ func ParseLinkInfo(r *bytes.Reader) (LinkInfo, error) {
 	if err != nil {
 		return linkInfo, err
 	}
+	if linkInfo.LinkInfoHeaderSize > linkInfoSize {
+		return linkInfo, &ParseError{Section: "LinkInfo", Offset: start, Expected: uint64(linkInfo.LinkInfoHeaderSize), Available: uint64(linkInfoSize)}
+	}
 	err = binary.Read(linkInfoReader, binary.LittleEndian, &linkInfo.LinkInfoFlags)
 	if err != nil {
 		return linkInfo, err
@@ -816,6 +1021,9 @@ func ParseLinkInfo(r *bytes.Reader) (LinkInfo, error) {
 		return linkInfo, err
 	}
 	if linkInfo.LinkInfoHeaderSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		if linkInfoSize < LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+			return linkInfo, &ParseError{Section: "LinkInfo", Offset: start, Expected: uint64(LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom), Available: uint64(linkInfoSize)}
+		}
 		err = binary.Read(linkInfoReader, binary.LittleEndian, &linkInfo.LocalBasePathOffsetUnicode)
 		if err != nil {
 			return linkInfo, err
@@ -829,328 +1037,393 @@ func ParseLinkInfo(r *bytes.Reader) (LinkInfo, error) {
 		linkInfo.CommonPathSuffixOffsetUnicode = 0
 	}
 	if (linkInfo.LinkInfoFlags & VolumeIDAndLocalBasePathPresent) != 0 {
-		var volumeID VolumeID
 		if linkInfo.VolumeIDOffset != 0 {
-			_, err = linkInfoReader.Seek(int64(linkInfo.VolumeIDOffset-4), io.SeekStart)
-			if err != nil {
-				return linkInfo, err
-			}
-
-			err = binary.Read(linkInfoReader, binary.LittleEndian, &volumeID.VolumeIDSize)
+			linkInfo.VolumeID, err = parseVolumeID(linkInfoData, linkInfo.VolumeIDOffset-4, codePage, &linkInfo.UnicodeErrors)
 			if err != nil {
-				return linkInfo, err
-			}
-			//TODO throw if < VolumeIDSizeMin
-
-			err = binary.Read(linkInfoReader, binary.LittleEndian, &volumeID.DriveSerialNumber)
-			if err != nil {
-				return linkInfo, err
-			}
-			err = binary.Read(linkInfoReader, binary.LittleEndian, &volumeID.VolumeLabelOffset)
-			if err != nil {
-				return linkInfo, err
-			}
-			volumeLabelSize := volumeID.VolumeIDSize - VolumeIDSizeMin
-			volumeLabelData := make([]byte, volumeLabelSize)
-			if volumeID.VolumeLabelOffset == VolumeLabelOffsetUnicodePresent {
-				err = binary.Read(linkInfoReader, binary.LittleEndian, &volumeID.VolumeLabelOffsetUnicode)
-				if err != nil {
-					return linkInfo, err
-				}
-				volumeID.VolumeLabel = ""
-				//TODO set by offset
-				err = binary.Read(linkInfoReader, binary.LittleEndian, volumeLabelData)
-
-				if err != nil {
-					return linkInfo, err
-				}
-				//TODO utf-16BE, utf-16LE, utf-32, utf-32LE, utf-32BE
-				volumeID.VolumeLableBase64 = base64.StdEncoding.EncodeToString(volumeLabelData)
-				if utf8.Valid(volumeLabelData) {
-					volumeID.VolumeLabelUnicode = string(volumeLabelData)
-				} else {
-					// utf16s := utf16.Decode(volumeLabelData)
-					// if err != nil {
-					// 	//TODO other
-					// }
-					volumeID.VolumeLabelUnicode = string(volumeLabelData)
-				}
-
-			} else {
-				volumeID.VolumeLabelOffsetUnicode = 0
-				volumeID.VolumeLabelUnicode = ""
-				//TODO set by offset
-				err = binary.Read(linkInfoReader, binary.LittleEndian, volumeLabelData)
-				if err != nil {
-					return linkInfo, err
-				}
-				volumeID.VolumeLabel = string(volumeLabelData)
-				volumeID.VolumeLableBase64 = base64.StdEncoding.EncodeToString(volumeLabelData)
+				return linkInfo, rebaseParseError(err, "LinkInfo", start+4)
 			}
 		}
-		linkInfo.VolumeID = volumeID
 
 		if linkInfo.LocalBasePathOffset != 0 {
-			_, err = linkInfoReader.Seek(int64(linkInfo.LocalBasePathOffset-4), io.SeekStart)
-			if err != nil {
-				return linkInfo, err
-			}
-			linkInfo.LocalBasePath, linkInfo.LocalBasePathBase64, err = readByteStringZeroTerminated(linkInfoReader)
+			linkInfo.LocalBasePath, linkInfo.LocalBasePathBase64, err = readByteStringAt(linkInfoData, linkInfo.LocalBasePathOffset-4, "LocalBasePath", codePage)
 			if err != nil {
-				return linkInfo, err
+				return linkInfo, rebaseParseError(err, "LinkInfo", start+4)
 			}
 		}
 
-		if linkInfo.LinkInfoHeaderSize > LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		if linkInfo.LinkInfoHeaderSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
 			if linkInfo.LocalBasePathOffsetUnicode != 0 {
-				_, err = linkInfoReader.Seek(int64(linkInfo.LocalBasePathOffsetUnicode-4), io.SeekStart)
+				linkInfo.LocalBasePathUnicode, linkInfo.LocalBasePathUnicodeBase64, err = readUnicodeStringAt(linkInfoData, linkInfo.LocalBasePathOffsetUnicode-4, "LocalBasePathUnicode", &linkInfo.UnicodeErrors)
 				if err != nil {
-					return linkInfo, err
-				}
-				linkInfo.LocalBasePath, linkInfo.LocalBasePathBase64, err = readByteStringZeroTerminated(linkInfoReader)
-				if err != nil {
-					return linkInfo, err
+					return linkInfo, rebaseParseError(err, "LinkInfo", start+4)
 				}
 			}
 		}
-		linkInfo.VolumeID = volumeID
 	}
 
-	if (linkInfo.LinkInfoFlags & CommonNetworkRelativeLinkAndPathSuffixPresent) != 0 {
-		if linkInfo.CommonNetworkRelativeLinkOffset != 0 {
-			var commonNetworkRelativeLink CommonNetworkRelativeLink
-			_, err = linkInfoReader.Seek(int64(linkInfo.CommonNetworkRelativeLinkOffset-4), io.SeekStart)
-			if err != nil {
-				linkInfo.CommonNetworkRelativeLink = commonNetworkRelativeLink
-				return linkInfo, err
-			}
-			err = binary.Read(linkInfoReader, binary.LittleEndian, commonNetworkRelativeLink.CommonNetworkRelativeLinkSize)
-			if err != nil {
-				return linkInfo, err
-			}
-			commonNetworkRelativeLinkData := make([]byte, commonNetworkRelativeLink.CommonNetworkRelativeLinkSize-4)
-			err = binary.Read(linkInfoReader, binary.LittleEndian, commonNetworkRelativeLinkData)
-			if err != nil {
-				return linkInfo, err
-			}
-			commonNetworkRelativeLinkReader := bytes.NewReader(commonNetworkRelativeLinkData)
+	if (linkInfo.LinkInfoFlags&CommonNetworkRelativeLinkAndPathSuffixPresent) != 0 && linkInfo.CommonNetworkRelativeLinkOffset != 0 {
+		linkInfo.CommonNetworkRelativeLink, err = parseCommonNetworkRelativeLink(linkInfoData, linkInfo.CommonNetworkRelativeLinkOffset-4, codePage, &linkInfo.UnicodeErrors)
+		if err != nil {
+			return linkInfo, rebaseParseError(err, "LinkInfo", start+4)
+		}
+	}
 
-			err = binary.Read(commonNetworkRelativeLinkReader, binary.LittleEndian, commonNetworkRelativeLink.CommonNetworkRelativeLinkFlags)
-			if err != nil {
-				return linkInfo, err
-			}
+	// the suffixes are last, a broken one is returned with everything else parsed for lenient parsing to keep
+	var suffixErr error
+	if linkInfo.CommonPathSuffixOffset != 0 {
+		linkInfo.CommonPathSuffix, linkInfo.CommonPathSuffixBase64, err = readByteStringAt(linkInfoData, linkInfo.CommonPathSuffixOffset-4, "CommonPathSuffix", codePage)
+		if err != nil {
+			suffixErr = rebaseParseError(err, "LinkInfo", start+4)
+		}
+	}
 
-			err = binary.Read(commonNetworkRelativeLinkReader, binary.LittleEndian, commonNetworkRelativeLink.NetNameOffset)
-			if err != nil {
-				return linkInfo, err
+	if linkInfo.LinkInfoHeaderSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		if linkInfo.CommonPathSuffixOffsetUnicode != 0 {
+			linkInfo.CommonPathSuffixUnicode, linkInfo.CommonPathSuffixUnicodeBase64, err = readUnicodeStringAt(linkInfoData, linkInfo.CommonPathSuffixOffsetUnicode-4, "CommonPathSuffixUnicode", &linkInfo.UnicodeErrors)
+			if err != nil && suffixErr == nil {
+				suffixErr = rebaseParseError(err, "LinkInfo", start+4)
 			}
+		}
+	}
 
-			err = binary.Read(commonNetworkRelativeLinkReader, binary.LittleEndian, commonNetworkRelativeLink.DeviceNameOffset)
-			if err != nil {
-				return linkInfo, err
-			}
-			//TODO throw const mismatch flag
+	if linkInfo.CommonNetworkRelativeLink != nil {
+		linkInfo.NetworkPath = joinPath(linkInfo.CommonNetworkRelativeLink.Name(), linkInfo.PathSuffix())
+	}
+	linkInfo.LinkInfoBase64 = base64.StdEncoding.EncodeToString(append(putUint32(nil, linkInfoSize), linkInfoData...))
+	return linkInfo, suffixErr
+}
 
-			err = binary.Read(commonNetworkRelativeLinkReader, binary.LittleEndian, commonNetworkRelativeLink.NetworkProviderType)
-			if err != nil {
-				return linkInfo, err
-			}
-			//TODO throw const mismatch flag
+func readByteStringSizeSpecified(r *bytes.Reader, size uint64, codePage *CodePage) (str string, b64 string, err error) {
+	byteString := make([]byte, size)
+	_, err = io.ReadFull(r, byteString)
+	if err != nil {
+		return "", "", err
+	}
+	return codePage.Decode(byteString), base64.StdEncoding.EncodeToString(byteString), nil
+}
 
-			err = binary.Read(commonNetworkRelativeLinkReader, binary.LittleEndian, commonNetworkRelativeLink.NetNameOffsetUnicode)
-			if err != nil {
-				return linkInfo, err
-			}
+func readUnicodeStringSizeSpecified(r *bytes.Reader, countCharacters uint64) (str string, b64 string, err error) {
+	byteString := make([]byte, countCharacters*2)
+	_, err = io.ReadFull(r, byteString)
+	if err != nil {
+		return "", "", err
+	}
+	return decodeUTF16LE(byteString), base64.StdEncoding.EncodeToString(byteString), nil
+}
 
-			err = binary.Read(commonNetworkRelativeLinkReader, binary.LittleEndian, commonNetworkRelativeLink.DeviceNameOffsetUnicode)
-			if err != nil {
-				return linkInfo, err
-			}
+// decodeUTF16LE converts little-endian UTF-16 bytes to a Go string.
+func decodeUTF16LE(b []byte) string {
+	u16s := make([]uint16, len(b)/2)
+	for i := range u16s {
+		u16s[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u16s))
+}
 
-			if commonNetworkRelativeLink.NetNameOffset != 0 {
-				_, err = commonNetworkRelativeLinkReader.Seek(int64(commonNetworkRelativeLink.NetNameOffset-4), io.SeekStart)
-				if err != nil {
-					commonNetworkRelativeLink.NetName = ""
-				} else {
-					commonNetworkRelativeLink.NetName, commonNetworkRelativeLink.NetNameBase64, err = readByteStringZeroTerminated(commonNetworkRelativeLinkReader)
-					if err != nil {
-						commonNetworkRelativeLink.NetName = ""
-					}
-				}
-			}
-			if commonNetworkRelativeLink.DeviceNameOffset != 0 {
-				_, err = commonNetworkRelativeLinkReader.Seek(int64(commonNetworkRelativeLink.DeviceNameOffset-4), io.SeekStart)
-				if err != nil {
-					commonNetworkRelativeLink.DeviceName = ""
-				} else {
-					commonNetworkRelativeLink.DeviceName, commonNetworkRelativeLink.DeviceNameBase64, err = readByteStringZeroTerminated(commonNetworkRelativeLinkReader)
-					if err != nil {
-						commonNetworkRelativeLink.DeviceName = ""
-					}
-				}
-			}
-			if commonNetworkRelativeLink.NetNameOffset > CommonNetworkRelativeLinkUnicodeMinOffsets {
-				_, err = commonNetworkRelativeLinkReader.Seek(int64(commonNetworkRelativeLink.NetNameOffsetUnicode-4), io.SeekStart)
-				if err != nil {
-					commonNetworkRelativeLink.NetNameUnicode = ""
-				} else {
-					if commonNetworkRelativeLink.NetNameOffsetUnicode > 0 {
-						commonNetworkRelativeLink.NetNameUnicode, commonNetworkRelativeLink.NetNameBase64, err = readByteStringZeroTerminated(commonNetworkRelativeLinkReader)
-						if err != nil {
-							commonNetworkRelativeLink.NetNameUnicode = ""
-						}
-					}
-				}
-				_, err = commonNetworkRelativeLinkReader.Seek(int64(commonNetworkRelativeLink.DeviceNameOffsetUnicode-4), io.SeekStart)
-				if err != nil {
-					commonNetworkRelativeLink.DeviceNameUnicode = ""
-				} else {
-					if commonNetworkRelativeLink.DeviceNameOffsetUnicode > 0 {
-						commonNetworkRelativeLink.DeviceNameUnicode, commonNetworkRelativeLink.DeviceNameBase64, err = readByteStringZeroTerminated(commonNetworkRelativeLinkReader)
-						if err != nil {
-							commonNetworkRelativeLink.DeviceNameUnicode = ""
-						}
-					}
-				}
+// InvalidUTF16Error reports an unpaired surrogate in a UTF-16LE string, it is decoded as U+FFFD.
+type InvalidUTF16Error struct {
+	Offset int // Byte offset of the code unit in the string
+	Unit   uint16
+}
 
+func (e *InvalidUTF16Error) Error() string {
+	return fmt.Sprintf("unpaired UTF-16 surrogate 0x%04X at byte %v", e.Unit, e.Offset)
+}
+
+// validateUTF16 returns an InvalidUTF16Error for the first unpaired surrogate.
+func validateUTF16(u16s []uint16) error {
+	for i := 0; i < len(u16s); i++ {
+		switch {
+		case u16s[i] >= 0xD800 && u16s[i] < 0xDC00:
+			if i+1 < len(u16s) && u16s[i+1] >= 0xDC00 && u16s[i+1] < 0xE000 {
+				i++
+				continue
 			}
-			linkInfo.CommonNetworkRelativeLink = commonNetworkRelativeLink
+			return &InvalidUTF16Error{Offset: i * 2, Unit: u16s[i]}
+		case u16s[i] >= 0xDC00 && u16s[i] < 0xE000:
+			return &InvalidUTF16Error{Offset: i * 2, Unit: u16s[i]}
 		}
 	}
+	return nil
+}
 
-	if linkInfo.CommonPathSuffixOffset != 0 {
-		_, err = linkInfoReader.Seek(int64(linkInfo.CommonPathSuffixOffset-4), io.SeekStart)
+// readUnicodeStringZeroTerminated reads a null-terminated UTF-16LE string. The string is also
+// returned with an InvalidUTF16Error, other errors mean the terminator was not found.
+func readUnicodeStringZeroTerminated(r *bytes.Reader) (str string, b64 string, err error) {
+	var byteString []byte
+	var u16s []uint16
+	for {
+		var u16 uint16
+		err = binary.Read(r, binary.LittleEndian, &u16)
 		if err != nil {
-			return linkInfo, err
+			return string(utf16.Decode(u16s)), base64.StdEncoding.EncodeToString(byteString), err
 		}
-		linkInfo.CommonPathSuffix, linkInfo.CommonPathSuffixBase64, err = readByteStringZeroTerminated(linkInfoReader)
-		if err != nil {
-			linkInfo.CommonPathSuffix = ""
+		byteString = append(byteString, byte(u16), byte(u16>>8))
+		if u16 == 0 {
+			break
 		}
+		u16s = append(u16s, u16)
 	}
+	// the terminator is kept in base64 only
+	return string(utf16.Decode(u16s)), base64.StdEncoding.EncodeToString(byteString), validateUTF16(u16s)
+}
 
-	if linkInfo.LinkInfoHeaderSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
-		if linkInfo.CommonPathSuffixOffsetUnicode >= 0 {
-			_, err = linkInfoReader.Seek(int64(linkInfo.CommonPathSuffixOffsetUnicode-4), io.SeekStart)
-			if err != nil {
-				return linkInfo, err
-			}
-			linkInfo.CommonPathSuffixUnicode, linkInfo.CommonPathSuffixBase64, err = readByteStringZeroTerminated(linkInfoReader)
-			if err != nil {
-				linkInfo.CommonPathSuffixUnicode = ""
-			}
-			//TODO read
-		}
-	}
-	return linkInfo, nil
+// filetimeEpochOffset is the number of 100ns intervals between 1601-01-01 and 1970-01-01.
+const filetimeEpochOffset int64 = 116444736000000000
+
+// filetimeToTime converts a FILETIME to UTC time.
+func filetimeToTime(ft uint64) time.Time {
+	unix100ns := int64(ft) - filetimeEpochOffset
+	return time.Unix(unix100ns/10000000, (unix100ns%10000000)*100).UTC()
 }
 
-func readByteStringSizeSpecified(r *bytes.Reader, size uint64) (str string, b64 string, err error) {
+// timeToFiletime converts a time to a FILETIME, 0 for the zero time.
+func timeToFiletime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano()/100 + filetimeEpochOffset)
+}
 
-	var byteString []byte
-	for counter := 0; counter < size; counter++ {
-		b, err := r.ReadByte()
-		if err != nil {
-			break
-		}
-		byteString = append(byteString, b)
+// readStringDataItem reads a single StringData structure: CountCharacters followed by
+// the string itself, UTF-16LE if the link is unicode and ANSI in codePage otherwise.
+func readStringDataItem(r *bytes.Reader, section string, isUnicode bool, codePage *CodePage) (str string, b64 string, err error) {
+	err = checkRemaining(r, section, 2)
+	if err != nil {
+		return "", "", err
 	}
-	byteString = append(byteString, 0x0)
-	return string(byteString), base64.StdEncoding.EncodeToString(byteString), nil
+	var countCharacters uint16
+	err = binary.Read(r, binary.LittleEndian, &countCharacters)
+	if err != nil {
+		return "", "", err
+	}
+	size := uint64(countCharacters)
+	if isUnicode {
+		size *= 2
+	}
+	if size > uint64(r.Len()) {
+		return "", "", &ParseError{Section: section, Offset: readerOffset(r) - 2, Expected: size + 2, Available: uint64(r.Len()) + 2}
+	}
+	if isUnicode {
+		return readUnicodeStringSizeSpecified(r, uint64(countCharacters))
+	}
+	return readByteStringSizeSpecified(r, uint64(countCharacters), codePage)
 }
 
-func ParseStringData(r *bytes.Reader, linkFlagsParsed LinkFlagsParsed) (StringData, error) {
+func ParseStringData(r *bytes.Reader, linkFlagsParsed LinkFlagsParsed, codePage *CodePage) (StringData, error) {
 	var stringData StringData
-	var countCharacters int16
+	var err error
+	isUnicode := linkFlagsParsed.IsUnicode
 
 	if linkFlagsParsed.HasName {
-		err = binary.Read(r, binary.LittleEndian, countCharacters)
+		stringData.NameString, stringData.NameStringBase64, err = readStringDataItem(r, "StringData.NameString", isUnicode, codePage)
 		if err != nil {
-			stringData.NameString = ""
 			return stringData, err
 		}
-		stringData.NameString, stringData.NameStringBase64, err = readByteStringSizeSpecified(r, uint64(countCharacters))
+	}
+
+	if linkFlagsParsed.HasRelativePath {
+		stringData.RelativePath, stringData.RelativePathBase64, err = readStringDataItem(r, "StringData.RelativePath", isUnicode, codePage)
 		if err != nil {
 			return stringData, err
 		}
 	}
-	//TODO firtsly
-	if linkFlagsParsed.HasRelativePath {
 
+	if linkFlagsParsed.HasWorkingDir {
+		stringData.WorkingDir, stringData.WorkingDirBase64, err = readStringDataItem(r, "StringData.WorkingDir", isUnicode, codePage)
+		if err != nil {
+			return stringData, err
+		}
 	}
 
-	if linkFlagsParsed.HasWorkingDir {
+	if linkFlagsParsed.HasArguments {
+		stringData.CommandLineArgs, stringData.CommandLineArgsBase64, err = readStringDataItem(r, "StringData.CommandLineArgs", isUnicode, codePage)
+		if err != nil {
+			return stringData, err
+		}
+	}
 
+	if linkFlagsParsed.HasIconLocation {
+		stringData.IconLocation, stringData.IconLocationBase64, err = readStringDataItem(r, "StringData.IconLocation", isUnicode, codePage)
+		if err != nil {
+			return stringData, err
+		}
 	}
 
-	if linkFlagsParsed.HasArguments {
+	return stringData, nil
+}
+
+// ParseOptions configures how a .lnk file is parsed.
+type ParseOptions struct {
+	CodePage *CodePage // Code page of ANSI strings; if nil, a supported ConsoleFEDataBlock CodePage or DefaultCodePage
+	Lenient  bool      // Recover what can be parsed from damaged files and list the problems in Warnings instead of failing
+}
+
+// ParseData parses a whole .lnk file.
+func ParseData(r *bytes.Reader) (ShellLinkParsed, error) {
+	return ParseDataWithOptions(r, ParseOptions{})
+}
 
+// ParseDataWithOptions parses a whole .lnk file. ANSI strings are decoded in opts.CodePage if given, otherwise
+// in DefaultCodePage, unless the file has a ConsoleFEDataBlock with a supported CodePage, then the file is parsed again with it.
+func ParseDataWithOptions(r *bytes.Reader, opts ParseOptions) (ShellLinkParsed, error) {
+	start := r.Size() - int64(r.Len())
+	if opts.CodePage != nil {
+		shellLinkParsed, err := parseData(r, opts.CodePage, opts.Lenient)
+		shellLinkParsed.CodePage = opts.CodePage
+		shellLinkParsed.CodePageSource = "ParseOptions"
+		return shellLinkParsed, err
 	}
 
-	if linkFlagsParsed.HasIconLocation {
+	codePage := DefaultCodePage
+	shellLinkParsed, err := parseData(r, codePage, opts.Lenient)
+	shellLinkParsed.CodePage = codePage
+	shellLinkParsed.CodePageSource = "default"
+	if err != nil {
+		return shellLinkParsed, err
+	}
 
+	hint := consoleFECodePage(shellLinkParsed.ExtraData)
+	if hint == nil || hint == codePage {
+		return shellLinkParsed, nil
+	}
+	_, err = r.Seek(start, io.SeekStart)
+	if err != nil {
+		return shellLinkParsed, err
 	}
+	shellLinkParsed, err = parseData(r, hint, opts.Lenient)
+	shellLinkParsed.CodePage = hint
+	shellLinkParsed.CodePageSource = "ConsoleFEDataBlock"
+	return shellLinkParsed, err
+}
 
+// consoleFECodePage returns the code page of a ConsoleFEDataBlock, nil if absent or unsupported.
+func consoleFECodePage(extraData []ExtraDataBlock) *CodePage {
+	for _, block := range extraData {
+		if block.ConsoleFEDataBlock != nil {
+			return CodePageByID(block.ConsoleFEDataBlock.CodePage)
+		}
+	}
+	return nil
 }
 
-func ParseData(r *bytes.Reader) (ShellLinkParsed, error) {
+func parseData(r *bytes.Reader, codePage *CodePage, lenient bool) (ShellLinkParsed, error) {
 	var shellLinkParsed ShellLinkParsed
-	shellLinkHeader, err := ParseShellLinkHeader(r)
+	err := parseSections(r, &shellLinkParsed, codePage, lenient)
 	if err != nil {
-		switch t := err.(type) {
-		default:
+		if !lenient {
 			return shellLinkParsed, err
-		case *ConstMismatchError:
-			fmt.Println("ConstMismatchError", t)
-			err.Error()
 		}
+		shellLinkParsed.Warnings = append(shellLinkParsed.Warnings, newParseWarning(err, "", readerOffset(r)))
+	}
+
+	for _, block := range shellLinkParsed.ExtraData {
+		if block.VistaAndAboveIDListDataBlock != nil {
+			comparison := CompareIDLists(shellLinkParsed.LinkTargetIDList, block.VistaAndAboveIDListDataBlock.IDListData)
+			shellLinkParsed.IDListComparison = &comparison
+		}
+	}
+	shellLinkParsed.KnownFolderPath = SplitKnownFolderPath(shellLinkParsed.LinkTargetIDList, shellLinkParsed.ExtraData)
+	shellLinkParsed.Target = ResolveTarget(&shellLinkParsed)
+
+	return shellLinkParsed, nil
+}
 
+// parseSections parses the header, IDList, LinkInfo, StringData and ExtraData in file order. In lenient mode
+// an error inside a section becomes a warning and parsing goes on after it, only an error that leaves the
+// end of the section unknown is returned.
+func parseSections(r *bytes.Reader, shellLinkParsed *ShellLinkParsed, codePage *CodePage, lenient bool) error {
+	shellLinkHeader, err := ParseShellLinkHeader(r)
+	if err != nil {
+		// in lenient mode a ConstMismatchError is not fatal, the rest of the file is still parsed
+		var constMismatchError *ConstMismatchError
+		if !lenient || !errors.As(err, &constMismatchError) {
+			return err
+		}
+		shellLinkParsed.Warnings = append(shellLinkParsed.Warnings, newParseWarning(err, "ShellLinkHeader", 0))
 	}
-	shellLinkParsed.header = shellLinkHeader
+	shellLinkParsed.Header = shellLinkHeader
 
 	linkFlagsParsed := ParseLinkFlags(shellLinkHeader.LinkFlags)
-	shellLinkParsed.linkFlagsParsed = linkFlagsParsed
+	shellLinkParsed.LinkFlagsParsed = linkFlagsParsed
 
 	fileAttributesParsed := ParseFileAttributes(shellLinkHeader.FileAttributes)
-	shellLinkParsed.fileAttributesParsed = fileAttributesParsed
+	shellLinkParsed.FileAttributesParsed = fileAttributesParsed
 
-	//TODO: parse HotKeyFlags
+	shellLinkParsed.HeaderParsed = ParseHeaderFields(shellLinkHeader)
 
-	var linkTargetIDList LinkTargetIDList
 	if linkFlagsParsed.HasLinkTargetIDList {
-		linkTargetIDList, err = ParseLinkTargetIDList(r)
+		start := readerOffset(r)
+		linkTargetIDList, err := ParseLinkTargetIDList(r, codePage)
 		if err != nil {
-			return shellLinkParsed, err
+			if !lenient || isSectionSizeError(err, "LinkTargetIDList") {
+				return err
+			}
+			shellLinkParsed.Warnings = append(shellLinkParsed.Warnings, newParseWarning(err, "LinkTargetIDList", start))
 		}
+		shellLinkParsed.LinkTargetIDList = &linkTargetIDList
 	}
-	shellLinkParsed.linkTargetIDList = linkTargetIDList
 
-	var linkInfo LinkInfo
 	if linkFlagsParsed.HasLinkInfo {
-		linkInfo, err = ParseLinkInfo(r)
+		start := readerOffset(r)
+		linkInfo, err := ParseLinkInfo(r, codePage)
 		if err != nil {
-			return shellLinkParsed, err
+			if !lenient || isSectionSizeError(err, "LinkInfo") {
+				return err
+			}
+			shellLinkParsed.Warnings = append(shellLinkParsed.Warnings, newParseWarning(err, "LinkInfo", start))
 		}
+		shellLinkParsed.LinkInfo = &linkInfo
 	}
-	shellLinkParsed.linkInfo = linkInfo
 
-	var stringData StringData
 	if linkFlagsParsed.HasName || linkFlagsParsed.HasRelativePath ||
 		linkFlagsParsed.HasWorkingDir || linkFlagsParsed.HasArguments ||
 		linkFlagsParsed.HasIconLocation {
-		stringData, err = ParseStringData(r, linkFlagsParsed)
+		// the items follow each other, nothing after a broken one can be located
+		shellLinkParsed.StringData, err = ParseStringData(r, linkFlagsParsed, codePage)
+		if err != nil {
+			return err
+		}
+	}
+	if linkFlagsParsed.HasIconLocation {
+		shellLinkParsed.HeaderParsed.IconLocation = fmt.Sprintf("%v,%v", shellLinkParsed.StringData.IconLocation, shellLinkHeader.IconIndex)
+	}
+
+	var warnings *[]ParseWarning
+	if lenient {
+		warnings = &shellLinkParsed.Warnings
+	}
+	var terminalBlockSize *uint32
+	shellLinkParsed.ExtraData, terminalBlockSize, err = parseExtraData(r, codePage, warnings)
+	if err == nil && terminalBlockSize == nil {
+		shellLinkParsed.TerminalBlockMissing = true
+	} else if err == nil {
+		shellLinkParsed.TerminalBlockSize = *terminalBlockSize
+	}
+	if err == nil && r.Len() > 0 {
+		rest, _ := ioutil.ReadAll(r)
+		shellLinkParsed.TrailingDataBase64 = base64.StdEncoding.EncodeToString(rest)
 	}
+	return err
 }
 
-func main() {
-	// Replace "example.lnk" with the path to your .lnk file.
-	data, err := ReadLnkFile("FineReaderPortable.lnk")
+// Parse parses a .lnk file from a reader, use bytes.NewReader for a byte slice.
+func Parse(r io.Reader) (ShellLinkParsed, error) {
+	return ParseWithOptions(r, ParseOptions{})
+}
+
+// ParseWithOptions parses a .lnk file from a reader with non-default options.
+func ParseWithOptions(r io.Reader, opts ParseOptions) (ShellLinkParsed, error) {
+	if br, ok := r.(*bytes.Reader); ok {
+		return ParseDataWithOptions(br, opts)
+	}
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
-		fmt.Printf("Error reading .lnk file: %v\n", err)
-		return
+		return ShellLinkParsed{}, err
 	}
+	return ParseDataWithOptions(bytes.NewReader(data), opts)
+}
 
-	reader := bytes.NewReader(data)
-	header, err := ParseShellLinkHeader(reader)
-
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// PropertyStorage represents a Serialized Property Storage of a PropertyStoreDataBlock (MS-PROPSTORE).
+type PropertyStorage struct {
+	StorageSize uint32                    `json:"StorageSize"`
+	Version     uint32                    `json:"Version"`
+	FormatID    GUID                      `json:"FormatID"`
+	Values      []SerializedPropertyValue `json:"Values"`
+}
+
+// SerializedPropertyValue represents a single property, named by ID or by Name if the
+// FormatID is PropertyStorageStringNameFormatID.
+type SerializedPropertyValue struct {
+	ValueSize    uint32             `json:"ValueSize"`
+	ID           uint32             `json:"ID"`
+	NameSize     uint32             `json:"NameSize,omitempty"`
+	Name         string             `json:"Name,omitempty"`
+	Reserved     uint8              `json:"Reserved"`
+	PropertyName string             `json:"PropertyName,omitempty"` // Canonical name of well-known FormatID/ID pairs
+	Value        TypedPropertyValue `json:"Value"`
+}
+
+// TypedPropertyValue represents a PROPVARIANT (MS-OLEPS), types that are not decoded are kept as base64.
+type TypedPropertyValue struct {
+	Type        uint16      `json:"Type"`
+	TypeName    string      `json:"TypeName"`
+	Value       interface{} `json:"Value,omitempty"`
+	ValueBase64 string      `json:"ValueBase64,omitempty"`
+}
+
+// PropertyStorage consts
+const (
+	PropertyStorageVersion          uint32 = 0x53505331 // "1SPS"
+	PropertyStorageHeaderSize       uint32 = 0x00000018
+	SerializedPropertyValueSizeMin  uint32 = 0x00000009
+	SerializedPropertyValueTypeSize uint32 = 0x00000004
+)
+
+// PropertyStorageStringNameFormatID marks a storage whose properties are named by strings.
+var PropertyStorageStringNameFormatID = mustParseGUID("D5CDD505-2E9C-101B-9397-08002B2CF9AE")
+
+// PropertyType
+const (
+	VT_EMPTY    uint16 = 0x0000
+	VT_NULL     uint16 = 0x0001
+	VT_I2       uint16 = 0x0002
+	VT_I4       uint16 = 0x0003
+	VT_R4       uint16 = 0x0004
+	VT_R8       uint16 = 0x0005
+	VT_CY       uint16 = 0x0006
+	VT_DATE     uint16 = 0x0007
+	VT_BSTR     uint16 = 0x0008
+	VT_ERROR    uint16 = 0x000A
+	VT_BOOL     uint16 = 0x000B
+	VT_VARIANT  uint16 = 0x000C
+	VT_DECIMAL  uint16 = 0x000E
+	VT_I1       uint16 = 0x0010
+	VT_UI1      uint16 = 0x0011
+	VT_UI2      uint16 = 0x0012
+	VT_UI4      uint16 = 0x0013
+	VT_I8       uint16 = 0x0014
+	VT_UI8      uint16 = 0x0015
+	VT_INT      uint16 = 0x0016
+	VT_UINT     uint16 = 0x0017
+	VT_LPSTR    uint16 = 0x001E
+	VT_LPWSTR   uint16 = 0x001F
+	VT_FILETIME uint16 = 0x0040
+	VT_BLOB     uint16 = 0x0041
+	VT_STREAM   uint16 = 0x0042
+	VT_STORAGE  uint16 = 0x0043
+	VT_CLSID    uint16 = 0x0048
+	VT_VECTOR   uint16 = 0x1000
+	VT_ARRAY    uint16 = 0x2000
+)
+
+var propertyTypeNames = map[uint16]string{
+	VT_EMPTY:    "VT_EMPTY",
+	VT_NULL:     "VT_NULL",
+	VT_I2:       "VT_I2",
+	VT_I4:       "VT_I4",
+	VT_R4:       "VT_R4",
+	VT_R8:       "VT_R8",
+	VT_CY:       "VT_CY",
+	VT_DATE:     "VT_DATE",
+	VT_BSTR:     "VT_BSTR",
+	VT_ERROR:    "VT_ERROR",
+	VT_BOOL:     "VT_BOOL",
+	VT_VARIANT:  "VT_VARIANT",
+	VT_DECIMAL:  "VT_DECIMAL",
+	VT_I1:       "VT_I1",
+	VT_UI1:      "VT_UI1",
+	VT_UI2:      "VT_UI2",
+	VT_UI4:      "VT_UI4",
+	VT_I8:       "VT_I8",
+	VT_UI8:      "VT_UI8",
+	VT_INT:      "VT_INT",
+	VT_UINT:     "VT_UINT",
+	VT_LPSTR:    "VT_LPSTR",
+	VT_LPWSTR:   "VT_LPWSTR",
+	VT_FILETIME: "VT_FILETIME",
+	VT_BLOB:     "VT_BLOB",
+	VT_STREAM:   "VT_STREAM",
+	VT_STORAGE:  "VT_STORAGE",
+	VT_CLSID:    "VT_CLSID",
+}
+
+// PropertyKey identifies a property by its FormatID and ID.
+type PropertyKey struct {
+	FormatID GUID
+	ID       uint32
+}
+
+// propertyNames maps well-known property keys to their canonical names.
+var propertyNames = map[PropertyKey]string{
+	{mustParseGUID("B725F130-47EF-101A-A5F1-02608C9EEBAC"), 4}:   "System.ItemTypeText",
+	{mustParseGUID("B725F130-47EF-101A-A5F1-02608C9EEBAC"), 10}:  "System.ItemNameDisplay",
+	{mustParseGUID("B725F130-47EF-101A-A5F1-02608C9EEBAC"), 12}:  "System.Size",
+	{mustParseGUID("B725F130-47EF-101A-A5F1-02608C9EEBAC"), 13}:  "System.FileAttributes",
+	{mustParseGUID("B725F130-47EF-101A-A5F1-02608C9EEBAC"), 14}:  "System.DateModified",
+	{mustParseGUID("B725F130-47EF-101A-A5F1-02608C9EEBAC"), 15}:  "System.DateCreated",
+	{mustParseGUID("B725F130-47EF-101A-A5F1-02608C9EEBAC"), 16}:  "System.DateAccessed",
+	{mustParseGUID("28636AA6-953D-11D2-B5D6-00C04FD918D0"), 2}:   "System.DescriptionID",
+	{mustParseGUID("28636AA6-953D-11D2-B5D6-00C04FD918D0"), 11}:  "System.ItemType",
+	{mustParseGUID("28636AA6-953D-11D2-B5D6-00C04FD918D0"), 24}:  "System.ParsingName",
+	{mustParseGUID("28636AA6-953D-11D2-B5D6-00C04FD918D0"), 25}:  "System.SFGAOFlags",
+	{mustParseGUID("28636AA6-953D-11D2-B5D6-00C04FD918D0"), 30}:  "System.ParsingPath",
+	{mustParseGUID("41CF5AE0-F75A-4806-BD87-59C7D9248EB9"), 100}: "System.FileName",
+	{mustParseGUID("446D16B1-8DAD-4870-A748-402EA43D788C"), 100}: "System.ThumbnailCacheId",
+	{mustParseGUID("446D16B1-8DAD-4870-A748-402EA43D788C"), 104}: "System.VolumeId",
+	{mustParseGUID("B9B4B3FC-2B51-4A42-B5D8-324146AFCF25"), 2}:   "System.Link.TargetParsingPath",
+	{mustParseGUID("B9B4B3FC-2B51-4A42-B5D8-324146AFCF25"), 5}:   "System.Link.Comment",
+	{mustParseGUID("B9B4B3FC-2B51-4A42-B5D8-324146AFCF25"), 8}:   "System.Link.TargetSFGAOFlags",
+	{mustParseGUID("436F2667-14E2-4FEB-B30A-146C53B5B674"), 100}: "System.Link.Arguments",
+	{mustParseGUID("5CBF2787-48CF-4208-B90E-EE5E5D420294"), 2}:   "System.Link.TargetUrl",
+	{mustParseGUID("7A7D76F4-B630-4BD7-95FF-37CC51A975C9"), 2}:   "System.Link.TargetExtension",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 2}:   "System.AppUserModel.RelaunchCommand",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 3}:   "System.AppUserModel.RelaunchIconResource",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 4}:   "System.AppUserModel.RelaunchDisplayNameResource",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 5}:   "System.AppUserModel.ID",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 8}:   "System.AppUserModel.ExcludeFromShowInNewInstall",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 9}:   "System.AppUserModel.PreventPinning",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 11}:  "System.AppUserModel.IsDualMode",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 12}:  "System.AppUserModel.StartPinOption",
+	{mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 26}:  "System.AppUserModel.ToastActivatorCLSID",
+}
+
+// ParsePropertyStore parses the Serialized Property Storage list of a PropertyStoreDataBlock.
+func ParsePropertyStore(data []byte) ([]PropertyStorage, error) {
+	var storages = []PropertyStorage{}
+	offset := 0
+	for {
+		section := fmt.Sprintf("PropertyStorage[%d]", len(storages))
+		err := checkBounds(data, section, uint64(offset), 4)
+		if err != nil {
+			return storages, err
+		}
+		storageSize := binary.LittleEndian.Uint32(data[offset:])
+		// terminated by a zero StorageSize
+		if storageSize == 0 {
+			break
+		}
+		if storageSize < PropertyStorageHeaderSize {
+			return storages, fmt.Errorf("PropertyStorage StorageSize 0x%X out of range", storageSize)
+		}
+		err = checkBounds(data, section, uint64(offset), uint64(storageSize))
+		if err != nil {
+			return storages, err
+		}
+
+		storage, err := parsePropertyStorage(data[offset : offset+int(storageSize)])
+		if err != nil {
+			return storages, rebaseParseError(err, section, int64(offset))
+		}
+		storages = append(storages, storage)
+		offset += int(storageSize)
+	}
+	return storages, nil
+}
+
+func parsePropertyStorage(data []byte) (PropertyStorage, error) {
+	var storage PropertyStorage
+	storage.StorageSize = binary.LittleEndian.Uint32(data)
+	storage.Version = binary.LittleEndian.Uint32(data[4:])
+	copy(storage.FormatID[:], data[8:24])
+	if storage.Version != PropertyStorageVersion {
+		return storage, &ConstMismatchError{
+			At:       "PropertyStorage Version",
+			Is:       fmt.Sprintf("0x%08X", storage.Version),
+			Expected: fmt.Sprintf("0x%08X", PropertyStorageVersion),
+		}
+	}
+	stringNamed := storage.FormatID == PropertyStorageStringNameFormatID
+
+	storage.Values = []SerializedPropertyValue{}
+	offset := int(PropertyStorageHeaderSize)
+	for {
+		section := fmt.Sprintf("Values[%d]", len(storage.Values))
+		err := checkBounds(data, section, uint64(offset), 4)
+		if err != nil {
+			return storage, err
+		}
+		valueSize := binary.LittleEndian.Uint32(data[offset:])
+		// terminated by a zero ValueSize
+		if valueSize == 0 {
+			break
+		}
+		if valueSize < SerializedPropertyValueSizeMin {
+			return storage, fmt.Errorf("SerializedPropertyValue ValueSize 0x%X out of range", valueSize)
+		}
+		err = checkBounds(data, section, uint64(offset), uint64(valueSize))
+		if err != nil {
+			return storage, err
+		}
+
+		value, err := parseSerializedPropertyValue(data[offset:offset+int(valueSize)], storage.FormatID, stringNamed)
+		if err != nil {
+			return storage, err
+		}
+		storage.Values = append(storage.Values, value)
+		offset += int(valueSize)
+	}
+	return storage, nil
+}
+
+func parseSerializedPropertyValue(data []byte, formatID GUID, stringNamed bool) (SerializedPropertyValue, error) {
+	var value SerializedPropertyValue
+	value.ValueSize = binary.LittleEndian.Uint32(data)
+	value.Reserved = data[8]
+	typed := data[SerializedPropertyValueSizeMin:]
+	if stringNamed {
+		value.NameSize = binary.LittleEndian.Uint32(data[4:])
+		if uint64(value.NameSize) > uint64(len(typed)) {
+			return value, fmt.Errorf("SerializedPropertyValue NameSize 0x%X out of range", value.NameSize)
+		}
+		value.Name = decodeFixedUnicode(typed[:value.NameSize])
+		typed = typed[value.NameSize:]
+	} else {
+		value.ID = binary.LittleEndian.Uint32(data[4:])
+		value.PropertyName = propertyNames[PropertyKey{FormatID: formatID, ID: value.ID}]
+	}
+
+	typedPropertyValue, err := parseTypedPropertyValue(typed)
+	if err != nil {
+		return value, err
+	}
+	value.Value = typedPropertyValue
+	return value, nil
+}
+
+// parseTypedPropertyValue decodes Type, Padding and the value that follows.
+func parseTypedPropertyValue(data []byte) (TypedPropertyValue, error) {
+	var typedPropertyValue TypedPropertyValue
+	if uint32(len(data)) < SerializedPropertyValueTypeSize {
+		return typedPropertyValue, io.ErrUnexpectedEOF
+	}
+	typedPropertyValue.Type = binary.LittleEndian.Uint16(data)
+	typedPropertyValue.TypeName = propertyTypeName(typedPropertyValue.Type)
+	data = data[SerializedPropertyValueTypeSize:]
+
+	r := bytes.NewReader(data)
+	var value interface{}
+	var err error
+	ok := true
+	if typedPropertyValue.Type&VT_VECTOR != 0 {
+		value, ok, err = readPropertyVector(r, typedPropertyValue.Type&^VT_VECTOR)
+	} else {
+		value, ok, err = readPropertyScalar(r, typedPropertyValue.Type, false)
+	}
+	if err != nil {
+		return typedPropertyValue, fmt.Errorf("TypedPropertyValue %v: %w", typedPropertyValue.TypeName, err)
+	}
+	if !ok {
+		typedPropertyValue.ValueBase64 = base64.StdEncoding.EncodeToString(data)
+		return typedPropertyValue, nil
+	}
+	typedPropertyValue.Value = value
+	return typedPropertyValue, nil
+}
+
+func propertyTypeName(propertyType uint16) string {
+	name, ok := propertyTypeNames[propertyType&^(VT_VECTOR|VT_ARRAY)]
+	if !ok {
+		name = fmt.Sprintf("0x%04X", propertyType&^(VT_VECTOR|VT_ARRAY))
+	}
+	if propertyType&VT_VECTOR != 0 {
+		name = "VT_VECTOR|" + name
+	}
+	if propertyType&VT_ARRAY != 0 {
+		name = "VT_ARRAY|" + name
+	}
+	return name
+}
+
+// readPropertyVector reads a VectorHeader and its elements, ok is false for element types that are not decoded.
+func readPropertyVector(r *bytes.Reader, elementType uint16) (interface{}, bool, error) {
+	var length uint32
+	err := binary.Read(r, binary.LittleEndian, &length)
+	if err != nil {
+		return nil, true, err
+	}
+	if uint64(length) > uint64(r.Len()) {
+		return nil, true, fmt.Errorf("vector length %v out of range", length)
+	}
+	values := make([]interface{}, 0, length)
+	for i := uint32(0); i < length; i++ {
+		var value interface{}
+		var ok bool
+		if elementType == VT_VARIANT {
+			value, ok, err = readPropertyVariant(r)
+		} else {
+			value, ok, err = readPropertyScalar(r, elementType, true)
+		}
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		values = append(values, value)
+	}
+	return values, true, nil
+}
+
+func readPropertyVariant(r *bytes.Reader) (interface{}, bool, error) {
+	var header [2]uint16
+	err := binary.Read(r, binary.LittleEndian, &header)
+	if err != nil {
+		return nil, true, err
+	}
+	value, ok, err := readPropertyScalar(r, header[0], false)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return TypedPropertyValue{Type: header[0], TypeName: propertyTypeName(header[0]), Value: value}, true, nil
+}
+
+// readPropertyScalar reads a single value, packed values of a vector are not padded to 4 bytes.
+func readPropertyScalar(r *bytes.Reader, propertyType uint16, packed bool) (interface{}, bool, error) {
+	var err error
+	switch propertyType {
+	case VT_EMPTY, VT_NULL:
+		return nil, true, nil
+	case VT_I1:
+		var v int8
+		err = readPadded(r, &v, 1, packed)
+		return v, true, err
+	case VT_UI1:
+		var v uint8
+		err = readPadded(r, &v, 1, packed)
+		return v, true, err
+	case VT_I2:
+		var v int16
+		err = readPadded(r, &v, 2, packed)
+		return v, true, err
+	case VT_UI2:
+		var v uint16
+		err = readPadded(r, &v, 2, packed)
+		return v, true, err
+	case VT_BOOL:
+		var v uint16
+		err = readPadded(r, &v, 2, packed)
+		return v != 0, true, err
+	case VT_I4, VT_INT:
+		var v int32
+		err = binary.Read(r, binary.LittleEndian, &v)
+		return v, true, err
+	case VT_UI4, VT_UINT, VT_ERROR:
+		var v uint32
+		err = binary.Read(r, binary.LittleEndian, &v)
+		return v, true, err
+	case VT_I8, VT_CY:
+		var v int64
+		err = binary.Read(r, binary.LittleEndian, &v)
+		return v, true, err
+	case VT_UI8:
+		var v uint64
+		err = binary.Read(r, binary.LittleEndian, &v)
+		return v, true, err
+	case VT_R4:
+		var v float32
+		err = binary.Read(r, binary.LittleEndian, &v)
+		return jsonFloat(float64(v)), true, err
+	case VT_R8, VT_DATE:
+		var v float64
+		err = binary.Read(r, binary.LittleEndian, &v)
+		return jsonFloat(v), true, err
+	case VT_FILETIME:
+		var v uint64
+		err = binary.Read(r, binary.LittleEndian, &v)
+		if err != nil || v == 0 {
+			return nil, true, err
+		}
+		return filetimeToTime(v), true, nil
+	case VT_CLSID:
+		var v GUID
+		_, err = io.ReadFull(r, v[:])
+		return v, true, err
+	case VT_LPWSTR, VT_BSTR, VT_LPSTR:
+		// property stores use the UTF-16 code page for all strings
+		b, err := readPropertyCounted(r, propertyType == VT_LPWSTR)
+		if err != nil {
+			return nil, true, err
+		}
+		return decodeFixedUnicode(b), true, nil
+	case VT_BLOB:
+		b, err := readPropertyCounted(r, false)
+		if err != nil {
+			return nil, true, err
+		}
+		return base64.StdEncoding.EncodeToString(b), true, nil
+	}
+	return nil, false, nil
+}
+
+// readPadded reads a value smaller than 4 bytes followed by padding to 4 bytes.
+func readPadded(r *bytes.Reader, v interface{}, size int64, packed bool) error {
+	err := binary.Read(r, binary.LittleEndian, v)
+	if err != nil || packed {
+		return err
+	}
+	_, err = r.Seek(4-size, io.SeekCurrent)
+	return err
+}
+
+// readPropertyCounted reads a Size or Length prefixed value padded to 4 bytes,
+// the count is in characters for UnicodeString and in bytes otherwise.
+func readPropertyCounted(r *bytes.Reader, countCharacters bool) ([]byte, error) {
+	var count uint32
+	err := binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(count)
+	if countCharacters {
+		size *= 2
+	}
+	if size > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, size)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, err
+	}
+	if pad := size % 4; pad != 0 && int64(r.Len()) >= int64(4-pad) {
+		_, err = r.Seek(int64(4-pad), io.SeekCurrent)
+	}
+	return b, err
+}
+
+// jsonFloat keeps NaN and Inf out of JSON, which cannot represent them.
+func jsonFloat(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprint(v)
+	}
+	return v
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// propertyStorage builds a Serialized Property Storage from already serialized values.
+func propertyStorage(formatID GUID, values ...[]byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("1SPS")
+	b.Write(formatID[:])
+	for _, v := range values {
+		b.Write(v)
+	}
+	b.Write([]byte{0x00, 0x00, 0x00, 0x00})
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(b.Len()+4))
+	return append(size, b.Bytes()...)
+}
+
+// integerNamedValue builds a SerializedPropertyValue named by ID.
+func integerNamedValue(id uint32, typed []byte) []byte {
+	b := make([]byte, 9, 9+len(typed))
+	binary.LittleEndian.PutUint32(b, uint32(9+len(typed)))
+	binary.LittleEndian.PutUint32(b[4:], id)
+	return append(b, typed...)
+}
+
+func Test_ParsePropertyStore(t *testing.T) {
+	linkFormatID := mustParseGUID("B9B4B3FC-2B51-4A42-B5D8-324146AFCF25")
+	appUserModelFormatID := mustParseGUID("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3")
+	type args struct {
+		data []byte
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []PropertyStorage
+		wantErr bool
+	}{
+		{
+			name: "terminator only",
+			args: args{data: []byte{0x00, 0x00, 0x00, 0x00}},
+			want: []PropertyStorage{},
+		},
+		{
+			name: "integer named values",
+			args: args{data: append(propertyStorage(appUserModelFormatID,
+				integerNamedValue(5, []byte{0x1F, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 'A', 0x00, 0x00, 0x00}),
+				integerNamedValue(9, []byte{0x0B, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00}),
+				integerNamedValue(0x77, []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x2B, 0x50, 0x9A, 0x5E, 0x56, 0xD9, 0x01}),
+			), 0x00, 0x00, 0x00, 0x00)},
+			want: []PropertyStorage{
+				{
+					StorageSize: 0x57,
+					Version:     PropertyStorageVersion,
+					FormatID:    appUserModelFormatID,
+					Values: []SerializedPropertyValue{
+						{ValueSize: 0x15, ID: 5, PropertyName: "System.AppUserModel.ID", Value: TypedPropertyValue{Type: VT_LPWSTR, TypeName: "VT_LPWSTR", Value: "A"}},
+						{ValueSize: 0x11, ID: 9, PropertyName: "System.AppUserModel.PreventPinning", Value: TypedPropertyValue{Type: VT_BOOL, TypeName: "VT_BOOL", Value: true}},
+						{ValueSize: 0x15, ID: 0x77, Value: TypedPropertyValue{Type: VT_FILETIME, TypeName: "VT_FILETIME", Value: time.Date(2023, 3, 14, 10, 20, 30, 0, time.UTC)}},
+					},
+				},
+			},
+		},
+		{
+			name: "vector of strings and unknown type",
+			args: args{data: append(propertyStorage(linkFormatID,
+				integerNamedValue(2, []byte{0x1F, 0x10, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 'a', 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 'b', 0x00, 0x00, 0x00}),
+				integerNamedValue(3, []byte{0x49, 0x00, 0x00, 0x00, 0x01, 0x02}),
+			), 0x00, 0x00, 0x00, 0x00)},
+			want: []PropertyStorage{
+				{
+					StorageSize: 0x4C,
+					Version:     PropertyStorageVersion,
+					FormatID:    linkFormatID,
+					Values: []SerializedPropertyValue{
+						{ValueSize: 0x21, ID: 2, PropertyName: "System.Link.TargetParsingPath", Value: TypedPropertyValue{Type: VT_VECTOR | VT_LPWSTR, TypeName: "VT_VECTOR|VT_LPWSTR", Value: []interface{}{"a", "b"}}},
+						{ValueSize: 0x0F, ID: 3, Value: TypedPropertyValue{Type: 0x49, TypeName: "0x0049", ValueBase64: "AQI="}},
+					},
+				},
+			},
+		},
+		{
+			name: "string named value",
+			args: args{data: append(propertyStorage(PropertyStorageStringNameFormatID,
+				[]byte{0x17, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00, 'a', 0x00, 'b', 0x00, 0x00, 0x00, 0x13, 0x00, 0x00, 0x00, 0x2A, 0x00, 0x00, 0x00},
+			), 0x00, 0x00, 0x00, 0x00)},
+			want: []PropertyStorage{
+				{
+					StorageSize: 0x33,
+					Version:     PropertyStorageVersion,
+					FormatID:    PropertyStorageStringNameFormatID,
+					Values: []SerializedPropertyValue{
+						{ValueSize: 0x17, NameSize: 6, Name: "ab", Value: TypedPropertyValue{Type: VT_UI4, TypeName: "VT_UI4", Value: uint32(42)}},
+					},
+				},
+			},
+		},
+		{
+			name:    "wrong version",
+			args:    args{data: []byte{0x18, 0x00, 0x00, 0x00, '2', 'S', 'P', 'S', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+			want:    []PropertyStorage{},
+			wantErr: true,
+		},
+		{
+			name:    "storage size beyond data",
+			args:    args{data: []byte{0xFF, 0x00, 0x00, 0x00, '1', 'S', 'P', 'S'}},
+			want:    []PropertyStorage{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePropertyStore(tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePropertyStore() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePropertyStore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ShellItem is the decoded ItemIDData of an ItemID, exactly one of the typed fields is set
+// for the known class types.
+type ShellItem struct {
+	ClassType       uint8                     `json:"ClassType"`
+	ClassTypeName   string                    `json:"ClassTypeName"`
+	RootFolder      *RootFolderShellItem      `json:"RootFolder,omitempty"`
+	Volume          *VolumeShellItem          `json:"Volume,omitempty"`
+	FileEntry       *FileEntryShellItem       `json:"FileEntry,omitempty"`
+	NetworkLocation *NetworkLocationShellItem `json:"NetworkLocation,omitempty"`
+}
+
+// RootFolderShellItem represents a root folder shell item, a shell folder identified by CLSID.
+type RootFolderShellItem struct {
+	SortIndex       uint8  `json:"SortIndex"`
+	ShellFolderID   GUID   `json:"ShellFolderID"`
+	ShellFolderName string `json:"ShellFolderName,omitempty"`
+}
+
+// VolumeShellItem represents a volume shell item, usually a drive letter.
+type VolumeShellItem struct {
+	Flags           uint8  `json:"Flags"`
+	Name            string `json:"Name,omitempty"`
+	ShellFolderID   *GUID  `json:"ShellFolderID,omitempty"`
+	ShellFolderName string `json:"ShellFolderName,omitempty"`
+}
+
+// FileEntryShellItem represents a file or directory shell item.
+type FileEntryShellItem struct {
+	Flags                uint8                `json:"Flags"`
+	IsDirectory          bool                 `json:"IsDirectory"`
+	IsFile               bool                 `json:"IsFile"`
+	IsUnicode            bool                 `json:"IsUnicode"`
+	FileSize             uint32               `json:"FileSize"`
+	ModificationTimeRaw  uint32               `json:"ModificationTimeRaw"`
+	ModificationTime     *time.Time           `json:"ModificationTime"`
+	FileAttributes       uint16               `json:"FileAttributes"`
+	FileAttributesParsed FileAttributesParsed `json:"FileAttributesParsed"`
+	PrimaryName          string               `json:"PrimaryName"`
+	ExtensionBlocks      []ExtensionBlock     `json:"ExtensionBlocks,omitempty"`
+}
+
+// NetworkLocationShellItem represents a network location shell item: domain, server or UNC share.
+type NetworkLocationShellItem struct {
+	Flags       uint8  `json:"Flags"`
+	Location    string `json:"Location"`
+	Description string `json:"Description,omitempty"`
+	Comments    string `json:"Comments,omitempty"`
+}
+
+// ShellItem class types
+const (
+	ShellItemClassTypeRootFolder      uint8 = 0x1F
+	ShellItemClassTypeVolume          uint8 = 0x20 // 0x20 - 0x2F
+	ShellItemClassTypeFileEntry       uint8 = 0x30 // 0x30 - 0x3F
+	ShellItemClassTypeNetworkLocation uint8 = 0x40 // 0x40 - 0x4F
+	ShellItemClassTypeMask            uint8 = 0x70
+)
+
+// ShellItem class type flags
+const (
+	VolumeShellItemHasName                 uint8 = 0x01
+	VolumeShellItemHasShellFolderID        uint8 = 0x0E
+	FileEntryShellItemIsDirectory          uint8 = 0x01
+	FileEntryShellItemIsFile               uint8 = 0x02
+	FileEntryShellItemIsUnicode            uint8 = 0x04
+	NetworkLocationShellItemHasDescription uint8 = 0x80
+	NetworkLocationShellItemHasComments    uint8 = 0x40
+)
+
+// ShellItem layout
+const (
+	RootFolderShellItemSize             int = 18
+	VolumeShellItemShellFolderIDOffset  int = 4
+	FileEntryShellItemPrimaryNameOffset int = 12
+	NetworkLocationShellItemSizeMin     int = 4
+)
+
+// shellFolderNames maps well-known shell folder CLSIDs to their display names.
+var shellFolderNames = map[GUID]string{
+	mustParseGUID("00021400-0000-0000-C000-000000000046"): "Desktop",
+	mustParseGUID("20D04FE0-3AEA-1069-A2D8-08002B30309D"): "My Computer",
+	mustParseGUID("450D8FBA-AD25-11D0-98A8-0800361B1103"): "My Documents",
+	mustParseGUID("208D2C60-3AEA-1069-A2D7-08002B30309D"): "My Network Places",
+	mustParseGUID("F02C1A0D-BE21-4350-88B0-7367FC96EF3C"): "Network",
+	mustParseGUID("645FF040-5081-101B-9F08-00AA002F954E"): "Recycle Bin",
+	mustParseGUID("21EC2020-3AEA-1069-A2DD-08002B30309D"): "Control Panel",
+	mustParseGUID("26EE0668-A00A-44D7-9371-BEB064C98683"): "Control Panel",
+	mustParseGUID("2227A280-3AEA-1069-A2DE-08002B30309D"): "Printers",
+	mustParseGUID("D20EA4E1-3957-11D2-A40B-0C5020524153"): "Administrative Tools",
+	mustParseGUID("59031A47-3F72-44A7-89C5-5595FE6B30EE"): "Users Files",
+	mustParseGUID("031E4825-7B94-4DC3-B131-E946B44C8DD5"): "Libraries",
+	mustParseGUID("679F85CB-0220-4080-B29B-5540CC05AAB6"): "Quick Access",
+	mustParseGUID("871C5380-42A0-1069-A2EA-08002B30309D"): "Internet Explorer",
+	mustParseGUID("018D5C66-4533-4307-9B53-224DE2ED1FE6"): "OneDrive",
+	mustParseGUID("4234D49B-0245-4DF3-B780-3893943456E1"): "Applications",
+	mustParseGUID("B4BFCC3A-DB2C-424C-B029-7FE99A87C641"): "Desktop",
+	mustParseGUID("D3162B92-9365-467A-956B-92703ACA08AF"): "Documents",
+	mustParseGUID("088E3905-0323-4B02-9826-5D99428E115F"): "Downloads",
+	mustParseGUID("3DFDF296-DBEC-4FB4-81D1-6A3438BCF4DE"): "Music",
+	mustParseGUID("24AD3AD4-A569-4530-98E1-AB02F9417AA8"): "Pictures",
+	mustParseGUID("F86FA3AB-70D2-4FC7-9C99-FCBF05467F3A"): "Videos",
+	mustParseGUID("0DB7E03F-FC29-4DC6-9020-FF41B59E513A"): "3D Objects",
+}
+
+// ParseShellItem decodes the ItemIDData of an ItemID following its class type.
+// Unknown or truncated items are reported by class type only.
+func ParseShellItem(data []byte, codePage *CodePage) ShellItem {
+	var shellItem ShellItem
+	if len(data) == 0 {
+		shellItem.ClassTypeName = "Unknown"
+		return shellItem
+	}
+	shellItem.ClassType = data[0]
+
+	switch {
+	case shellItem.ClassType == ShellItemClassTypeRootFolder && len(data) >= RootFolderShellItemSize:
+		shellItem.ClassTypeName = "RootFolder"
+		shellItem.RootFolder = parseRootFolderShellItem(data)
+	case shellItem.ClassType&ShellItemClassTypeMask == ShellItemClassTypeVolume:
+		shellItem.ClassTypeName = "Volume"
+		shellItem.Volume = parseVolumeShellItem(data, codePage)
+	case shellItem.ClassType&ShellItemClassTypeMask == ShellItemClassTypeFileEntry && len(data) > FileEntryShellItemPrimaryNameOffset:
+		shellItem.ClassTypeName = "FileEntry"
+		shellItem.FileEntry = parseFileEntryShellItem(data, codePage)
+	case shellItem.ClassType&ShellItemClassTypeMask == ShellItemClassTypeNetworkLocation && len(data) >= NetworkLocationShellItemSizeMin:
+		shellItem.ClassTypeName = "NetworkLocation"
+		shellItem.NetworkLocation = parseNetworkLocationShellItem(data, codePage)
+	default:
+		shellItem.ClassTypeName = "Unknown"
+	}
+	return shellItem
+}
+
+func parseRootFolderShellItem(data []byte) *RootFolderShellItem {
+	rootFolder := RootFolderShellItem{SortIndex: data[1]}
+	copy(rootFolder.ShellFolderID[:], data[2:RootFolderShellItemSize])
+	rootFolder.ShellFolderName = shellFolderNames[rootFolder.ShellFolderID]
+	return &rootFolder
+}
+
+func parseVolumeShellItem(data []byte, codePage *CodePage) *VolumeShellItem {
+	volume := VolumeShellItem{Flags: data[0] & 0x0F}
+	if volume.Flags == VolumeShellItemHasShellFolderID && len(data) >= VolumeShellItemShellFolderIDOffset+16 {
+		var shellFolderID GUID
+		copy(shellFolderID[:], data[VolumeShellItemShellFolderIDOffset:])
+		volume.ShellFolderID = &shellFolderID
+		volume.ShellFolderName = shellFolderNames[shellFolderID]
+		return &volume
+	}
+	if volume.Flags&VolumeShellItemHasName != 0 {
+		volume.Name, _ = cutZeroTerminatedANSI(data[1:], codePage)
+	}
+	return &volume
+}
+
+func parseFileEntryShellItem(data []byte, codePage *CodePage) *FileEntryShellItem {
+	flags := data[0] & 0x0F
+	fileEntry := FileEntryShellItem{
+		Flags:               flags,
+		IsDirectory:         flags&FileEntryShellItemIsDirectory != 0,
+		IsFile:              flags&FileEntryShellItemIsFile != 0,
+		IsUnicode:           flags&FileEntryShellItemIsUnicode != 0,
+		FileSize:            binary.LittleEndian.Uint32(data[2:]),
+		ModificationTimeRaw: binary.LittleEndian.Uint32(data[6:]),
+		FileAttributes:      binary.LittleEndian.Uint16(data[10:]),
+	}
+	fileEntry.ModificationTime = fatToTime(fileEntry.ModificationTimeRaw)
+	fileEntry.FileAttributesParsed = ParseFileAttributes(uint32(fileEntry.FileAttributes))
+	var n int
+	if fileEntry.IsUnicode {
+		fileEntry.PrimaryName, n = cutZeroTerminatedUnicode(data[FileEntryShellItemPrimaryNameOffset:])
+	} else {
+		fileEntry.PrimaryName, n = cutZeroTerminatedANSI(data[FileEntryShellItemPrimaryNameOffset:], codePage)
+	}
+	fileEntry.ExtensionBlocks = ParseExtensionBlocks(data[FileEntryShellItemPrimaryNameOffset+n:], codePage)
+	return &fileEntry
+}
+
+// Name returns the long name of the 0xBEEF0004 extension block if present, the primary name otherwise.
+func (f *FileEntryShellItem) Name() string {
+	for _, extensionBlock := range f.ExtensionBlocks {
+		if extensionBlock.FileEntry != nil && extensionBlock.FileEntry.LongName != "" {
+			return extensionBlock.FileEntry.LongName
+		}
+	}
+	return f.PrimaryName
+}
+
+func parseNetworkLocationShellItem(data []byte, codePage *CodePage) *NetworkLocationShellItem {
+	networkLocation := NetworkLocationShellItem{Flags: data[2]}
+	rest := data[3:]
+	var n int
+	networkLocation.Location, n = cutZeroTerminatedANSI(rest, codePage)
+	rest = rest[n:]
+	if networkLocation.Flags&NetworkLocationShellItemHasDescription != 0 {
+		networkLocation.Description, n = cutZeroTerminatedANSI(rest, codePage)
+		rest = rest[n:]
+	}
+	if networkLocation.Flags&NetworkLocationShellItemHasComments != 0 {
+		networkLocation.Comments, _ = cutZeroTerminatedANSI(rest, codePage)
+	}
+	return &networkLocation
+}
+
+// cutZeroTerminatedANSI decodes a null-terminated ANSI string and returns the number of bytes used, terminator included.
+func cutZeroTerminatedANSI(b []byte, codePage *CodePage) (string, int) {
+	i := bytes.IndexByte(b, 0x0)
+	if i < 0 {
+		return codePage.Decode(b), len(b)
+	}
+	return codePage.Decode(b[:i]), i + 1
+}
+
+// cutZeroTerminatedUnicode decodes a null-terminated UTF-16LE string and returns the number of bytes used, terminator included.
+func cutZeroTerminatedUnicode(b []byte) (string, int) {
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0x0 && b[i+1] == 0x0 {
+			return decodeUTF16LE(b[:i]), i + 2
+		}
+	}
+	return decodeUTF16LE(b), len(b)
+}
+
+// fatToTime converts a FAT date and time, date in the low word, to time, nil if unset or invalid.
+func fatToTime(fat uint32) *time.Time {
+	date := uint16(fat)
+	tm := uint16(fat >> 16)
+	if date == 0 && tm == 0 {
+		return nil
+	}
+	year := int(date>>9) + 1980
+	month := time.Month((date >> 5) & 0x0F)
+	day := int(date & 0x1F)
+	hour := int(tm >> 11)
+	minute := int((tm >> 5) & 0x3F)
+	second := int(tm&0x1F) * 2
+	if month < time.January || month > time.December || day == 0 || hour > 23 || minute > 59 || second > 59 {
+		return nil
+	}
+	t := time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+	return &t
+}
+
+// IDListPath composes the path the shell items point to, empty if no item contributes a path.
+func IDListPath(itemIDs []ItemID) string {
+	parts := idListParts(itemIDs)
+	values := make([]string, len(parts))
+	for i, part := range parts {
+		values[i] = part.Value
+	}
+	if len(values) == 1 && strings.HasSuffix(values[0], ":") {
+		return values[0] + "\\"
+	}
+	return strings.Join(values, "\\")
+}
+
+// idListParts lists the names that make up the path of an IDList, a volume or network location restarts it.
+func idListParts(itemIDs []ItemID) []TargetPart {
+	var parts []TargetPart
+	for i, itemID := range itemIDs {
+		shellItem := itemID.ShellItem
+		source := fmt.Sprintf("ItemIDs[%v]", i)
+		switch {
+		case shellItem.Volume != nil && shellItem.Volume.Name != "":
+			parts = []TargetPart{{Value: strings.TrimSuffix(shellItem.Volume.Name, "\\"), Source: source}}
+		case shellItem.NetworkLocation != nil:
+			parts = []TargetPart{{Value: shellItem.NetworkLocation.Location, Source: source}}
+		case shellItem.FileEntry != nil:
+			parts = append(parts, TargetPart{Value: shellItem.FileEntry.Name(), Source: source})
+		}
+	}
+	return parts
+}
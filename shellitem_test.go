@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ParseShellItem(t *testing.T) {
+	modified := time.Date(2019, 12, 7, 9, 9, 4, 0, time.UTC)
+	type args struct {
+		data []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want ShellItem
+	}{
+		{
+			name: "volume",
+			args: args{data: []byte{0x2F, 'D', ':', '\\', 0x00, 0x00, 0x00}},
+			want: ShellItem{ClassType: 0x2F, ClassTypeName: "Volume", Volume: &VolumeShellItem{Flags: 0x0F, Name: "D:\\"}},
+		},
+		{
+			name: "unicode file entry",
+			args: args{data: []byte{
+				0x36, 0x00, 0x10, 0x00, 0x00, 0x00, 0x87, 0x4F, 0x22, 0x49, 0x20, 0x00,
+				'a', 0x00, 0x1F, 0x04, 0x00, 0x00,
+			}},
+			want: ShellItem{ClassType: 0x36, ClassTypeName: "FileEntry", FileEntry: &FileEntryShellItem{
+				Flags:                0x06,
+				IsFile:               true,
+				IsUnicode:            true,
+				FileSize:             0x10,
+				ModificationTimeRaw:  0x49224F87,
+				ModificationTime:     &modified,
+				FileAttributes:       0x20,
+				FileAttributesParsed: FileAttributesParsed{FileAttributeArchive: true},
+				PrimaryName:          "aП",
+			}},
+		},
+		{
+			name: "network share",
+			args: args{data: []byte{
+				0x43, 0x01, 0x80, '\\', '\\', 's', '\\', 'x', 0x00, 'd', 0x00, 0x00, 0x00,
+			}},
+			want: ShellItem{ClassType: 0x43, ClassTypeName: "NetworkLocation", NetworkLocation: &NetworkLocationShellItem{
+				Flags:       0x80,
+				Location:    "\\\\s\\x",
+				Description: "d",
+			}},
+		},
+		{
+			name: "unknown class type",
+			args: args{data: []byte{0x71, 0x00}},
+			want: ShellItem{ClassType: 0x71, ClassTypeName: "Unknown"},
+		},
+		{
+			name: "truncated root folder",
+			args: args{data: []byte{0x1F, 0x50, 0xE0}},
+			want: ShellItem{ClassType: 0x1F, ClassTypeName: "Unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseShellItem(tt.args.data, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseShellItem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fatToTime(t *testing.T) {
+	want := time.Date(2023, 3, 14, 10, 20, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		fat  uint32
+		want *time.Time
+	}{
+		{name: "valid", fat: 0x528F566E, want: &want},
+		{name: "zero", fat: 0, want: nil},
+		{name: "invalid month", fat: 0x528F5600, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fatToTime(tt.fat); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fatToTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_IDListPath(t *testing.T) {
+	fileEntry := func(name string) ItemID {
+		return ItemID{ShellItem: ShellItem{FileEntry: &FileEntryShellItem{PrimaryName: name}}}
+	}
+	tests := []struct {
+		name    string
+		itemIDs []ItemID
+		want    string
+	}{
+		{
+			name:    "drive root",
+			itemIDs: []ItemID{{ShellItem: ShellItem{Volume: &VolumeShellItem{Name: "C:\\"}}}},
+			want:    "C:\\",
+		},
+		{
+			name: "file on drive",
+			itemIDs: []ItemID{
+				{ShellItem: ShellItem{RootFolder: &RootFolderShellItem{}}},
+				{ShellItem: ShellItem{Volume: &VolumeShellItem{Name: "C:\\"}}},
+				fileEntry("Windows"),
+				fileEntry("notepad.exe"),
+			},
+			want: "C:\\Windows\\notepad.exe",
+		},
+		{
+			name: "file on share",
+			itemIDs: []ItemID{
+				{ShellItem: ShellItem{NetworkLocation: &NetworkLocationShellItem{Location: "\\\\server\\share"}}},
+				fileEntry("x"),
+			},
+			want: "\\\\server\\share\\x",
+		},
+		{
+			name:    "no path",
+			itemIDs: []ItemID{{ShellItem: ShellItem{RootFolder: &RootFolderShellItem{}}}},
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IDListPath(tt.itemIDs); got != tt.want {
+				t.Errorf("IDListPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
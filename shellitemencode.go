@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Shell folder CLSIDs and sort indexes of the root folder items IDListForPath writes
+var (
+	myComputerShellFolderID = mustParseGUID("20D04FE0-3AEA-1069-A2D8-08002B30309D")
+	networkShellFolderID    = mustParseGUID("F02C1A0D-BE21-4350-88B0-7367FC96EF3C")
+)
+
+const (
+	myComputerSortIndex uint8 = 0x50
+	networkSortIndex    uint8 = 0x58
+)
+
+// ShellItem layout written by EncodeShellItem and IDListForPath
+const (
+	VolumeShellItemSize               int    = 23
+	FileEntryExtensionBlockVersion    uint16 = 9
+	FileEntryExtensionBlockIdentifier uint16 = 0x2E // Windows 8.1, 10, 11
+)
+
+// networkLocationShellItem* describe the UNC share item of IDListForPath
+const (
+	networkLocationShellItemClassTypeUNC uint8 = 0xC3
+	networkLocationShellItemDescription        = "Microsoft Network"
+)
+
+// PathItem is the metadata of a directory or file for IDListForPath, zero values are left unset.
+// FAT timestamps keep the wall clock of the time without its location.
+type PathItem struct {
+	FileSize         uint32
+	FileAttributes   uint16
+	ModificationTime time.Time
+	CreationTime     time.Time
+	AccessTime       time.Time
+	ShortName        string // 8.3 name stored as PrimaryName, the long name if empty
+}
+
+// IDListForPath synthesizes the ItemIDs of a local path like C:\dir\file.exe or a UNC path like \\server\share\file.exe:
+// a My Computer or Network root folder item, a volume or network location item and a file entry item with a
+// 0xBEEF0004 extension block per name. items holds the metadata of the names in order and may be shorter.
+// The last name is a file unless the path ends with a backslash or its FileAttributes mark a directory.
+func IDListForPath(path string, items []PathItem, codePage *CodePage) ([]ItemID, error) {
+	path = strings.ReplaceAll(path, "/", `\`)
+	var shellItems []ShellItem
+	var names []string
+	switch {
+	case len(path) >= 3 && path[1] == ':' && path[2] == '\\' && (path[0] >= 'A' && path[0] <= 'Z' || path[0] >= 'a' && path[0] <= 'z'):
+		shellItems = append(shellItems, rootFolderItem(myComputerShellFolderID, myComputerSortIndex), ShellItem{
+			ClassType: ShellItemClassTypeVolume | 0x0F,
+			Volume:    &VolumeShellItem{Flags: 0x0F, Name: strings.ToUpper(path[:1]) + `:\`},
+		})
+		names = splitPathNames(path[3:])
+	case strings.HasPrefix(path, `\\`):
+		parts := strings.SplitN(path[2:], `\`, 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.New("UNC path must start with \\\\server\\share")
+		}
+		shellItems = append(shellItems, rootFolderItem(networkShellFolderID, networkSortIndex), ShellItem{
+			ClassType: networkLocationShellItemClassTypeUNC,
+			NetworkLocation: &NetworkLocationShellItem{
+				Flags:       NetworkLocationShellItemHasDescription | 0x01,
+				Location:    `\\` + parts[0] + `\` + parts[1],
+				Description: networkLocationShellItemDescription,
+			},
+		})
+		if len(parts) == 3 {
+			names = splitPathNames(parts[2])
+		}
+	default:
+		return nil, fmt.Errorf("path %q is neither a local nor a UNC path", path)
+	}
+	if len(items) > len(names) {
+		return nil, fmt.Errorf("%v PathItems for %v names", len(items), len(names))
+	}
+
+	for i, name := range names {
+		var item PathItem
+		if i < len(items) {
+			item = items[i]
+		}
+		isDirectory := i < len(names)-1 || strings.HasSuffix(path, `\`) || item.FileAttributes&uint16(FileAttributeDirectory) != 0
+		shellItems = append(shellItems, fileEntryItem(name, item, isDirectory, codePage))
+	}
+
+	var idList []byte
+	for i, shellItem := range shellItems {
+		data, err := EncodeShellItem(shellItem, codePage)
+		if err != nil {
+			return nil, fmt.Errorf("ItemIDs[%d]: %w", i, err)
+		}
+		idList = putUint16(idList, uint16(len(data)+2))
+		idList = append(idList, data...)
+	}
+	return ParseIDList(idList, codePage)
+}
+
+// splitPathNames splits the names of a path below its root, empty names are dropped.
+func splitPathNames(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, `\`) {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func rootFolderItem(shellFolderID GUID, sortIndex uint8) ShellItem {
+	return ShellItem{
+		ClassType:  ShellItemClassTypeRootFolder,
+		RootFolder: &RootFolderShellItem{SortIndex: sortIndex, ShellFolderID: shellFolderID},
+	}
+}
+
+// fileEntryItem returns a file entry shell item with a version 9 0xBEEF0004 extension block holding the long name.
+func fileEntryItem(name string, item PathItem, isDirectory bool, codePage *CodePage) ShellItem {
+	flags := FileEntryShellItemIsFile
+	fileAttributes := item.FileAttributes
+	if isDirectory {
+		flags = FileEntryShellItemIsDirectory
+		fileAttributes |= uint16(FileAttributeDirectory)
+	}
+	primaryName := item.ShortName
+	if primaryName == "" {
+		primaryName = name
+	}
+	var primaryNameSize int
+	if encoded, err := codePage.Encode(primaryName); err == nil {
+		primaryNameSize = len(encoded) + 1
+	} else {
+		flags |= FileEntryShellItemIsUnicode
+		primaryNameSize = len(encodeUTF16LE(primaryName)) + 2
+	}
+
+	return ShellItem{
+		ClassType: ShellItemClassTypeFileEntry | flags,
+		FileEntry: &FileEntryShellItem{
+			Flags:               flags,
+			FileSize:            item.FileSize,
+			ModificationTimeRaw: timeToFAT(item.ModificationTime),
+			FileAttributes:      fileAttributes,
+			PrimaryName:         primaryName,
+			ExtensionBlocks: []ExtensionBlock{{
+				Version:   FileEntryExtensionBlockVersion,
+				Signature: FileEntryExtensionBlockSignature,
+				FileEntry: &FileEntryExtensionBlock{
+					CreationTimeRaw:   timeToFAT(item.CreationTime),
+					AccessTimeRaw:     timeToFAT(item.AccessTime),
+					Identifier:        FileEntryExtensionBlockIdentifier,
+					NTFSFileReference: &NTFSFileReference{},
+					LongName:          name,
+					// offset of the extension block in the ItemID, ItemIDSize included
+					FirstExtensionBlockVersionOffset: uint16(2 + FileEntryShellItemPrimaryNameOffset + (primaryNameSize+1)&^1),
+				},
+			}},
+		},
+	}
+}
+
+// EncodeShellItem serializes the ItemIDData of a shell item decoded by ParseShellItem. ClassType is written as is,
+// sizes are recomputed and the names and fields that ParseShellItem composes are ignored.
+func EncodeShellItem(shellItem ShellItem, codePage *CodePage) ([]byte, error) {
+	data := []byte{shellItem.ClassType}
+	switch {
+	case shellItem.RootFolder != nil:
+		data = append(data, shellItem.RootFolder.SortIndex)
+		return append(data, shellItem.RootFolder.ShellFolderID[:]...), nil
+	case shellItem.Volume != nil:
+		if shellItem.Volume.ShellFolderID != nil {
+			data = append(data, make([]byte, VolumeShellItemShellFolderIDOffset-1)...)
+			return append(data, shellItem.Volume.ShellFolderID[:]...), nil
+		}
+		if shellItem.Volume.Flags&VolumeShellItemHasName != 0 {
+			name, err := encodeANSIString(shellItem.Volume.Name, "", true, codePage)
+			if err != nil {
+				return nil, fmt.Errorf("Volume Name: %w", err)
+			}
+			data = append(data, name...)
+		}
+		if len(data) < VolumeShellItemSize {
+			data = append(data, make([]byte, VolumeShellItemSize-len(data))...)
+		}
+		return data, nil
+	case shellItem.FileEntry != nil:
+		return encodeFileEntryShellItem(data, shellItem.FileEntry, codePage)
+	case shellItem.NetworkLocation != nil:
+		networkLocation := shellItem.NetworkLocation
+		data = append(data, 0x0, networkLocation.Flags)
+		for _, field := range []struct {
+			name    string
+			present bool
+			value   string
+		}{
+			{"Location", true, networkLocation.Location},
+			{"Description", networkLocation.Flags&NetworkLocationShellItemHasDescription != 0, networkLocation.Description},
+			{"Comments", networkLocation.Flags&NetworkLocationShellItemHasComments != 0, networkLocation.Comments},
+		} {
+			if !field.present {
+				continue
+			}
+			str, err := encodeANSIString(field.value, "", true, codePage)
+			if err != nil {
+				return nil, fmt.Errorf("NetworkLocation %v: %w", field.name, err)
+			}
+			data = append(data, str...)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("shell item of class type 0x%02X is not decoded, use ItemIDDataBase64", shellItem.ClassType)
+}
+
+func encodeFileEntryShellItem(data []byte, fileEntry *FileEntryShellItem, codePage *CodePage) ([]byte, error) {
+	data = append(data, 0x0)
+	data = putUint32(data, fileEntry.FileSize)
+	data = putUint32(data, fileEntry.ModificationTimeRaw)
+	data = putUint16(data, fileEntry.FileAttributes)
+	var primaryName []byte
+	var err error
+	if fileEntry.Flags&FileEntryShellItemIsUnicode != 0 {
+		primaryName, err = encodeUnicodeString(fileEntry.PrimaryName, "", true)
+	} else {
+		primaryName, err = encodeANSIString(fileEntry.PrimaryName, "", true, codePage)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FileEntry PrimaryName: %w", err)
+	}
+	data = append(data, primaryName...)
+	if len(data)%2 != 0 {
+		// extension blocks start 2-byte aligned in the ItemID
+		data = append(data, 0x0)
+	}
+	for i, extensionBlock := range fileEntry.ExtensionBlocks {
+		block, err := EncodeExtensionBlock(extensionBlock, codePage)
+		if err != nil {
+			return nil, fmt.Errorf("FileEntry ExtensionBlocks[%d]: %w", i, err)
+		}
+		data = append(data, block...)
+	}
+	return data, nil
+}
+
+// EncodeExtensionBlock serializes an extension block decoded by ParseExtensionBlocks, Size is recomputed.
+func EncodeExtensionBlock(extensionBlock ExtensionBlock, codePage *CodePage) ([]byte, error) {
+	var body []byte
+	switch {
+	case extensionBlock.FileEntry != nil:
+		var err error
+		body, err = encodeFileEntryExtensionBlock(extensionBlock.FileEntry, extensionBlock.Version, codePage)
+		if err != nil {
+			return nil, err
+		}
+	case extensionBlock.ShellFolderID != nil:
+		body = append(extensionBlock.ShellFolderID[:], 0x0, 0x0)
+	default:
+		var err error
+		body, err = base64.StdEncoding.DecodeString(extensionBlock.DataBase64)
+		if err != nil {
+			return nil, fmt.Errorf("DataBase64: %w", err)
+		}
+	}
+	size := ExtensionBlockHeaderSize + len(body)
+	if size > 0xFFFF {
+		return nil, fmt.Errorf("Size 0x%X out of range", size)
+	}
+	data := putUint16(nil, uint16(size))
+	data = putUint16(data, extensionBlock.Version)
+	data = putUint32(data, extensionBlock.Signature)
+	return append(data, body...), nil
+}
+
+// encodeFileEntryExtensionBlock serializes a 0xBEEF0004 block after its header, see parseFileEntryExtensionBlock.
+func encodeFileEntryExtensionBlock(fileEntry *FileEntryExtensionBlock, version uint16, codePage *CodePage) ([]byte, error) {
+	data := putUint32(nil, fileEntry.CreationTimeRaw)
+	data = putUint32(data, fileEntry.AccessTimeRaw)
+	data = putUint16(data, fileEntry.Identifier)
+	if version >= FileEntryExtensionBlockNTFSVersionMin {
+		var fileReference uint64
+		if fileEntry.NTFSFileReference != nil {
+			fileReference = fileEntry.NTFSFileReference.FileReference
+		}
+		data = putUint16(data, 0)
+		data = putUint32(data, uint32(fileReference))
+		data = putUint32(data, uint32(fileReference>>32))
+		data = append(data, make([]byte, 8)...)
+	}
+	if version >= 3 {
+		data = putUint16(data, fileEntry.LongStringSize)
+	}
+	if version >= 9 {
+		data = putUint32(data, 0)
+	}
+	if version >= 8 {
+		data = putUint32(data, 0)
+	}
+	longName, _ := encodeUnicodeString(fileEntry.LongName, "", true)
+	data = append(data, longName...)
+	if version >= 3 && fileEntry.LongStringSize > 0 {
+		var localizedName []byte
+		var err error
+		if version >= 7 {
+			localizedName, err = encodeUnicodeString(fileEntry.LocalizedName, "", true)
+		} else {
+			localizedName, err = encodeANSIString(fileEntry.LocalizedName, "", true, codePage)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("LocalizedName: %w", err)
+		}
+		data = append(data, localizedName...)
+	}
+	return putUint16(data, fileEntry.FirstExtensionBlockVersionOffset), nil
+}
+
+// timeToFAT converts a time to a FAT date and time, date in the low word, 0 for the zero time
+// and times FAT cannot represent.
+func timeToFAT(t time.Time) uint32 {
+	if t.IsZero() || t.Year() < 1980 || t.Year() > 2107 {
+		return 0
+	}
+	date := uint32(t.Year()-1980)<<9 | uint32(t.Month())<<5 | uint32(t.Day())
+	tm := uint32(t.Hour())<<11 | uint32(t.Minute())<<5 | uint32(t.Second()/2)
+	return tm<<16 | date
+}
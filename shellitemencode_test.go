@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func Test_EncodeShellItem(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shellLinkParsed, err := ParseData(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, itemID := range shellLinkParsed.LinkTargetIDList.IDListData.ItemIDs {
+		t.Run(itemID.ShellItem.ClassTypeName, func(t *testing.T) {
+			got, err := EncodeShellItem(itemID.ShellItem, nil)
+			if err != nil {
+				t.Fatalf("EncodeShellItem() error = %v", err)
+			}
+			if !bytes.Equal(got, itemID.ItemIDData) {
+				t.Errorf("EncodeShellItem() = % X, want % X", got, itemID.ItemIDData)
+			}
+		})
+	}
+
+	if _, err := EncodeShellItem(ShellItem{ClassType: 0x71, ClassTypeName: "Unknown"}, nil); err == nil {
+		t.Errorf("EncodeShellItem() of an unknown item, want error")
+	}
+}
+
+func Test_IDListForPath(t *testing.T) {
+	modified := time.Date(2023, 3, 14, 10, 20, 30, 0, time.UTC)
+	created := time.Date(2019, 12, 7, 9, 9, 2, 0, time.UTC)
+	tests := []struct {
+		name     string
+		path     string
+		items    []PathItem
+		wantPath string
+		check    func(*testing.T, []ItemID)
+		wantErr  bool
+	}{
+		{
+			name: "local file",
+			path: `c:\Program Files\App\app.exe`,
+			items: []PathItem{{}, {ShortName: "App"}, {
+				FileSize:         196608,
+				FileAttributes:   uint16(FileAttributeArchive),
+				ModificationTime: modified,
+				CreationTime:     created,
+				ShortName:        "APP~1.EXE",
+			}},
+			wantPath: `C:\Program Files\App\app.exe`,
+			check: func(t *testing.T, itemIDs []ItemID) {
+				if len(itemIDs) != 5 || itemIDs[0].ShellItem.RootFolder.ShellFolderName != "My Computer" {
+					t.Fatalf("ItemIDs = %+v", itemIDs)
+				}
+				directory := itemIDs[2].ShellItem.FileEntry
+				if !directory.IsDirectory || directory.FileAttributes != uint16(FileAttributeDirectory) || directory.ModificationTime != nil {
+					t.Errorf("FileEntry = %+v", directory)
+				}
+				file := itemIDs[4].ShellItem.FileEntry
+				if !file.IsFile || file.PrimaryName != "APP~1.EXE" || file.FileSize != 196608 || !file.FileAttributesParsed.FileAttributeArchive {
+					t.Errorf("FileEntry = %+v", file)
+				}
+				if file.ModificationTime == nil || !file.ModificationTime.Equal(modified) {
+					t.Errorf("ModificationTime = %v, want %v", file.ModificationTime, modified)
+				}
+				extension := file.ExtensionBlocks[0].FileEntry
+				if extension.CreationTime == nil || !extension.CreationTime.Equal(created) || extension.AccessTime != nil || extension.IdentifierName != "Windows 8.1, 10, 11" {
+					t.Errorf("FileEntryExtensionBlock = %+v", extension)
+				}
+				if extension.FirstExtensionBlockVersionOffset != 2+12+10 {
+					t.Errorf("FirstExtensionBlockVersionOffset = %v", extension.FirstExtensionBlockVersionOffset)
+				}
+			},
+		},
+		{
+			name:     "directory",
+			path:     `D:\Data\`,
+			wantPath: `D:\Data`,
+			check: func(t *testing.T, itemIDs []ItemID) {
+				if !itemIDs[2].ShellItem.FileEntry.IsDirectory {
+					t.Errorf("FileEntry = %+v", itemIDs[2].ShellItem.FileEntry)
+				}
+			},
+		},
+		{
+			name:     "drive",
+			path:     `E:\`,
+			wantPath: `E:\`,
+		},
+		{
+			name:     "UNC path",
+			path:     `\\server\share\dir\report.txt`,
+			wantPath: `\\server\share\dir\report.txt`,
+			check: func(t *testing.T, itemIDs []ItemID) {
+				networkLocation := itemIDs[1].ShellItem.NetworkLocation
+				if itemIDs[0].ShellItem.RootFolder.ShellFolderName != "Network" || networkLocation.Description != "Microsoft Network" {
+					t.Errorf("ItemIDs = %+v, %+v", itemIDs[0].ShellItem.RootFolder, networkLocation)
+				}
+			},
+		},
+		{
+			name:     "name outside the code page",
+			path:     `C:\Отчёт.docx`,
+			wantPath: `C:\Отчёт.docx`,
+			check: func(t *testing.T, itemIDs []ItemID) {
+				if file := itemIDs[2].ShellItem.FileEntry; !file.IsUnicode || file.PrimaryName != "Отчёт.docx" {
+					t.Errorf("FileEntry = %+v", file)
+				}
+			},
+		},
+		{
+			name:    "relative path",
+			path:    `dir\app.exe`,
+			wantErr: true,
+		},
+		{
+			name:    "UNC path without share",
+			path:    `\\server`,
+			wantErr: true,
+		},
+		{
+			name:    "more items than names",
+			path:    `C:\app.exe`,
+			items:   []PathItem{{}, {}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IDListForPath(tt.path, tt.items, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IDListForPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if path := IDListPath(got); path != tt.wantPath {
+				t.Errorf("IDListPath() = %v, want %v", path, tt.wantPath)
+			}
+			for i, itemID := range got {
+				data, err := EncodeShellItem(itemID.ShellItem, nil)
+				if err != nil || !bytes.Equal(data, itemID.ItemIDData) {
+					t.Errorf("EncodeShellItem(ItemIDs[%d]) = % X, %v, want % X", i, data, err, itemID.ItemIDData)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, got)
+			}
+		})
+	}
+}
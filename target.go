@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Target is the link target resolved from every structure that records it, in the order Windows uses.
+type Target struct {
+	Path       string            `json:"Path"`
+	Source     string            `json:"Source"`
+	Conflict   bool              `json:"Conflict"` // The absolute candidate paths disagree
+	Candidates []TargetCandidate `json:"Candidates"`
+}
+
+// TargetCandidate is the target path according to a single structure.
+type TargetCandidate struct {
+	Source  string       `json:"Source"`
+	Path    string       `json:"Path"`
+	Parts   []TargetPart `json:"Parts"`
+	Ignored string       `json:"Ignored,omitempty"` // Reason the candidate is not used by Windows
+}
+
+// TargetPart is a piece of a candidate path and the field it was read from.
+type TargetPart struct {
+	Value  string `json:"Value"`
+	Source string `json:"Source"`
+}
+
+// ResolveTarget lists the target candidates of a parsed link by precedence: the EnvironmentVariableDataBlock
+// if LinkFlag 'PreferEnvironmentPath' is set, the VistaAndAboveIDListDataBlock, the LinkTargetIDList,
+// the LinkInfo unless LinkFlag 'ForceNoLinkInfo' is set, the EnvironmentVariableDataBlock, the
+// System.Link.TargetParsingPath property and finally the RelativePath. Returns nil without candidates.
+func ResolveTarget(shellLink *ShellLinkParsed) *Target {
+	var environment, vistaAndAbove, propertyStore *TargetCandidate
+	for _, block := range shellLink.ExtraData {
+		switch {
+		case block.EnvironmentVariableDataBlock != nil:
+			environment = environmentCandidate(block.EnvironmentVariableDataBlock)
+		case block.VistaAndAboveIDListDataBlock != nil:
+			vistaAndAbove = idListCandidate("VistaAndAboveIDListDataBlock", block.VistaAndAboveIDListDataBlock.IDListData.ItemIDs)
+		case block.PropertyStoreDataBlock != nil:
+			propertyStore = propertyStoreCandidate(block.PropertyStoreDataBlock)
+		}
+	}
+
+	var candidates []*TargetCandidate
+	if shellLink.LinkFlagsParsed.PreferEnvironmentPath {
+		candidates = append(candidates, environment)
+	}
+	candidates = append(candidates, vistaAndAbove)
+	if shellLink.LinkTargetIDList != nil {
+		candidates = append(candidates, idListCandidate("LinkTargetIDList", shellLink.LinkTargetIDList.IDListData.ItemIDs))
+	}
+	if shellLink.LinkInfo != nil {
+		linkInfo := linkInfoCandidate(shellLink.LinkInfo)
+		if linkInfo != nil && shellLink.LinkFlagsParsed.ForceNoLinkInfo {
+			linkInfo.Ignored = "LinkFlag 'ForceNoLinkInfo' is set"
+		}
+		candidates = append(candidates, linkInfo)
+	}
+	if !shellLink.LinkFlagsParsed.PreferEnvironmentPath {
+		candidates = append(candidates, environment)
+	}
+	candidates = append(candidates, propertyStore)
+	if shellLink.StringData.RelativePath != "" {
+		candidates = append(candidates, &TargetCandidate{
+			Source: "StringData",
+			Path:   shellLink.StringData.RelativePath,
+			Parts:  []TargetPart{{Value: shellLink.StringData.RelativePath, Source: "StringData.RelativePath"}},
+		})
+	}
+
+	var target Target
+	var absolutePath string
+	for _, candidate := range candidates {
+		if candidate == nil || candidate.Path == "" {
+			continue
+		}
+		target.Candidates = append(target.Candidates, *candidate)
+		if candidate.Ignored != "" {
+			continue
+		}
+		if target.Source == "" {
+			target.Path = candidate.Path
+			target.Source = candidate.Source
+		}
+		if isAbsolutePath(candidate.Path) {
+			if absolutePath == "" {
+				absolutePath = candidate.Path
+			} else if !strings.EqualFold(strings.TrimSuffix(absolutePath, "\\"), strings.TrimSuffix(candidate.Path, "\\")) {
+				target.Conflict = true
+			}
+		}
+	}
+	if len(target.Candidates) == 0 {
+		return nil
+	}
+	return &target
+}
+
+func environmentCandidate(block *EnvironmentVariableDataBlock) *TargetCandidate {
+	if block.TargetUnicode != "" {
+		return singlePartCandidate("EnvironmentVariableDataBlock", block.TargetUnicode, "EnvironmentVariableDataBlock.TargetUnicode")
+	}
+	return singlePartCandidate("EnvironmentVariableDataBlock", block.TargetAnsi, "EnvironmentVariableDataBlock.TargetAnsi")
+}
+
+func idListCandidate(source string, itemIDs []ItemID) *TargetCandidate {
+	parts := idListParts(itemIDs)
+	for i := range parts {
+		parts[i].Source = source + "." + parts[i].Source
+	}
+	return &TargetCandidate{
+		Source: source,
+		Path:   IDListPath(itemIDs),
+		Parts:  parts,
+	}
+}
+
+// linkInfoCandidate joins the LocalBasePath, or the NetName of a network target, with the CommonPathSuffix.
+func linkInfoCandidate(linkInfo *LinkInfo) *TargetCandidate {
+	candidate := TargetCandidate{Source: "LinkInfo"}
+	switch {
+	case linkInfo.LocalBasePathUnicode != "":
+		candidate.Parts = append(candidate.Parts, TargetPart{Value: linkInfo.LocalBasePathUnicode, Source: "LinkInfo.LocalBasePathUnicode"})
+	case linkInfo.LocalBasePath != "":
+		candidate.Parts = append(candidate.Parts, TargetPart{Value: linkInfo.LocalBasePath, Source: "LinkInfo.LocalBasePath"})
+	case linkInfo.CommonNetworkRelativeLink != nil && linkInfo.CommonNetworkRelativeLink.NetNameUnicode != "":
+		candidate.Parts = append(candidate.Parts, TargetPart{Value: linkInfo.CommonNetworkRelativeLink.NetNameUnicode, Source: "LinkInfo.CommonNetworkRelativeLink.NetNameUnicode"})
+	case linkInfo.CommonNetworkRelativeLink != nil && linkInfo.CommonNetworkRelativeLink.NetName != "":
+		candidate.Parts = append(candidate.Parts, TargetPart{Value: linkInfo.CommonNetworkRelativeLink.NetName, Source: "LinkInfo.CommonNetworkRelativeLink.NetName"})
+	default:
+		return nil
+	}
+	switch {
+	case linkInfo.CommonPathSuffixUnicode != "":
+		candidate.Parts = append(candidate.Parts, TargetPart{Value: linkInfo.CommonPathSuffixUnicode, Source: "LinkInfo.CommonPathSuffixUnicode"})
+	case linkInfo.CommonPathSuffix != "":
+		candidate.Parts = append(candidate.Parts, TargetPart{Value: linkInfo.CommonPathSuffix, Source: "LinkInfo.CommonPathSuffix"})
+	}
+
+	candidate.Path = candidate.Parts[0].Value
+	if len(candidate.Parts) > 1 {
+		candidate.Path = joinPath(candidate.Path, candidate.Parts[1].Value)
+	}
+	return &candidate
+}
+
+func propertyStoreCandidate(block *PropertyStoreDataBlock) *TargetCandidate {
+	for i, storage := range block.PropertyStorages {
+		for j, value := range storage.Values {
+			path, ok := value.Value.Value.(string)
+			if value.PropertyName == "System.Link.TargetParsingPath" && ok {
+				return singlePartCandidate("PropertyStoreDataBlock", path, fmt.Sprintf("PropertyStoreDataBlock.PropertyStorages[%v].Values[%v]", i, j))
+			}
+		}
+	}
+	return nil
+}
+
+func singlePartCandidate(source string, path string, partSource string) *TargetCandidate {
+	return &TargetCandidate{
+		Source: source,
+		Path:   path,
+		Parts:  []TargetPart{{Value: path, Source: partSource}},
+	}
+}
+
+// joinPath joins two path pieces with a single backslash, an empty suffix leaves the base unchanged.
+func joinPath(base string, suffix string) string {
+	if suffix == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "\\") + "\\" + strings.TrimPrefix(suffix, "\\")
+}
+
+// isAbsolutePath reports whether a path starts with a drive letter or is a UNC path.
+func isAbsolutePath(path string) bool {
+	if strings.HasPrefix(path, "\\\\") {
+		return true
+	}
+	return len(path) >= 3 && path[1] == ':' && path[2] == '\\' &&
+		(path[0] >= 'A' && path[0] <= 'Z' || path[0] >= 'a' && path[0] <= 'z')
+}
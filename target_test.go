@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_ResolveTarget(t *testing.T) {
+	linkInfo := &LinkInfo{LocalBasePath: "C:\\Users\\", CommonPathSuffix: "a\\file.txt"}
+	networkLinkInfo := &LinkInfo{
+		CommonNetworkRelativeLink: &CommonNetworkRelativeLink{NetName: "\\\\server\\share"},
+		CommonPathSuffix:          "file.txt",
+	}
+	environment := ExtraDataBlock{EnvironmentVariableDataBlock: &EnvironmentVariableDataBlock{
+		TargetAnsi:    "%USERPROFILE%\\a\\file.txt",
+		TargetUnicode: "%USERPROFILE%\\a\\file.txt",
+	}}
+	linkInfoCandidate := TargetCandidate{
+		Source: "LinkInfo",
+		Path:   "C:\\Users\\a\\file.txt",
+		Parts: []TargetPart{
+			{Value: "C:\\Users\\", Source: "LinkInfo.LocalBasePath"},
+			{Value: "a\\file.txt", Source: "LinkInfo.CommonPathSuffix"},
+		},
+	}
+	environmentCandidate := TargetCandidate{
+		Source: "EnvironmentVariableDataBlock",
+		Path:   "%USERPROFILE%\\a\\file.txt",
+		Parts:  []TargetPart{{Value: "%USERPROFILE%\\a\\file.txt", Source: "EnvironmentVariableDataBlock.TargetUnicode"}},
+	}
+	tests := []struct {
+		name      string
+		shellLink ShellLinkParsed
+		want      *Target
+	}{
+		{
+			name:      "nothing recorded",
+			shellLink: ShellLinkParsed{},
+			want:      nil,
+		},
+		{
+			name:      "LinkInfo before environment",
+			shellLink: ShellLinkParsed{LinkInfo: linkInfo, ExtraData: []ExtraDataBlock{environment}},
+			want: &Target{
+				Path:       "C:\\Users\\a\\file.txt",
+				Source:     "LinkInfo",
+				Candidates: []TargetCandidate{linkInfoCandidate, environmentCandidate},
+			},
+		},
+		{
+			name: "PreferEnvironmentPath",
+			shellLink: ShellLinkParsed{
+				LinkFlagsParsed: LinkFlagsParsed{PreferEnvironmentPath: true},
+				LinkInfo:        linkInfo,
+				ExtraData:       []ExtraDataBlock{environment},
+			},
+			want: &Target{
+				Path:       "%USERPROFILE%\\a\\file.txt",
+				Source:     "EnvironmentVariableDataBlock",
+				Candidates: []TargetCandidate{environmentCandidate, linkInfoCandidate},
+			},
+		},
+		{
+			name: "ForceNoLinkInfo",
+			shellLink: ShellLinkParsed{
+				LinkFlagsParsed: LinkFlagsParsed{ForceNoLinkInfo: true},
+				LinkInfo:        linkInfo,
+				StringData:      StringData{RelativePath: ".\\a\\file.txt"},
+			},
+			want: &Target{
+				Path:   ".\\a\\file.txt",
+				Source: "StringData",
+				Candidates: []TargetCandidate{
+					{
+						Source:  "LinkInfo",
+						Path:    "C:\\Users\\a\\file.txt",
+						Parts:   linkInfoCandidate.Parts,
+						Ignored: "LinkFlag 'ForceNoLinkInfo' is set",
+					},
+					{
+						Source: "StringData",
+						Path:   ".\\a\\file.txt",
+						Parts:  []TargetPart{{Value: ".\\a\\file.txt", Source: "StringData.RelativePath"}},
+					},
+				},
+			},
+		},
+		{
+			name: "conflicting sources",
+			shellLink: ShellLinkParsed{
+				LinkTargetIDList: &LinkTargetIDList{IDListData: IDList{ItemIDs: []ItemID{
+					{ShellItem: ShellItem{Volume: &VolumeShellItem{Name: "D:\\"}}},
+					{ShellItem: ShellItem{FileEntry: &FileEntryShellItem{PrimaryName: "file.txt"}}},
+				}}},
+				LinkInfo: networkLinkInfo,
+			},
+			want: &Target{
+				Path:     "D:\\file.txt",
+				Source:   "LinkTargetIDList",
+				Conflict: true,
+				Candidates: []TargetCandidate{
+					{
+						Source: "LinkTargetIDList",
+						Path:   "D:\\file.txt",
+						Parts: []TargetPart{
+							{Value: "D:", Source: "LinkTargetIDList.ItemIDs[0]"},
+							{Value: "file.txt", Source: "LinkTargetIDList.ItemIDs[1]"},
+						},
+					},
+					{
+						Source: "LinkInfo",
+						Path:   "\\\\server\\share\\file.txt",
+						Parts: []TargetPart{
+							{Value: "\\\\server\\share", Source: "LinkInfo.CommonNetworkRelativeLink.NetName"},
+							{Value: "file.txt", Source: "LinkInfo.CommonPathSuffix"},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveTarget(&tt.shellLink); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveTarget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violation is a broken MUST rule of MS-SHLLINK.
+type Violation struct {
+	Reference string `json:"Reference"` // MS-SHLLINK section, e.g. "2.3.1"
+	Field     string `json:"Field"`     // e.g. "LinkInfo.VolumeID.VolumeIDSize"
+	Is        string `json:"Is"`
+	Expected  string `json:"Expected"`
+}
+
+// FileAttributes reserved bits, they MUST be zero
+const (
+	FileAttributeReserved1 uint32 = FileAttributeVolumeLabel
+	FileAttributeReserved2 uint32 = 0x00000040
+)
+
+// Validate checks the MUST rules of MS-SHLLINK that the parser does not enforce itself and returns
+// the violations in file order. Sections with parse warnings are only checked as far as they were decoded.
+func Validate(shellLinkParsed *ShellLinkParsed) []Violation {
+	var v validator
+	v.violations = []Violation{}
+	v.validateHeader(&shellLinkParsed.Header)
+	if shellLinkParsed.LinkTargetIDList != nil && !hasWarning(shellLinkParsed, "LinkTargetIDList") {
+		v.validateIDList("2.2.1", "LinkTargetIDList.IDList", shellLinkParsed.LinkTargetIDList.IDListData.ItemIDs, uint32(shellLinkParsed.LinkTargetIDList.IDListSize))
+	}
+	if shellLinkParsed.LinkInfo != nil {
+		v.validateLinkInfo(shellLinkParsed.LinkInfo)
+	}
+	for i, block := range shellLinkParsed.ExtraData {
+		v.validateExtraDataBlock(fmt.Sprintf("ExtraData[%d]", i), &block, !hasWarning(shellLinkParsed, fmt.Sprintf("ExtraData[%d]", i)))
+	}
+	if shellLinkParsed.TerminalBlockMissing {
+		v.add("2.5", "ExtraData.TerminalBlock", "missing", fmt.Sprintf("< 0x%X", TerminalBlockSizeMax))
+	} else if shellLinkParsed.TerminalBlockSize >= TerminalBlockSizeMax {
+		v.add("2.5", "ExtraData.TerminalBlock", fmt.Sprintf("0x%X", shellLinkParsed.TerminalBlockSize), fmt.Sprintf("< 0x%X", TerminalBlockSizeMax))
+	}
+	return v.violations
+}
+
+// hasWarning reports whether a warning was recorded for section or a part of it.
+func hasWarning(shellLinkParsed *ShellLinkParsed, section string) bool {
+	for _, warning := range shellLinkParsed.Warnings {
+		if warning.Section == section || strings.HasPrefix(warning.Section, section+".") {
+			return true
+		}
+	}
+	return false
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(reference string, field string, is string, expected string) {
+	v.violations = append(v.violations, Violation{Reference: reference, Field: field, Is: is, Expected: expected})
+}
+
+// expectEqual adds a violation if a field with a fixed value differs from it.
+func (v *validator) expectEqual(reference string, field string, is uint32, expected uint32) {
+	if is != expected {
+		v.add(reference, field, fmt.Sprintf("0x%X", is), fmt.Sprintf("0x%X", expected))
+	}
+}
+
+func (v *validator) validateHeader(header *ShellLinkHeader) {
+	v.expectEqual("2.1", "ShellLinkHeader.HeaderSize", header.HeaderSize, HeaderSizeExpected)
+	if header.LinkCLSID != LinkCLSIDExpected {
+		v.add("2.1", "ShellLinkHeader.LinkCLSID", GUID(header.LinkCLSID).String(), GUID(LinkCLSIDExpected).String())
+	}
+	v.expectEqual("2.1.2", "ShellLinkHeader.FileAttributes.Reserved1", header.FileAttributes&FileAttributeReserved1, 0)
+	v.expectEqual("2.1.2", "ShellLinkHeader.FileAttributes.Reserved2", header.FileAttributes&FileAttributeReserved2, 0)
+	v.expectEqual("2.1", "ShellLinkHeader.Reserved1", uint32(header.Reserved1), 0)
+	v.expectEqual("2.1", "ShellLinkHeader.Reserved2", header.Reserved2, 0)
+	v.expectEqual("2.1", "ShellLinkHeader.Reserved3", header.Reserved3, 0)
+}
+
+// validateIDList checks that the ItemIDs fill an IDList of idListSize bytes up to its TerminalID.
+func (v *validator) validateIDList(reference string, field string, itemIDs []ItemID, idListSize uint32) {
+	var terminalIDOffset uint32
+	for _, itemID := range itemIDs {
+		terminalIDOffset += uint32(itemID.ItemIDSize)
+	}
+	switch {
+	case terminalIDOffset+2 > idListSize:
+		v.add(reference, field+".TerminalID", "missing", "0x0000 ending the IDList")
+	case terminalIDOffset+2 < idListSize:
+		v.add(reference, field+".TerminalID", fmt.Sprintf("at 0x%X", terminalIDOffset), fmt.Sprintf("at 0x%X", idListSize-2))
+	}
+}
+
+func (v *validator) validateLinkInfo(linkInfo *LinkInfo) {
+	headerSize := linkInfo.LinkInfoHeaderSize
+	if headerSize != LinkInfoHeaderSizeOptionalFieldsNotSpecified && headerSize < LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		v.add("2.3", "LinkInfo.LinkInfoHeaderSize", fmt.Sprintf("0x%X", headerSize),
+			fmt.Sprintf("0x%X or >= 0x%X", LinkInfoHeaderSizeOptionalFieldsNotSpecified, LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom))
+	}
+
+	volumeIDAndLocalBasePath := linkInfo.LinkInfoFlags&VolumeIDAndLocalBasePathPresent != 0
+	commonNetworkRelativeLink := linkInfo.LinkInfoFlags&CommonNetworkRelativeLinkAndPathSuffixPresent != 0
+	type offsetField struct {
+		name    string
+		offset  uint32
+		present bool // the flag the field depends on is set, it MUST be zero otherwise
+	}
+	offsets := []offsetField{
+		{"VolumeIDOffset", linkInfo.VolumeIDOffset, volumeIDAndLocalBasePath},
+		{"LocalBasePathOffset", linkInfo.LocalBasePathOffset, volumeIDAndLocalBasePath},
+		{"CommonNetworkRelativeLinkOffset", linkInfo.CommonNetworkRelativeLinkOffset, commonNetworkRelativeLink},
+		{"CommonPathSuffixOffset", linkInfo.CommonPathSuffixOffset, true},
+	}
+	if headerSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		offsets = append(offsets,
+			offsetField{"LocalBasePathOffsetUnicode", linkInfo.LocalBasePathOffsetUnicode, volumeIDAndLocalBasePath},
+			offsetField{"CommonPathSuffixOffsetUnicode", linkInfo.CommonPathSuffixOffsetUnicode, true},
+		)
+	}
+	for _, o := range offsets {
+		field := "LinkInfo." + o.name
+		if !o.present {
+			v.expectEqual("2.3", field, o.offset, 0)
+			continue
+		}
+		if o.offset < headerSize || o.offset >= linkInfo.LinkInfoSize {
+			v.add("2.3", field, fmt.Sprintf("0x%X", o.offset), fmt.Sprintf(">= 0x%X and < 0x%X", headerSize, linkInfo.LinkInfoSize))
+		}
+	}
+
+	if volumeIDAndLocalBasePath && linkInfo.VolumeIDOffset != 0 {
+		v.validateVolumeID(&linkInfo.VolumeID)
+	}
+	if linkInfo.CommonNetworkRelativeLink != nil {
+		v.validateCommonNetworkRelativeLink(linkInfo.CommonNetworkRelativeLink)
+	}
+}
+
+func (v *validator) validateVolumeID(volumeID *VolumeID) {
+	if volumeID.VolumeIDSize <= VolumeIDSizeMin {
+		v.add("2.3.1", "LinkInfo.VolumeID.VolumeIDSize", fmt.Sprintf("0x%X", volumeID.VolumeIDSize), fmt.Sprintf("> 0x%X", VolumeIDSizeMin))
+		return
+	}
+	if _, ok := driveTypeNames[volumeID.DriveType]; !ok {
+		v.add("2.3.1", "LinkInfo.VolumeID.DriveType", fmt.Sprintf("0x%X", volumeID.DriveType), "DRIVE_UNKNOWN to DRIVE_RAMDISK")
+	}
+	labelOffset, labelField := volumeID.VolumeLabelOffset, "VolumeLabelOffset"
+	if labelOffset == VolumeLabelOffsetUnicodePresent {
+		labelOffset, labelField = volumeID.VolumeLabelOffsetUnicode, "VolumeLabelOffsetUnicode"
+	}
+	if labelOffset >= volumeID.VolumeIDSize {
+		v.add("2.3.1", "LinkInfo.VolumeID."+labelField, fmt.Sprintf("0x%X", labelOffset), fmt.Sprintf("< 0x%X", volumeID.VolumeIDSize))
+	}
+}
+
+func (v *validator) validateCommonNetworkRelativeLink(link *CommonNetworkRelativeLink) {
+	const reference = "2.3.2"
+	if link.CommonNetworkRelativeLinkSize < CommonNetworkRelativeLinkUnicodeMinOffsets {
+		v.add(reference, "LinkInfo.CommonNetworkRelativeLink.CommonNetworkRelativeLinkSize",
+			fmt.Sprintf("0x%X", link.CommonNetworkRelativeLinkSize), fmt.Sprintf(">= 0x%X", CommonNetworkRelativeLinkUnicodeMinOffsets))
+		return
+	}
+	if !link.ValidDevice {
+		v.expectEqual(reference, "LinkInfo.CommonNetworkRelativeLink.DeviceNameOffset", link.DeviceNameOffset, 0)
+	}
+	// WNNC_NET_LANMAN is missing from the MS-SHLLINK table but written by Windows for SMB shares
+	if _, ok := networkProviderNames[link.NetworkProviderType]; link.ValidNetType && !ok {
+		v.add(reference, "LinkInfo.CommonNetworkRelativeLink.NetworkProviderType", fmt.Sprintf("0x%08X", link.NetworkProviderType), "a WNNC_NET_* value")
+	}
+	if link.NetNameOffset >= link.CommonNetworkRelativeLinkSize {
+		v.add(reference, "LinkInfo.CommonNetworkRelativeLink.NetNameOffset",
+			fmt.Sprintf("0x%X", link.NetNameOffset), fmt.Sprintf("< 0x%X", link.CommonNetworkRelativeLinkSize))
+	}
+}
+
+// validateExtraDataBlock checks the BlockSize of a block against its signature, its content only if it was fully parsed.
+func (v *validator) validateExtraDataBlock(field string, block *ExtraDataBlock, parsed bool) {
+	exact := func(reference string, blockSize uint32, expected uint32) {
+		v.expectEqual(reference, field+".BlockSize", blockSize, expected)
+	}
+	atLeast := func(reference string, blockSize uint32, min uint32) {
+		if blockSize < min {
+			v.add(reference, field+".BlockSize", fmt.Sprintf("0x%X", blockSize), fmt.Sprintf(">= 0x%X", min))
+		}
+	}
+	switch {
+	case block.ConsoleDataBlock != nil:
+		exact("2.5.1", block.ConsoleDataBlock.BlockSize, ConsoleDataBlockSize)
+	case block.ConsoleFEDataBlock != nil:
+		exact("2.5.2", block.ConsoleFEDataBlock.BlockSize, ConsoleFEDataBlockSize)
+	case block.DarwinDataBlock != nil:
+		exact("2.5.3", block.DarwinDataBlock.BlockSize, DarwinDataBlockSize)
+	case block.EnvironmentVariableDataBlock != nil:
+		exact("2.5.4", block.EnvironmentVariableDataBlock.BlockSize, EnvironmentVariableDataBlockSize)
+	case block.IconEnvironmentDataBlock != nil:
+		exact("2.5.5", block.IconEnvironmentDataBlock.BlockSize, IconEnvironmentDataBlockSize)
+	case block.KnownFolderDataBlock != nil:
+		exact("2.5.6", block.KnownFolderDataBlock.BlockSize, KnownFolderDataBlockSize)
+	case block.PropertyStoreDataBlock != nil:
+		atLeast("2.5.7", block.PropertyStoreDataBlock.BlockSize, PropertyStoreDataBlockSizeMin)
+	case block.ShimDataBlock != nil:
+		atLeast("2.5.8", block.ShimDataBlock.BlockSize, ShimDataBlockSizeMin)
+	case block.SpecialFolderDataBlock != nil:
+		exact("2.5.9", block.SpecialFolderDataBlock.BlockSize, SpecialFolderDataBlockSize)
+	case block.TrackerDataBlock != nil:
+		exact("2.5.10", block.TrackerDataBlock.BlockSize, TrackerDataBlockSize)
+		if parsed {
+			v.expectEqual("2.5.10", field+".Length", block.TrackerDataBlock.Length, TrackerDataBlockLength)
+			v.expectEqual("2.5.10", field+".Version", block.TrackerDataBlock.Version, 0)
+		}
+	case block.VistaAndAboveIDListDataBlock != nil:
+		atLeast("2.5.11", block.VistaAndAboveIDListDataBlock.BlockSize, VistaAndAboveIDListDataBlockSizeMin)
+		if parsed {
+			v.validateIDList("2.5.11", field+".IDList", block.VistaAndAboveIDListDataBlock.IDListData.ItemIDs, block.VistaAndAboveIDListDataBlock.BlockSize-ExtraDataBlockSizeMin)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_Validate(t *testing.T) {
+	data, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		data   func([]byte) []byte
+		modify func(s *ShellLinkParsed)
+		want   []Violation
+	}{
+		{
+			name:   "conforming",
+			modify: func(s *ShellLinkParsed) {},
+			want:   []Violation{},
+		},
+		{
+			name: "reserved header fields",
+			modify: func(s *ShellLinkParsed) {
+				s.Header.FileAttributes |= FileAttributeReserved2
+				s.Header.Reserved1 = 1
+			},
+			want: []Violation{
+				{Reference: "2.1.2", Field: "ShellLinkHeader.FileAttributes.Reserved2", Is: "0x40", Expected: "0x0"},
+				{Reference: "2.1", Field: "ShellLinkHeader.Reserved1", Is: "0x1", Expected: "0x0"},
+			},
+		},
+		{
+			name: "IDList without TerminalID",
+			modify: func(s *ShellLinkParsed) {
+				s.LinkTargetIDList.IDListSize -= 2
+			},
+			want: []Violation{
+				{Reference: "2.2.1", Field: "LinkTargetIDList.IDList.TerminalID", Is: "missing", Expected: "0x0000 ending the IDList"},
+			},
+		},
+		{
+			name: "LinkInfo offsets",
+			modify: func(s *ShellLinkParsed) {
+				s.LinkInfo.LinkInfoHeaderSize = 0x20
+				s.LinkInfo.CommonNetworkRelativeLinkOffset = 0x30
+			},
+			want: []Violation{
+				{Reference: "2.3", Field: "LinkInfo.LinkInfoHeaderSize", Is: "0x20", Expected: "0x1C or >= 0x24"},
+				{Reference: "2.3", Field: "LinkInfo.VolumeIDOffset", Is: "0x1C", Expected: ">= 0x20 and < 0x45"},
+				{Reference: "2.3", Field: "LinkInfo.CommonNetworkRelativeLinkOffset", Is: "0x30", Expected: "0x0"},
+			},
+		},
+		{
+			name: "VolumeIDSize",
+			modify: func(s *ShellLinkParsed) {
+				s.LinkInfo.VolumeID.VolumeIDSize = VolumeIDSizeMin
+			},
+			want: []Violation{
+				{Reference: "2.3.1", Field: "LinkInfo.VolumeID.VolumeIDSize", Is: "0x10", Expected: "> 0x10"},
+			},
+		},
+		{
+			name: "TrackerDataBlock",
+			modify: func(s *ShellLinkParsed) {
+				s.ExtraData[4].TrackerDataBlock.BlockSize = 0x5C
+				s.ExtraData[4].TrackerDataBlock.Version = 1
+			},
+			want: []Violation{
+				{Reference: "2.5.10", Field: "ExtraData[4].BlockSize", Is: "0x5C", Expected: "0x60"},
+				{Reference: "2.5.10", Field: "ExtraData[4].Version", Is: "0x1", Expected: "0x0"},
+			},
+		},
+		{
+			name:   "no TerminalBlock",
+			data:   func(data []byte) []byte { return data[:len(data)-4] },
+			modify: func(s *ShellLinkParsed) {},
+			want: []Violation{
+				{Reference: "2.5", Field: "ExtraData.TerminalBlock", Is: "missing", Expected: "< 0x4"},
+			},
+		},
+		{
+			name: "TerminalBlock of 4",
+			modify: func(s *ShellLinkParsed) {
+				s.TerminalBlockSize = 4
+			},
+			want: []Violation{
+				{Reference: "2.5", Field: "ExtraData.TerminalBlock", Is: "0x4", Expected: "< 0x4"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := data
+			if tt.data != nil {
+				data = tt.data(data)
+			}
+			shellLinkParsed, err := Parse(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&shellLinkParsed)
+			if got := Validate(&shellLinkParsed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"unicode/utf16"
+)
+
+// Encode serializes a parsed shortcut to a .lnk file. Sizes and offsets are recomputed, the sections written
+// follow Header.LinkFlags. Strings keep the bytes of their *Base64 field unless the decoded text was changed,
+// LinkInfo keeps LinkInfoBase64 unless one of its fields was changed, unknown ExtraData blocks, the excess data
+// of blocks, the data after a TerminalID or the TerminalBlock and a missing or nonzero TerminalBlock are written
+// back as they were read, so an unmodified file is reproduced byte for byte. ItemIDs and PropertyStoreDataBlocks are
+// written from their *Base64 fields only. Compile rejects JSON in which a text and its *Base64 field disagree.
+func Encode(shellLinkParsed *ShellLinkParsed) ([]byte, error) {
+	sections, err := encodeSections(shellLinkParsed)
+	if err != nil {
+		return nil, err
+	}
+	trailingData, err := base64.StdEncoding.DecodeString(shellLinkParsed.TrailingDataBase64)
+	if err != nil {
+		return nil, fmt.Errorf("encode TrailingDataBase64: %w", err)
+	}
+	terminalBlock, err := encodeTerminalBlock(shellLinkParsed)
+	if err != nil {
+		return nil, fmt.Errorf("encode ExtraData: %w", err)
+	}
+	b := bytes.Join([][]byte{sections.header, sections.linkTargetIDList, sections.linkInfo, sections.stringData}, nil)
+	for _, block := range sections.extraData {
+		b = append(b, block...)
+	}
+	b = append(b, terminalBlock...)
+	return append(b, trailingData...), nil
+}
+
+// encodedSections holds the serialized sections of a .lnk file, nil if a section is not present.
+type encodedSections struct {
+	header           []byte
+	linkTargetIDList []byte
+	linkInfo         []byte
+	stringData       []byte
+	extraData        [][]byte // without the TerminalBlock
+}
+
+// encodeSections serializes each section of a parsed shortcut, see Encode.
+func encodeSections(shellLinkParsed *ShellLinkParsed) (encodedSections, error) {
+	var sections encodedSections
+	codePage := shellLinkParsed.CodePage
+	linkFlagsParsed := ParseLinkFlags(shellLinkParsed.Header.LinkFlags)
+	var err error
+	sections.header, err = EncodeShellLinkHeader(shellLinkParsed.Header)
+	if err != nil {
+		return sections, fmt.Errorf("encode ShellLinkHeader: %w", err)
+	}
+
+	if linkFlagsParsed.HasLinkTargetIDList {
+		if shellLinkParsed.LinkTargetIDList == nil {
+			return sections, errors.New("encode LinkTargetIDList: LinkFlag 'HasLinkTargetIDList' is set but LinkTargetIDList is missing")
+		}
+		sections.linkTargetIDList, err = EncodeLinkTargetIDList(shellLinkParsed.LinkTargetIDList)
+		if err != nil {
+			return sections, fmt.Errorf("encode LinkTargetIDList: %w", err)
+		}
+	}
+
+	if linkFlagsParsed.HasLinkInfo {
+		if shellLinkParsed.LinkInfo == nil {
+			return sections, errors.New("encode LinkInfo: LinkFlag 'HasLinkInfo' is set but LinkInfo is missing")
+		}
+		sections.linkInfo, err = EncodeLinkInfo(shellLinkParsed.LinkInfo, codePage)
+		if err != nil {
+			return sections, fmt.Errorf("encode LinkInfo: %w", err)
+		}
+	}
+
+	sections.stringData, err = EncodeStringData(&shellLinkParsed.StringData, linkFlagsParsed, codePage)
+	if err != nil {
+		return sections, fmt.Errorf("encode StringData: %w", err)
+	}
+
+	for i := range shellLinkParsed.ExtraData {
+		block, err := EncodeExtraDataBlock(&shellLinkParsed.ExtraData[i], codePage)
+		if err != nil {
+			return sections, fmt.Errorf("encode ExtraData: ExtraData[%d]: %w", i, err)
+		}
+		sections.extraData = append(sections.extraData, block)
+	}
+	return sections, nil
+}
+
+// Write writes a parsed shortcut as .lnk file to w, see Encode.
+func Write(w io.Writer, shellLinkParsed *ShellLinkParsed) error {
+	data, err := Encode(shellLinkParsed)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// EncodeShellLinkHeader serializes the header, HeaderSize is always 0x4C.
+func EncodeShellLinkHeader(header ShellLinkHeader) ([]byte, error) {
+	header.HeaderSize = HeaderSizeExpected
+	var b bytes.Buffer
+	err := binary.Write(&b, binary.LittleEndian, header)
+	return b.Bytes(), err
+}
+
+// EncodeLinkTargetIDList serializes IDListSize followed by the IDList.
+func EncodeLinkTargetIDList(linkTargetIDList *LinkTargetIDList) ([]byte, error) {
+	idList, err := EncodeIDList(linkTargetIDList.IDListData)
+	if err != nil {
+		return nil, err
+	}
+	if len(idList) > 0xFFFF {
+		return nil, fmt.Errorf("IDListSize 0x%X out of range", len(idList))
+	}
+	return append(putUint16(nil, uint16(len(idList))), idList...), nil
+}
+
+// EncodeIDList serializes the ItemIDs from their ItemIDDataBase64, the TerminalID unless the IDList
+// was read without one and the data that followed.
+func EncodeIDList(idList IDList) ([]byte, error) {
+	var b []byte
+	for i, itemID := range idList.ItemIDs {
+		data, err := base64.StdEncoding.DecodeString(itemID.ItemIDDataBase64)
+		if err != nil {
+			return nil, fmt.Errorf("ItemIDs[%d] ItemIDDataBase64: %w", i, err)
+		}
+		if len(data)+2 > 0xFFFF {
+			return nil, fmt.Errorf("ItemIDs[%d] ItemIDSize 0x%X out of range", i, len(data)+2)
+		}
+		b = putUint16(b, uint16(len(data)+2))
+		b = append(b, data...)
+	}
+	if !idList.Unterminated {
+		b = putUint16(b, 0)
+	}
+	trailingData, err := base64.StdEncoding.DecodeString(idList.TrailingDataBase64)
+	if err != nil {
+		return nil, fmt.Errorf("TrailingDataBase64: %w", err)
+	}
+	return append(b, trailingData...), nil
+}
+
+// EncodeLinkInfo returns LinkInfoBase64 if it still parses to linkInfo, otherwise it serializes a LinkInfo in the
+// layout Windows uses: header, VolumeID, LocalBasePath, CommonNetworkRelativeLink, CommonPathSuffix and the Unicode
+// strings. LinkInfoFlags decide which parts are written, a header beyond the optional fields is taken from LinkInfoBase64.
+func EncodeLinkInfo(linkInfo *LinkInfo, codePage *CodePage) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(linkInfo.LinkInfoBase64)
+	if err != nil {
+		return nil, fmt.Errorf("LinkInfoBase64: %w", err)
+	}
+	if len(raw) > 0 {
+		// offsets, their order and unused bytes are only known from the original bytes
+		if parsed, err := ParseLinkInfo(bytes.NewReader(raw), codePage); err == nil && reflect.DeepEqual(&parsed, linkInfo) {
+			return raw, nil
+		}
+	}
+
+	volumeIDAndLocalBasePath := linkInfo.LinkInfoFlags&VolumeIDAndLocalBasePathPresent != 0
+	commonNetworkRelativeLink := linkInfo.LinkInfoFlags&CommonNetworkRelativeLinkAndPathSuffixPresent != 0
+	if commonNetworkRelativeLink && linkInfo.CommonNetworkRelativeLink == nil {
+		return nil, errors.New("LinkInfoFlag 'CommonNetworkRelativeLinkAndPathSuffixPresent' is set but CommonNetworkRelativeLink is missing")
+	}
+	localBasePathUnicode := volumeIDAndLocalBasePath && (linkInfo.LocalBasePathOffsetUnicode != 0 || linkInfo.LocalBasePathUnicode != "")
+	commonPathSuffixUnicode := linkInfo.CommonPathSuffixOffsetUnicode != 0 || linkInfo.CommonPathSuffixUnicode != ""
+	headerSize := LinkInfoHeaderSizeOptionalFieldsNotSpecified
+	var headerExtension []byte
+	if linkInfo.LinkInfoHeaderSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		headerSize = linkInfo.LinkInfoHeaderSize
+	} else if localBasePathUnicode || commonPathSuffixUnicode {
+		headerSize = LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom
+	}
+	if headerSize > LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		if headerSize > linkInfo.LinkInfoSize {
+			return nil, fmt.Errorf("LinkInfoHeaderSize 0x%X is larger than LinkInfoSize 0x%X", headerSize, linkInfo.LinkInfoSize)
+		}
+		if uint64(len(raw)) < uint64(headerSize) {
+			return nil, fmt.Errorf("LinkInfoHeaderSize 0x%X: the header after the optional fields is not in LinkInfoBase64", headerSize)
+		}
+		headerExtension = raw[LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom:headerSize]
+	}
+
+	var body []byte
+	// appendPart adds a part to body and returns its offset from the start of the LinkInfo
+	appendPart := func(part []byte) uint32 {
+		offset := headerSize + uint32(len(body))
+		body = append(body, part...)
+		return offset
+	}
+	var volumeIDOffset, localBasePathOffset, commonNetworkRelativeLinkOffset uint32
+	var localBasePathOffsetUnicode, commonPathSuffixOffsetUnicode uint32
+	if volumeIDAndLocalBasePath {
+		volumeID, err := encodeVolumeID(&linkInfo.VolumeID, codePage)
+		if err != nil {
+			return nil, fmt.Errorf("VolumeID: %w", err)
+		}
+		volumeIDOffset = appendPart(volumeID)
+		localBasePath, err := encodeANSIString(linkInfo.LocalBasePath, linkInfo.LocalBasePathBase64, true, codePage)
+		if err != nil {
+			return nil, fmt.Errorf("LocalBasePath: %w", err)
+		}
+		localBasePathOffset = appendPart(localBasePath)
+	}
+	if commonNetworkRelativeLink {
+		link, err := encodeCommonNetworkRelativeLink(linkInfo.CommonNetworkRelativeLink, codePage)
+		if err != nil {
+			return nil, fmt.Errorf("CommonNetworkRelativeLink: %w", err)
+		}
+		commonNetworkRelativeLinkOffset = appendPart(link)
+	}
+	commonPathSuffix, err := encodeANSIString(linkInfo.CommonPathSuffix, linkInfo.CommonPathSuffixBase64, true, codePage)
+	if err != nil {
+		return nil, fmt.Errorf("CommonPathSuffix: %w", err)
+	}
+	commonPathSuffixOffset := appendPart(commonPathSuffix)
+	if localBasePathUnicode {
+		localBasePath, err := encodeUnicodeString(linkInfo.LocalBasePathUnicode, linkInfo.LocalBasePathUnicodeBase64, true)
+		if err != nil {
+			return nil, fmt.Errorf("LocalBasePathUnicode: %w", err)
+		}
+		localBasePathOffsetUnicode = appendPart(localBasePath)
+	}
+	if commonPathSuffixUnicode {
+		commonPathSuffix, err := encodeUnicodeString(linkInfo.CommonPathSuffixUnicode, linkInfo.CommonPathSuffixUnicodeBase64, true)
+		if err != nil {
+			return nil, fmt.Errorf("CommonPathSuffixUnicode: %w", err)
+		}
+		commonPathSuffixOffsetUnicode = appendPart(commonPathSuffix)
+	}
+
+	header := make([]byte, headerSize)
+	for i, field := range []uint32{
+		headerSize + uint32(len(body)),
+		headerSize,
+		linkInfo.LinkInfoFlags,
+		volumeIDOffset,
+		localBasePathOffset,
+		commonNetworkRelativeLinkOffset,
+		commonPathSuffixOffset,
+	} {
+		binary.LittleEndian.PutUint32(header[i*4:], field)
+	}
+	if headerSize >= LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom {
+		binary.LittleEndian.PutUint32(header[28:], localBasePathOffsetUnicode)
+		binary.LittleEndian.PutUint32(header[32:], commonPathSuffixOffsetUnicode)
+		copy(header[LinkInfoHeaderSizeOptionalFieldsSpecifiedFrom:], headerExtension)
+	}
+	return append(header, body...), nil
+}
+
+// encodeVolumeID serializes a VolumeID with the label right after its header, as UTF-16LE
+// if VolumeLabelOffset is 0x14 or VolumeLabelUnicode is set.
+func encodeVolumeID(volumeID *VolumeID, codePage *CodePage) ([]byte, error) {
+	headerSize := VolumeIDSizeMin
+	var label []byte
+	var err error
+	if volumeID.VolumeLabelOffset == VolumeLabelOffsetUnicodePresent || volumeID.VolumeLabelUnicode != "" {
+		headerSize = VolumeLabelOffsetUnicodePresent + 4
+		label, err = encodeUnicodeString(volumeID.VolumeLabelUnicode, volumeID.VolumeLableBase64, true)
+	} else {
+		label, err = encodeANSIString(volumeID.VolumeLabel, volumeID.VolumeLableBase64, true, codePage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, headerSize, int(headerSize)+len(label))
+	binary.LittleEndian.PutUint32(data, headerSize+uint32(len(label)))
+	binary.LittleEndian.PutUint32(data[4:], volumeID.DriveType)
+	binary.LittleEndian.PutUint32(data[8:], volumeID.DriveSerialNumber)
+	if headerSize == VolumeIDSizeMin {
+		binary.LittleEndian.PutUint32(data[12:], VolumeIDSizeMin)
+	} else {
+		binary.LittleEndian.PutUint32(data[12:], VolumeLabelOffsetUnicodePresent)
+		binary.LittleEndian.PutUint32(data[16:], headerSize)
+	}
+	return append(data, label...), nil
+}
+
+// encodeCommonNetworkRelativeLink serializes NetName, DeviceName and their Unicode versions after the header,
+// the Unicode offsets are written if NetNameOffset was beyond 0x14 or a Unicode name is set.
+func encodeCommonNetworkRelativeLink(link *CommonNetworkRelativeLink, codePage *CodePage) ([]byte, error) {
+	validDevice := link.CommonNetworkRelativeLinkFlags&ValidDevice != 0
+	unicode := link.NetNameOffset > CommonNetworkRelativeLinkUnicodeMinOffsets || link.NetNameUnicode != "" || link.DeviceNameUnicode != ""
+	headerSize := CommonNetworkRelativeLinkUnicodeMinOffsets
+	if unicode {
+		headerSize += 8
+	}
+
+	var body []byte
+	appendPart := func(part []byte) uint32 {
+		offset := headerSize + uint32(len(body))
+		body = append(body, part...)
+		return offset
+	}
+	var deviceNameOffset, netNameOffsetUnicode, deviceNameOffsetUnicode uint32
+	netName, err := encodeANSIString(link.NetName, link.NetNameBase64, true, codePage)
+	if err != nil {
+		return nil, fmt.Errorf("NetName: %w", err)
+	}
+	netNameOffset := appendPart(netName)
+	if validDevice {
+		deviceName, err := encodeANSIString(link.DeviceName, link.DeviceNameBase64, true, codePage)
+		if err != nil {
+			return nil, fmt.Errorf("DeviceName: %w", err)
+		}
+		deviceNameOffset = appendPart(deviceName)
+	}
+	if unicode {
+		netName, err := encodeUnicodeString(link.NetNameUnicode, link.NetNameUnicodeBase64, true)
+		if err != nil {
+			return nil, fmt.Errorf("NetNameUnicode: %w", err)
+		}
+		netNameOffsetUnicode = appendPart(netName)
+		if validDevice {
+			deviceName, err := encodeUnicodeString(link.DeviceNameUnicode, link.DeviceNameUnicodeBase64, true)
+			if err != nil {
+				return nil, fmt.Errorf("DeviceNameUnicode: %w", err)
+			}
+			deviceNameOffsetUnicode = appendPart(deviceName)
+		}
+	}
+
+	header := make([]byte, headerSize)
+	for i, field := range []uint32{
+		headerSize + uint32(len(body)),
+		link.CommonNetworkRelativeLinkFlags,
+		netNameOffset,
+		deviceNameOffset,
+		link.NetworkProviderType,
+	} {
+		binary.LittleEndian.PutUint32(header[i*4:], field)
+	}
+	if unicode {
+		binary.LittleEndian.PutUint32(header[20:], netNameOffsetUnicode)
+		binary.LittleEndian.PutUint32(header[24:], deviceNameOffsetUnicode)
+	}
+	return append(header, body...), nil
+}
+
+// EncodeStringData serializes the StringData items whose LinkFlag is set, UTF-16LE if the link is unicode.
+func EncodeStringData(stringData *StringData, linkFlagsParsed LinkFlagsParsed, codePage *CodePage) ([]byte, error) {
+	var b []byte
+	for _, item := range []struct {
+		name    string
+		present bool
+		str     string
+		b64     string
+	}{
+		{"NameString", linkFlagsParsed.HasName, stringData.NameString, stringData.NameStringBase64},
+		{"RelativePath", linkFlagsParsed.HasRelativePath, stringData.RelativePath, stringData.RelativePathBase64},
+		{"WorkingDir", linkFlagsParsed.HasWorkingDir, stringData.WorkingDir, stringData.WorkingDirBase64},
+		{"CommandLineArgs", linkFlagsParsed.HasArguments, stringData.CommandLineArgs, stringData.CommandLineArgsBase64},
+		{"IconLocation", linkFlagsParsed.HasIconLocation, stringData.IconLocation, stringData.IconLocationBase64},
+	} {
+		if !item.present {
+			continue
+		}
+		var data []byte
+		var err error
+		countCharacters := 0
+		if linkFlagsParsed.IsUnicode {
+			data, err = encodeUnicodeString(item.str, item.b64, false)
+			countCharacters = len(data) / 2
+		} else {
+			data, err = encodeANSIString(item.str, item.b64, false, codePage)
+			countCharacters = len(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", item.name, err)
+		}
+		if countCharacters > 0xFFFF {
+			return nil, fmt.Errorf("%v: CountCharacters 0x%X out of range", item.name, countCharacters)
+		}
+		b = putUint16(b, uint16(countCharacters))
+		b = append(b, data...)
+	}
+	return b, nil
+}
+
+// encodeTerminalBlock returns the TerminalBlock as it was read, nothing if the file ended without one.
+func encodeTerminalBlock(shellLinkParsed *ShellLinkParsed) ([]byte, error) {
+	if shellLinkParsed.TerminalBlockMissing {
+		if shellLinkParsed.TrailingDataBase64 != "" {
+			return nil, errors.New("TrailingDataBase64 is set but TerminalBlockMissing is true")
+		}
+		return nil, nil
+	}
+	if shellLinkParsed.TerminalBlockSize >= ExtraDataBlockSizeMin {
+		return nil, fmt.Errorf("TerminalBlockSize 0x%X is not below 0x%X", shellLinkParsed.TerminalBlockSize, ExtraDataBlockSizeMin)
+	}
+	return putUint32(nil, shellLinkParsed.TerminalBlockSize), nil
+}
+
+// EncodeExtraDataBlock serializes a single block with BlockSize and BlockSignature.
+func EncodeExtraDataBlock(block *ExtraDataBlock, codePage *CodePage) ([]byte, error) {
+	blockSignature, blockData, err := encodeExtraDataBlock(block, codePage)
+	if err != nil {
+		return nil, err
+	}
+	excessData, err := base64.StdEncoding.DecodeString(block.ExcessDataBase64)
+	if err != nil {
+		return nil, fmt.Errorf("ExcessDataBase64: %w", err)
+	}
+	blockData = append(blockData, excessData...)
+	b := putUint32(nil, ExtraDataBlockSizeMin+uint32(len(blockData)))
+	b = putUint32(b, blockSignature)
+	return append(b, blockData...), nil
+}
+
+// encodeExtraDataBlock returns the BlockSignature of a block and the data that follows it.
+func encodeExtraDataBlock(block *ExtraDataBlock, codePage *CodePage) (uint32, []byte, error) {
+	switch {
+	case block.ConsoleDataBlock != nil:
+		var b bytes.Buffer
+		if err := binary.Write(&b, binary.LittleEndian, block.ConsoleDataBlock); err != nil {
+			return 0, nil, err
+		}
+		return ConsoleDataBlockSignature, b.Bytes()[ExtraDataBlockSizeMin:], nil
+	case block.ConsoleFEDataBlock != nil:
+		return ConsoleFEDataBlockSignature, putUint32(nil, block.ConsoleFEDataBlock.CodePage), nil
+	case block.DarwinDataBlock != nil:
+		data, err := encodeAnsiUnicodeTarget(block.DarwinDataBlock.DarwinDataAnsi, block.DarwinDataBlock.DarwinDataAnsiBase64,
+			block.DarwinDataBlock.DarwinDataUnicode, block.DarwinDataBlock.DarwinDataUnicodeBase64, codePage)
+		return DarwinDataBlockSignature, data, err
+	case block.EnvironmentVariableDataBlock != nil:
+		data, err := encodeAnsiUnicodeTarget(block.EnvironmentVariableDataBlock.TargetAnsi, block.EnvironmentVariableDataBlock.TargetAnsiBase64,
+			block.EnvironmentVariableDataBlock.TargetUnicode, block.EnvironmentVariableDataBlock.TargetUnicodeBase64, codePage)
+		return EnviromentVariableDataBlockSignature, data, err
+	case block.IconEnvironmentDataBlock != nil:
+		data, err := encodeAnsiUnicodeTarget(block.IconEnvironmentDataBlock.TargetAnsi, block.IconEnvironmentDataBlock.TargetAnsiBase64,
+			block.IconEnvironmentDataBlock.TargetUnicode, block.IconEnvironmentDataBlock.TargetUnicodeBase64, codePage)
+		return IconEnviromentDataBlockSignature, data, err
+	case block.KnownFolderDataBlock != nil:
+		data := append([]byte{}, block.KnownFolderDataBlock.KnownFolderID[:]...)
+		return KnownFolderDataBlockSignature, putUint32(data, uint32(block.KnownFolderDataBlock.Offset)), nil
+	case block.PropertyStoreDataBlock != nil:
+		data, err := base64.StdEncoding.DecodeString(block.PropertyStoreDataBlock.PropertyStoreBase64)
+		return PropertyStoreDataBlockSignature, data, err
+	case block.ShimDataBlock != nil:
+		data, err := encodeFixedUnicode(block.ShimDataBlock.LayerName, block.ShimDataBlock.LayerNameBase64, int(ShimDataBlockSizeMin-ExtraDataBlockSizeMin))
+		return ShimDataBlockSignature, data, err
+	case block.SpecialFolderDataBlock != nil:
+		data := putUint32(nil, block.SpecialFolderDataBlock.SpecialFolderID)
+		return SpecialFolderDataBlockSignature, putUint32(data, uint32(block.SpecialFolderDataBlock.Offset)), nil
+	case block.TrackerDataBlock != nil:
+		tracker := block.TrackerDataBlock
+		// Length is kept, a block built from scratch has none
+		length := tracker.Length
+		if length == 0 {
+			length = TrackerDataBlockLength
+		}
+		data := putUint32(nil, length)
+		data = putUint32(data, tracker.Version)
+		machineID, err := encodeFixedANSI(tracker.MachineID, tracker.MachineIDBase64, 16, codePage)
+		if err != nil {
+			return 0, nil, fmt.Errorf("MachineID: %w", err)
+		}
+		data = append(data, machineID...)
+		for _, droid := range []GUID{tracker.DroidVolume, tracker.DroidFile, tracker.BirthDroidVolume, tracker.BirthDroidFile} {
+			data = append(data, droid[:]...)
+		}
+		return TrackerDataBlockSignature, data, nil
+	case block.VistaAndAboveIDListDataBlock != nil:
+		data, err := EncodeIDList(block.VistaAndAboveIDListDataBlock.IDListData)
+		return VistaAndAboveIDListDataBlockSignature, data, err
+	case block.UnknownDataBlock != nil:
+		data, err := base64.StdEncoding.DecodeString(block.UnknownDataBlock.BlockDataBase64)
+		return block.UnknownDataBlock.BlockSignature, data, err
+	}
+	return 0, nil, errors.New("no block is set")
+}
+
+// encodeAnsiUnicodeTarget serializes the 260 bytes ANSI and 520 bytes UTF-16LE strings, see readAnsiUnicodeTarget.
+func encodeAnsiUnicodeTarget(ansi string, ansiBase64 string, unicode string, unicodeBase64 string, codePage *CodePage) ([]byte, error) {
+	ansiData, err := encodeFixedANSI(ansi, ansiBase64, int(ExtraDataAnsiStringSize), codePage)
+	if err != nil {
+		return nil, err
+	}
+	unicodeData, err := encodeFixedUnicode(unicode, unicodeBase64, int(ExtraDataUnicodeStringSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(unicodeData) != int(ExtraDataUnicodeStringSize) {
+		return nil, fmt.Errorf("%q does not fit in %v bytes", unicode, ExtraDataUnicodeStringSize)
+	}
+	return append(ansiData, unicodeData...), nil
+}
+
+// encodeANSIString returns the bytes in b64 if they still decode to str in codePage and str encoded otherwise.
+// A terminated string ends with a null byte, b64 includes it then.
+func encodeANSIString(str string, b64 string, terminated bool, codePage *CodePage) ([]byte, error) {
+	if raw, err := base64.StdEncoding.DecodeString(b64); err == nil && b64 != "" {
+		text := raw
+		if terminated {
+			text = bytes.TrimSuffix(raw, []byte{0x0})
+		}
+		if (!terminated || len(text) < len(raw)) && codePage.Decode(text) == str {
+			return raw, nil
+		}
+	}
+	encoded, err := codePage.Encode(str)
+	if err != nil {
+		return nil, err
+	}
+	if terminated {
+		encoded = append(encoded, 0x0)
+	}
+	return encoded, nil
+}
+
+// encodeUnicodeString returns the bytes in b64 if they still decode to str and str as UTF-16LE otherwise.
+// A terminated string ends with a null character, b64 includes it then.
+func encodeUnicodeString(str string, b64 string, terminated bool) ([]byte, error) {
+	if raw, err := base64.StdEncoding.DecodeString(b64); err == nil && b64 != "" && len(raw)%2 == 0 {
+		text := raw
+		if terminated {
+			text = bytes.TrimSuffix(raw, []byte{0x0, 0x0})
+		}
+		if (!terminated || len(text) < len(raw)) && decodeUTF16LE(text) == str {
+			return raw, nil
+		}
+	}
+	encoded := encodeUTF16LE(str)
+	if terminated {
+		encoded = append(encoded, 0x0, 0x0)
+	}
+	return encoded, nil
+}
+
+// encodeFixedANSI returns a null-terminated ANSI string in a field of size bytes, the original
+// field in b64, bytes after the terminator included, if it still decodes to str.
+func encodeFixedANSI(str string, b64 string, size int, codePage *CodePage) ([]byte, error) {
+	if raw, err := base64.StdEncoding.DecodeString(b64); err == nil && len(raw) == size && decodeFixedANSI(raw, codePage) == str {
+		return raw, nil
+	}
+	encoded, err := codePage.Encode(str)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) >= size {
+		return nil, fmt.Errorf("%q does not fit in %v bytes", str, size)
+	}
+	field := make([]byte, size)
+	copy(field, encoded)
+	return field, nil
+}
+
+// encodeFixedUnicode returns a null-terminated UTF-16LE string in a field of at least minSize bytes,
+// the original field in b64 if it still decodes to str.
+func encodeFixedUnicode(str string, b64 string, minSize int) ([]byte, error) {
+	if raw, err := base64.StdEncoding.DecodeString(b64); err == nil && len(raw) >= minSize && decodeFixedUnicode(raw) == str {
+		return raw, nil
+	}
+	encoded := encodeUTF16LE(str)
+	size := len(encoded) + 2
+	if size < minSize {
+		size = minSize
+	}
+	field := make([]byte, size)
+	copy(field, encoded)
+	return field, nil
+}
+
+// encodeUTF16LE converts a Go string to UTF-16LE without terminator.
+func encodeUTF16LE(s string) []byte {
+	u16s := utf16.Encode([]rune(s))
+	b := make([]byte, len(u16s)*2)
+	for i, u := range u16s {
+		binary.LittleEndian.PutUint16(b[i*2:], u)
+	}
+	return b
+}
+
+func putUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func putUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func Test_Encode(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		modify func(*ShellLinkParsed)
+		check  func(*testing.T, ShellLinkParsed)
+	}{
+		{
+			name:   "unmodified",
+			modify: func(*ShellLinkParsed) {},
+		},
+		{
+			name: "string changed",
+			modify: func(s *ShellLinkParsed) {
+				s.StringData.CommandLineArgs = "C:\\Temp\\über.txt"
+				s.LinkInfo.LocalBasePath = "C:\\Windows\\System32\\notepad.exe"
+			},
+			check: func(t *testing.T, s ShellLinkParsed) {
+				if s.StringData.CommandLineArgs != "C:\\Temp\\über.txt" {
+					t.Errorf("CommandLineArgs = %q", s.StringData.CommandLineArgs)
+				}
+				if s.LinkInfo.LocalBasePath != "C:\\Windows\\System32\\notepad.exe" {
+					t.Errorf("LocalBasePath = %q", s.LinkInfo.LocalBasePath)
+				}
+				if s.LinkInfo.CommonPathSuffixOffset != s.LinkInfo.LocalBasePathOffset+uint32(len("C:\\Windows\\System32\\notepad.exe"))+1 {
+					t.Errorf("CommonPathSuffixOffset = 0x%X", s.LinkInfo.CommonPathSuffixOffset)
+				}
+			},
+		},
+		{
+			name: "unknown block",
+			modify: func(s *ShellLinkParsed) {
+				s.ExtraData = append(s.ExtraData, ExtraDataBlock{UnknownDataBlock: &UnknownDataBlock{
+					BlockSignature:  0xA0000042,
+					BlockDataBase64: base64.StdEncoding.EncodeToString([]byte{1, 2, 3, 4, 5}),
+				}})
+			},
+			check: func(t *testing.T, s ShellLinkParsed) {
+				last := s.ExtraData[len(s.ExtraData)-1].UnknownDataBlock
+				if last == nil || last.BlockSignature != 0xA0000042 || last.BlockDataBase64 != "AQIDBAU=" {
+					t.Errorf("UnknownDataBlock = %+v", last)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shellLinkParsed, err := ParseData(bytes.NewReader(fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&shellLinkParsed)
+			got, err := Encode(&shellLinkParsed)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if tt.check == nil {
+				if !bytes.Equal(got, fixture) {
+					t.Errorf("Encode() differs from the parsed file, got %v bytes, want %v", len(got), len(fixture))
+				}
+				return
+			}
+			reparsed, err := ParseData(bytes.NewReader(got))
+			if err != nil {
+				t.Fatalf("ParseData() of encoded file error = %v", err)
+			}
+			tt.check(t, reparsed)
+		})
+	}
+}
+
+// Test_Encode_unmodified changes the bytes of the fixture in ways the parsed fields do not show,
+// Encode has to reproduce them.
+func Test_Encode_unmodified(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// insert puts data at offset and adds its length to the uint32 size at sizeOffset
+	insert := func(offset int, sizeOffset int, data ...byte) []byte {
+		b := append(append(append([]byte{}, fixture[:offset]...), data...), fixture[offset:]...)
+		binary.LittleEndian.PutUint32(b[sizeOffset:], binary.LittleEndian.Uint32(b[sizeOffset:])+uint32(len(data)))
+		return b
+	}
+	tests := []struct {
+		name string
+		data func() []byte
+	}{
+		{
+			name: "data after the TerminalBlock",
+			data: func() []byte {
+				return append(append([]byte{}, fixture...), 0x01, 0x02, 0x03, 0x04)
+			},
+		},
+		{
+			name: "TrackerDataBlock Length",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				binary.LittleEndian.PutUint32(data[0x624:], 0x50)
+				return data
+			},
+		},
+		{
+			name: "SpecialFolderDataBlock larger than its size",
+			data: func() []byte {
+				return insert(0x502, 0x4F2, 0xAA, 0xBB, 0xCC, 0xDD)
+			},
+		},
+		{
+			name: "unused bytes at the end of LinkInfo",
+			data: func() []byte {
+				return insert(0x17A, 0x135, 0xAA, 0xBB, 0xCC, 0xDD)
+			},
+		},
+		{
+			name: "data after the LinkTargetIDList TerminalID",
+			data: func() []byte {
+				data := append(append(append([]byte{}, fixture[:0x135]...), 0xAA, 0xBB), fixture[0x135:]...)
+				binary.LittleEndian.PutUint16(data[0x4C:], binary.LittleEndian.Uint16(data[0x4C:])+2)
+				return data
+			},
+		},
+		{
+			name: "LinkTargetIDList without TerminalID",
+			data: func() []byte {
+				data := append(append([]byte{}, fixture[:0x133]...), fixture[0x135:]...)
+				binary.LittleEndian.PutUint16(data[0x4C:], binary.LittleEndian.Uint16(data[0x4C:])-2)
+				return data
+			},
+		},
+		{
+			name: "data after the VistaAndAboveIDListDataBlock TerminalID",
+			data: func() []byte {
+				return vistaAndAboveIDListFixture(fixture, 0x06, 0x00, 0x2F, 'C', ':', 0x00, 0x00, 0x00, 0xAA, 0xBB)
+			},
+		},
+		{
+			name: "VistaAndAboveIDListDataBlock without TerminalID",
+			data: func() []byte {
+				return vistaAndAboveIDListFixture(fixture, 0x06, 0x00, 0x2F, 'C', ':', 0x00)
+			},
+		},
+		{
+			name: "nonzero TerminalBlock",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				binary.LittleEndian.PutUint32(data[0x67C:], 0x5)
+				return data
+			},
+		},
+		{
+			name: "no TerminalBlock",
+			data: func() []byte {
+				return append([]byte{}, fixture[:0x67C]...)
+			},
+		},
+		{
+			name: "CommonPathSuffix before LocalBasePath",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				// the null byte terminating the VolumeLabel
+				binary.LittleEndian.PutUint32(data[0x14D:], 0x2C)
+				return data
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data()
+			shellLinkParsed, err := ParseData(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Encode(&shellLinkParsed)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("Encode() differs from the parsed file, got %v bytes, want %v", len(got), len(data))
+			}
+		})
+	}
+}
+
+// vistaAndAboveIDListFixture adds a VistaAndAboveIDListDataBlock with idList before the TerminalBlock of fixture.
+func vistaAndAboveIDListFixture(fixture []byte, idList ...byte) []byte {
+	block := putUint32(nil, ExtraDataBlockSizeMin+uint32(len(idList)))
+	block = putUint32(block, VistaAndAboveIDListDataBlockSignature)
+	block = append(block, idList...)
+	return append(append(append([]byte{}, fixture[:0x67C]...), block...), fixture[0x67C:]...)
+}
+
+func Test_Encode_linkInfoHeaderSize(t *testing.T) {
+	fixture, err := ReadLnkFile("testdata/notepad.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a flipped bit made LinkInfoHeaderSize 0x9000001C, Encode must not allocate a header of that size
+	data := append([]byte{}, fixture...)
+	data[0x13C] = 0x90
+	if _, err := ParseData(bytes.NewReader(data)); err == nil {
+		t.Errorf("ParseData() error = nil, want LinkInfoHeaderSize beyond LinkInfoSize to fail")
+	}
+	shellLinkParsed, err := ParseData(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shellLinkParsed.LinkInfo.LinkInfoHeaderSize = 0x9000001C
+	if _, err := Encode(&shellLinkParsed); err == nil {
+		t.Errorf("Encode() error = nil, want LinkInfoHeaderSize beyond LinkInfoSize to fail")
+	}
+}
+
+// linkInfoHeaderExtension is a LinkInfo whose LinkInfoHeaderSize is beyond the optional fields.
+var linkInfoHeaderExtension = []byte{
+	0x3E, 0x00, 0x00, 0x00, // LinkInfoSize
+	0x28, 0x00, 0x00, 0x00, // LinkInfoHeaderSize
+	0x01, 0x00, 0x00, 0x00, // LinkInfoFlags
+	0x28, 0x00, 0x00, 0x00, // VolumeIDOffset
+	0x39, 0x00, 0x00, 0x00, // LocalBasePathOffset
+	0x00, 0x00, 0x00, 0x00, // CommonNetworkRelativeLinkOffset
+	0x3D, 0x00, 0x00, 0x00, // CommonPathSuffixOffset
+	0x00, 0x00, 0x00, 0x00, // LocalBasePathOffsetUnicode
+	0x00, 0x00, 0x00, 0x00, // CommonPathSuffixOffsetUnicode
+	0xDE, 0xAD, 0xBE, 0xEF, // header beyond the optional fields
+	0x11, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0xCD, 0xAB, 0x34, 0x12, 0x10, 0x00, 0x00, 0x00, 0x00, // VolumeID
+	'C', ':', '\\', 0x00, // LocalBasePath
+	0x00, // CommonPathSuffix
+}
+
+func Test_EncodeLinkInfo_headerExtension(t *testing.T) {
+	data := linkInfoHeaderExtension
+	linkInfo, err := ParseLinkInfo(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := EncodeLinkInfo(&linkInfo, nil)
+	if err != nil {
+		t.Fatalf("EncodeLinkInfo() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("EncodeLinkInfo() = %X, want %X", got, data)
+	}
+
+	linkInfo.LocalBasePath = "D:\\"
+	got, err = EncodeLinkInfo(&linkInfo, nil)
+	if err != nil {
+		t.Fatalf("EncodeLinkInfo() error = %v", err)
+	}
+	if !bytes.Equal(got[0x24:0x28], data[0x24:0x28]) {
+		t.Errorf("EncodeLinkInfo() header = %X, want it to end with DEADBEEF", got[:0x28])
+	}
+	reparsed, err := ParseLinkInfo(bytes.NewReader(got), nil)
+	if err != nil {
+		t.Fatalf("ParseLinkInfo() of encoded LinkInfo error = %v", err)
+	}
+	if reparsed.LinkInfoHeaderSize != 0x28 || reparsed.LocalBasePath != "D:\\" {
+		t.Errorf("ParseLinkInfo() of encoded LinkInfo = %+v", reparsed)
+	}
+}
+
+func Test_encodeANSIString(t *testing.T) {
+	type args struct {
+		str        string
+		b64        string
+		terminated bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name: "raw bytes kept",
+			args: args{str: "ab", b64: base64.StdEncoding.EncodeToString([]byte("ab\x00")), terminated: true},
+			want: []byte("ab\x00"),
+		},
+		{
+			name: "changed text encoded",
+			args: args{str: "ä", b64: base64.StdEncoding.EncodeToString([]byte("ab\x00")), terminated: true},
+			want: []byte{0xE4, 0x00},
+		},
+		{
+			name: "not terminated",
+			args: args{str: "ab"},
+			want: []byte("ab"),
+		},
+		{
+			name:    "not in code page",
+			args:    args{str: "日本"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeANSIString(tt.args.str, tt.args.b64, tt.args.terminated, DefaultCodePage)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encodeANSIString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("encodeANSIString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}